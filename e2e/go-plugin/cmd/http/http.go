@@ -34,16 +34,12 @@ func run() (err error) {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
+	// The OTLP endpoint of a local stack can be set with OTEL_EXPORTER_OTLP_ENDPOINT.
 	options := []ldobserve.Option{
 		ldobserve.WithEnvironment("test"),
 		ldobserve.WithServiceName("go-plugin-example"),
 		ldobserve.WithServiceVersion(version.Commit),
 	}
-	// The SDK sets its endpoint explicitly, so the standard environment variable
-	// has to be applied by hand for this example to reach a local stack.
-	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
-		options = append(options, ldobserve.WithOTLPEndpoint(endpoint))
-	}
 
 	client, _ := ld.MakeCustomClient(os.Getenv("LAUNCHDARKLY_SDK_KEY"),
 		ld.Config{
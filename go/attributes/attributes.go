@@ -8,3 +8,7 @@ const ErrorSpanName = "highlight.error"
 
 // AttrSamplingRatio is the attribute key for the sampling ratio for sampled events and logs.
 const AttrSamplingRatio = "launchdarkly.sampling.ratio"
+
+// AttrSamplingForceKept is the attribute key which marks spans that were exported, despite not being sampled,
+// because they are part of a trace containing an error.
+const AttrSamplingForceKept = "launchdarkly.sampling.force_kept"
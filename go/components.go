@@ -0,0 +1,136 @@
+package ldobserve
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+	"github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// Components export the data of OpenTelemetry providers owned by the
+// application to LaunchDarkly. The processors and reader apply the sampling
+// configuration, in the same way as the providers created by the plugin.
+//
+// The LaunchDarkly project is identified by a resource attribute, so Resource
+// must be merged into the resource of each provider:
+//
+//	res, err := resource.Merge(appResource, components.Resource)
+//	tracerProvider := sdktrace.NewTracerProvider(
+//		sdktrace.WithResource(res),
+//		sdktrace.WithSpanProcessor(components.SpanProcessor),
+//	)
+//
+// Shutting down the providers shuts down the components, which flushes pending
+// data.
+type Components struct {
+	// SpanProcessor exports spans. Register it with sdktrace.WithSpanProcessor.
+	SpanProcessor sdktrace.SpanProcessor
+	// LogProcessor exports log records. Register it with sdklog.WithProcessor.
+	LogProcessor sdklog.Processor
+	// MetricReader exports metrics. Register it with sdkmetric.WithReader.
+	MetricReader sdkmetric.Reader
+	// Sampler is the head sampler configured by WithSamplingRateMap,
+	// WithHeadSamplingRules and WithSamplingRateLimit. It is nil when there is
+	// no head sampling configuration. Register it with sdktrace.WithSampler.
+	Sampler sdktrace.Sampler
+	// Resource holds the attributes LaunchDarkly requires, including the
+	// project ID, and the service attributes from the options.
+	Resource *resource.Resource
+}
+
+// NewComponents returns the components which export to LaunchDarkly with the
+// options, for use with providers owned by the application. The components do
+// not change the configuration of the plugin, and do not create providers or
+// set the global providers. Functions such as StartSpan and RecordLog use the
+// providers of the plugin, which export to LaunchDarkly independently of the
+// components.
+//
+// Each call creates new components, with their own sampling configuration.
+// The sampling configuration from LaunchDarkly is fetched until the components
+// are shut down. A persistent queue directory which is already in use, such as
+// by the plugin, is not used, and the components export without a queue.
+//
+// The logger of the plugin is not changed, including by WithDebug. Additional
+// exporters and readers belong with the providers of the application, so the
+// options which add them are rejected.
+func NewComponents(sdkKey string, opts ...Option) (*Components, error) {
+	config := newConfig(opts...)
+	if len(config.additionalSpanExporters) > 0 || len(config.additionalLogExporters) > 0 ||
+		len(config.additionalMetricReaders) > 0 {
+		return nil, errors.New("additional exporters and readers must be registered with the providers " +
+			"of the application rather than with the components")
+	}
+	protocol := resolveOTLPProtocol(config.otlpProtocol)
+	reportConfig(config, protocol)
+
+	sampler := otel.NewCustomSampler(otel.DefaultSampler)
+	setLocalSamplingRules(sampler, config.samplingRules)
+	sampler.SetTraceIDSampling(config.traceIDExportSampling)
+	stopRefresh := func() {}
+	if !config.disableRemoteSamplingConfig {
+		ctx := config.context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		refresher := newRemoteSamplingConfigRefresher(sdkKey, config, func(cfg *gql.GetSamplingConfigResponse) {
+			sampler.SetConfig(&cfg.Sampling)
+		})
+		refresher.start(ctx)
+		var once sync.Once
+		stopRefresh = func() { once.Do(refresher.stop) }
+	}
+
+	conf := newOtelConfig(sdkKey, config, protocol)
+	components, err := otel.NewComponents(context.Background(), &conf, sampler)
+	if err != nil {
+		stopRefresh()
+		return nil, err
+	}
+	return &Components{
+		SpanProcessor: componentSpanProcessor{SpanProcessor: components.SpanProcessor, stopRefresh: stopRefresh},
+		LogProcessor:  componentLogProcessor{Processor: components.LogProcessor, stopRefresh: stopRefresh},
+		MetricReader:  componentMetricReader{Reader: components.MetricReader, stopRefresh: stopRefresh},
+		Sampler:       components.Sampler,
+		Resource:      components.Resource,
+	}, nil
+}
+
+// The components stop fetching the sampling configuration when any of them is
+// shut down, as the providers which own them are shut down together.
+
+type componentSpanProcessor struct {
+	sdktrace.SpanProcessor
+	stopRefresh func()
+}
+
+func (p componentSpanProcessor) Shutdown(ctx context.Context) error {
+	p.stopRefresh()
+	return p.SpanProcessor.Shutdown(ctx)
+}
+
+type componentLogProcessor struct {
+	sdklog.Processor
+	stopRefresh func()
+}
+
+func (p componentLogProcessor) Shutdown(ctx context.Context) error {
+	p.stopRefresh()
+	return p.Processor.Shutdown(ctx)
+}
+
+type componentMetricReader struct {
+	sdkmetric.Reader
+	stopRefresh func()
+}
+
+func (r componentMetricReader) Shutdown(ctx context.Context) error {
+	r.stopRefresh()
+	return r.Reader.Shutdown(ctx)
+}
@@ -0,0 +1,73 @@
+package ldobserve
+
+import (
+	"context"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logging"
+	"github.com/launchdarkly/observability-sdk/go/internal/logtest"
+)
+
+func TestNewComponents_KeepsPluginConfiguration(t *testing.T) {
+	PreInitialize("plugin-key", WithManualStart(), WithoutRemoteSamplingConfig(), WithServiceName("plugin"))
+	setup := lastSetup.Load()
+
+	components, err := NewComponents("components-key",
+		WithoutRemoteSamplingConfig(),
+		WithServiceName("components"),
+		WithSamplingRateLimit(10),
+	)
+	if err != nil {
+		t.Fatalf("NewComponents failed: %v", err)
+	}
+	defer func() {
+		ctx := context.Background()
+		_ = components.SpanProcessor.Shutdown(ctx)
+		_ = components.LogProcessor.Shutdown(ctx)
+		_ = components.MetricReader.Shutdown(ctx)
+	}()
+
+	if lastSetup.Load() != setup {
+		t.Error("Expected the configuration of the plugin to be unchanged")
+	}
+	if components.Sampler == nil {
+		t.Error("Expected the head sampler of the options")
+	}
+	if value, ok := components.Resource.Set().Value("service.name"); !ok || value.AsString() != "components" {
+		t.Errorf("Expected the service name of the options, got %v", value)
+	}
+}
+
+func TestNewComponents_DoesNotChangeTheLogger(t *testing.T) {
+	logging.ClearLogger()
+
+	components, err := NewComponents("components-key", WithoutRemoteSamplingConfig(), WithDebug())
+	if err != nil {
+		t.Fatalf("NewComponents failed: %v", err)
+	}
+	defer func() {
+		ctx := context.Background()
+		_ = components.SpanProcessor.Shutdown(ctx)
+		_ = components.LogProcessor.Shutdown(ctx)
+		_ = components.MetricReader.Shutdown(ctx)
+	}()
+
+	if _, ok := logging.GetLogger().(logging.ConsoleLogger); ok {
+		t.Error("Expected the logger of the plugin to be unchanged")
+	}
+}
+
+func TestNewComponents_RejectsAdditionalExporters(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"span exporter": WithAdditionalSpanExporter(tracetest.NewInMemoryExporter(), false),
+		"log exporter":  WithAdditionalLogExporter(&logtest.RecordingExporter{}, true),
+		"metric reader": WithAdditionalMetricReader(sdkmetric.NewManualReader()),
+	} {
+		if _, err := NewComponents("components-key", WithoutRemoteSamplingConfig(), opt); err == nil {
+			t.Errorf("Expected an additional %s to be rejected", name)
+		}
+	}
+}
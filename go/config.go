@@ -2,11 +2,19 @@ package ldobserve
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/launchdarkly/observability-sdk/go/internal/defaults"
+	"github.com/launchdarkly/observability-sdk/go/internal/otel"
 
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
@@ -14,27 +22,115 @@ import (
 // does not.
 // These are copied from the logs SDK.
 
-// The maximum queue size for the log SDK. If more events than this are queued,
-// then events will be dropped until a flush.
-const defaultLogMaxQueueSize = 2048
-
 // The maximum number of logs in a single batch export.
 const defaultLogMaxExportBatchSize = 512
 
+// The maximum number of spans held by the trace buffer, when a maximum is not specified.
+const defaultTraceBufferMaxSpans = 8192
+
+// The maximum size of the persistent queue, when a maximum is not specified.
+const defaultPersistentQueueMaxBytes = 64 << 20
+
+// The maximum age of data in the persistent queue, when a maximum is not specified.
+const defaultPersistentQueueMaxAge = 24 * time.Hour
+
+// OTLPProtocol is a protocol which can be used to export data using OTLP.
+type OTLPProtocol string
+
+const (
+	// OTLPProtocolHTTPProtobuf exports data as protobuf over HTTP. This is the default.
+	OTLPProtocolHTTPProtobuf OTLPProtocol = "http/protobuf"
+	// OTLPProtocolGRPC exports data using gRPC.
+	OTLPProtocolGRPC OTLPProtocol = "grpc"
+)
+
+// RetryPolicy configures how exports which fail with a transient error are
+// retried. The delay between attempts grows exponentially. Durations which are
+// not greater than zero use the default.
+type RetryPolicy struct {
+	// Disabled turns off retries, so that data which fails to export is dropped.
+	Disabled bool
+	// The delay before the first retry. The default is 5 seconds.
+	InitialInterval time.Duration
+	// The maximum delay between retries. The default is 30 seconds.
+	MaxInterval time.Duration
+	// The maximum time spent retrying an export. The default is 1 minute.
+	MaxElapsedTime time.Duration
+}
+
+// The defaults of the retry policy, which match the OTLP exporters.
+const (
+	defaultRetryInitialInterval = 5 * time.Second
+	defaultRetryMaxInterval     = 30 * time.Second
+	defaultRetryMaxElapsedTime  = time.Minute
+)
+
+func (p *RetryPolicy) toRetryConfig() *otel.RetryConfig {
+	if p == nil {
+		return nil
+	}
+	return &otel.RetryConfig{
+		Enabled:         !p.Disabled,
+		InitialInterval: defaults.DurationOrDefault(p.InitialInterval, defaultRetryInitialInterval),
+		MaxInterval:     defaults.DurationOrDefault(p.MaxInterval, defaultRetryMaxInterval),
+		MaxElapsedTime:  defaults.DurationOrDefault(p.MaxElapsedTime, defaultRetryMaxElapsedTime),
+	}
+}
+
 type observabilityConfig struct {
 	serviceName            string
 	serviceVersion         string
 	environment            string
 	backendURL             string
 	otlpEndpoint           string
+	otlpProtocol           OTLPProtocol
+	otlpHeaders            map[string]string
+	otlpTimeout            time.Duration
+	otlpCompression        string
+	tlsConfig              *tls.Config
+	proxy                  func(*http.Request) (*url.URL, error)
+	httpClient             *http.Client
+	resourceAttributes     []attribute.KeyValue
 	manualStart            bool
 	context                context.Context
 	debug                  bool
 	samplingRateMap        map[trace.SpanKind]float64
+	headSamplingRules      []HeadSamplingRule
+	samplingRateLimit      float64
 	spanMaxExportBatchSize int
 	spanMaxQueueSize       int
 	logMaxExportBatchSize  int
 	logMaxQueueSize        int
+	spanBatchTimeout       time.Duration
+	spanExportTimeout      time.Duration
+	logBatchTimeout        time.Duration
+	logExportTimeout       time.Duration
+	metricInterval         time.Duration
+	metricTimeout          time.Duration
+	spanRetryPolicy        *RetryPolicy
+	logRetryPolicy         *RetryPolicy
+	metricRetryPolicy      *RetryPolicy
+
+	// Errors from the environment variables, which are logged when the
+	// plugin is initialized.
+	environmentErrors []error
+
+	samplingConfigRefreshInterval time.Duration
+	samplingConfigCachePath       string
+	samplingRules                 *SamplingRules
+	disableRemoteSamplingConfig   bool
+	keepErroredTraces             bool
+	traceBufferTimeout            time.Duration
+	traceBufferMaxSpans           int
+	traceIDExportSampling         bool
+	persistentQueueDir            string
+	persistentQueueMaxBytes       int64
+	persistentQueueMaxAge         time.Duration
+	skipGlobalProviders           bool
+	additionalSpanExporters       []otel.AdditionalSpanExporter
+	additionalLogExporters        []otel.AdditionalLogExporter
+	additionalMetricReaders       []sdkmetric.Reader
+	disableSelfTelemetry          bool
 }
 
 func defaultConfig() observabilityConfig {
@@ -44,11 +140,37 @@ func defaultConfig() observabilityConfig {
 		spanMaxExportBatchSize: sdktrace.DefaultMaxExportBatchSize,
 		spanMaxQueueSize:       sdktrace.DefaultMaxQueueSize,
 		logMaxExportBatchSize:  defaultLogMaxExportBatchSize,
-		logMaxQueueSize:        defaultLogMaxQueueSize,
+		logMaxQueueSize:        defaults.DefaultLogMaxQueueSize,
+		otlpCompression:        otel.CompressionGzip,
+		spanBatchTimeout:       defaults.DefaultBatchTimeout,
+		spanExportTimeout:      defaults.DefaultExportTimeout,
+		logBatchTimeout:        defaults.DefaultBatchTimeout,
+		logExportTimeout:       defaults.DefaultExportTimeout,
+		metricInterval:         defaults.DefaultMetricInterval,
+		metricTimeout:          defaults.DefaultExportTimeout,
 	}
 }
 
+// newConfig creates a configuration from the defaults, the OpenTelemetry
+// environment variables and the options, in increasing order of precedence.
+func newConfig(opts ...Option) observabilityConfig {
+	config := defaultConfig()
+	applyEnvironment(&config)
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
 // Option is a function that configures the observability plugin.
+//
+// Settings which are not configured with an option are read from the standard
+// OpenTelemetry environment variables, where there is an equivalent. These are
+// OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_HEADERS,
+// OTEL_EXPORTER_OTLP_TIMEOUT, OTEL_EXPORTER_OTLP_COMPRESSION,
+// OTEL_EXPORTER_OTLP_PROTOCOL, OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES,
+// the OTEL_BSP_* and OTEL_BLRP_* batch settings for spans and logs, and
+// OTEL_METRIC_EXPORT_INTERVAL and OTEL_METRIC_EXPORT_TIMEOUT.
 type Option func(*observabilityConfig)
 
 // WithServiceName sets the service name for the observability plugin.
@@ -80,12 +202,66 @@ func WithBackendURL(backendURL string) Option {
 }
 
 // WithOTLPEndpoint sets the OTLP endpoint for the observability plugin.
+// This takes precedence over the OTEL_EXPORTER_OTLP_ENDPOINT environment variable.
 func WithOTLPEndpoint(otlpEndpoint string) Option {
 	return func(c *observabilityConfig) {
 		c.otlpEndpoint = otlpEndpoint
 	}
 }
 
+// WithOTLPProtocol sets the protocol used to export traces, logs and metrics.
+// When this option is not used, the protocol is read from the
+// OTEL_EXPORTER_OTLP_PROTOCOL environment variable, and otherwise defaults to
+// OTLPProtocolHTTPProtobuf. When using gRPC, the OTLP endpoint should be set to
+// an endpoint which accepts OTLP over gRPC, such as a collector on port 4317.
+// Endpoints without an http:// scheme use TLS.
+func WithOTLPProtocol(protocol OTLPProtocol) Option {
+	return func(c *observabilityConfig) {
+		c.otlpProtocol = protocol
+	}
+}
+
+// WithOTLPHeaders sets headers which are sent with every request to the OTLP
+// endpoint, such as authentication headers. This takes precedence over the
+// OTEL_EXPORTER_OTLP_HEADERS environment variable.
+func WithOTLPHeaders(headers map[string]string) Option {
+	return func(c *observabilityConfig) {
+		c.otlpHeaders = headers
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used to connect to the OTLP endpoint
+// and to LaunchDarkly, for instance to provide a custom CA bundle or client
+// certificates. The configuration is not used for OTLP endpoints with an
+// http:// scheme.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *observabilityConfig) {
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// WithProxy sets the function which determines the proxy used for requests to
+// the OTLP endpoint and to LaunchDarkly. For instance, http.ProxyURL can be used
+// to always use a given proxy. When this option is not used, the proxy is read
+// from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables. Exports
+// using gRPC always use the proxy from the environment.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(c *observabilityConfig) {
+		c.proxy = proxy
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for requests to the OTLP endpoint
+// and to LaunchDarkly. The client takes precedence over WithTLSConfig and
+// WithProxy for these requests. Exports using gRPC do not use the client.
+// The client should not be instrumented with OpenTelemetry, as exporting the
+// telemetry of the exports would never end.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *observabilityConfig) {
+		c.httpClient = client
+	}
+}
+
 // WithManualStart indicates that the observability plugin should not start automatically.
 // Instead, the plugin should be started manually by calling the Start function.
 func WithManualStart() Option {
@@ -122,6 +298,33 @@ func WithSamplingRateMap(rates map[trace.SpanKind]float64) Option {
 	})
 }
 
+// WithHeadSamplingRules sets rules which sample traces at a rate based on the
+// name of their root span and the attributes it is started with. For instance,
+// health check routes can be sampled at a low rate while checkout routes are
+// always sampled. Rules are matched in order, and the first matching rule sets
+// the rate. Root spans which match no rule use the rates from
+// WithSamplingRateMap. Spans with a parent follow the decision of the parent.
+// This setting can influence the quality of metrics used for experiments and guarded
+// releases and should only be adjusted with consultation.
+func WithHeadSamplingRules(rules ...HeadSamplingRule) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.headSamplingRules = rules
+	})
+}
+
+// WithSamplingRateLimit limits the number of traces which are sampled each
+// second. The limit applies to root spans after the rates from
+// WithSamplingRateMap and WithHeadSamplingRules, which can also set limits for
+// the traces matching each rule. When traces are limited, the estimated
+// probability they were sampled with is recorded in the
+// launchdarkly.sampling.ratio attribute of the root span, so that counts can
+// still be extrapolated. A limit of zero or less means there is no limit.
+func WithSamplingRateLimit(rootsPerSecond float64) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.samplingRateLimit = rootsPerSecond
+	})
+}
+
 // WithSpanMaxExportBatchSize sets the maximum number of spans that can be exported in a single batch.
 // This controls the batch size for span exports to the OTLP endpoint.
 func WithSpanMaxExportBatchSize(size int) Option {
@@ -151,3 +354,288 @@ func WithLogMaxQueueSize(size int) Option {
 		conf.logMaxQueueSize = size
 	})
 }
+
+// WithSpanBatchTimeout sets the maximum time spans are held before they are
+// exported, when a batch is not full. Longer timeouts make larger batches,
+// which suits high throughput jobs, while shorter timeouts export spans with
+// less delay. The default is 1 second. This takes precedence over the
+// OTEL_BSP_SCHEDULE_DELAY environment variable.
+func WithSpanBatchTimeout(timeout time.Duration) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.spanBatchTimeout = timeout
+	})
+}
+
+// WithSpanExportTimeout sets the maximum time an export of spans may take,
+// including retries. The default is 30 seconds. This takes precedence over the
+// OTEL_BSP_EXPORT_TIMEOUT environment variable.
+func WithSpanExportTimeout(timeout time.Duration) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.spanExportTimeout = timeout
+	})
+}
+
+// WithSpanRetryPolicy sets how exports of spans which fail with a transient
+// error are retried.
+func WithSpanRetryPolicy(policy RetryPolicy) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.spanRetryPolicy = &policy
+	})
+}
+
+// WithLogBatchTimeout sets the maximum time log records are held before they
+// are exported, when a batch is not full. The default is 1 second. This takes
+// precedence over the OTEL_BLRP_SCHEDULE_DELAY environment variable.
+func WithLogBatchTimeout(timeout time.Duration) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.logBatchTimeout = timeout
+	})
+}
+
+// WithLogExportTimeout sets the maximum time an export of log records may take,
+// including retries. The default is 30 seconds. This takes precedence over the
+// OTEL_BLRP_EXPORT_TIMEOUT environment variable.
+func WithLogExportTimeout(timeout time.Duration) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.logExportTimeout = timeout
+	})
+}
+
+// WithLogRetryPolicy sets how exports of log records which fail with a
+// transient error are retried.
+func WithLogRetryPolicy(policy RetryPolicy) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.logRetryPolicy = &policy
+	})
+}
+
+// WithMetricExportInterval sets the interval at which metrics are exported.
+// The default is 5 seconds. This takes precedence over the
+// OTEL_METRIC_EXPORT_INTERVAL environment variable.
+func WithMetricExportInterval(interval time.Duration) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.metricInterval = interval
+	})
+}
+
+// WithMetricExportTimeout sets the maximum time an export of metrics may take,
+// including retries. The default is 30 seconds. This takes precedence over the
+// OTEL_METRIC_EXPORT_TIMEOUT environment variable.
+func WithMetricExportTimeout(timeout time.Duration) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.metricTimeout = timeout
+	})
+}
+
+// WithMetricRetryPolicy sets how exports of metrics which fail with a transient
+// error are retried.
+func WithMetricRetryPolicy(policy RetryPolicy) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.metricRetryPolicy = &policy
+	})
+}
+
+// WithSamplingConfigRefreshInterval sets how often the sampling configuration is
+// fetched from LaunchDarkly. By default the configuration is only fetched when
+// the plugin is initialized, so changes take effect after the process restarts.
+// When an interval is set, changes are applied while the process is running.
+// Intervals shorter than 10 seconds are raised to 10 seconds.
+// Failed fetches are retried with a jittered backoff, and the last successful
+// configuration remains in effect until a fetch succeeds.
+func WithSamplingConfigRefreshInterval(interval time.Duration) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.samplingConfigRefreshInterval = interval
+	})
+}
+
+// WithSamplingConfigCache enables caching the sampling configuration in the file
+// at the given path. When the plugin starts, a cached configuration is applied
+// before the configuration is fetched from LaunchDarkly, so that sampling is in
+// effect even when LaunchDarkly cannot be reached. The file is rewritten each time
+// the configuration is fetched successfully.
+// A cached configuration is ignored if the file is corrupt, was written by an
+// incompatible version of the plugin, was written for a different SDK key, or is
+// more than 7 days old.
+// The directory containing the file is created if it does not exist.
+func WithSamplingConfigCache(path string) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.samplingConfigCachePath = path
+	})
+}
+
+// WithSamplingRules sets sampling rules provided by the application. The rules
+// have the same form as the sampling configuration managed in LaunchDarkly, and
+// can be written in code or loaded from a file with LoadSamplingRules.
+// The configuration from LaunchDarkly is layered on top of these rules: its
+// rules are matched first, and these rules apply to items that none of them
+// match. Use WithoutRemoteSamplingConfig to only use these rules.
+func WithSamplingRules(rules SamplingRules) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.samplingRules = &rules
+	})
+}
+
+// WithoutRemoteSamplingConfig disables fetching the sampling configuration from
+// LaunchDarkly. This is intended for environments which cannot reach the
+// LaunchDarkly backend, in conjunction with WithSamplingRules.
+func WithoutRemoteSamplingConfig() Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.disableRemoteSamplingConfig = true
+	})
+}
+
+// WithKeepErroredTraces exports every span of a trace which contains an error,
+// even when the sampling configuration would drop some of those spans. A span
+// contains an error when it has an error status or an exception event.
+// Spans which are exported only because of this option have the
+// launchdarkly.sampling.force_kept attribute set to true.
+// Traces are only kept for spans which are exported in the same batch as the
+// error span.
+func WithKeepErroredTraces() Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.keepErroredTraces = true
+	})
+}
+
+// WithTraceBuffer holds spans until the trace they belong to is complete, so that
+// the sampling configuration applies to whole traces. Without the buffer, the
+// children of a span which is sampled out are only removed when they are exported
+// in the same batch as that span.
+//
+// A trace is complete when its root span within this process ends. Traces which
+// are not complete within the timeout are exported as they are. When the buffer
+// holds more than maxSpans spans, the oldest traces are exported as they are to
+// make room. If maxSpans is not greater than zero, then a maximum of 8192 spans
+// is used.
+//
+// Buffering delays the export of every span by up to the timeout, and spans
+// which end after the root span of their trace are not buffered with it.
+func WithTraceBuffer(timeout time.Duration, maxSpans int) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.traceBufferTimeout = timeout
+		if maxSpans <= 0 {
+			maxSpans = defaultTraceBufferMaxSpans
+		}
+		conf.traceBufferMaxSpans = maxSpans
+	})
+}
+
+// WithTraceIDExportSampling makes the sampling decisions of the sampling
+// configuration deterministic for each trace. Spans and logs belonging to a trace
+// which match rules with the same sampling ratio all receive the same decision,
+// including in other services which use this option. The decision is derived from
+// the trace ID, in the same way as WithSamplingRateMap. Logs without a trace
+// context are sampled randomly.
+func WithTraceIDExportSampling() Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.traceIDExportSampling = true
+	})
+}
+
+// WithPersistentQueue queues spans and logs which fail to export in files in
+// the given directory, and exports them when the OTLP endpoint recovers. While
+// the endpoint is failing, new data is written to the queue rather than being
+// held in memory, so that it is not dropped when the in-memory queues fill.
+// Data left in the queue when the process stops is exported by the next process
+// which uses the same directory, so the directory must not be shared by
+// processes running at the same time.
+//
+// The files in the queue use at most maxBytes, which is split evenly between
+// spans and logs, and the oldest data is discarded to make room for new data.
+// Data older than maxAge is discarded. If maxBytes is not greater than zero,
+// then a maximum of 64 MiB is used, and if maxAge is not greater than zero,
+// then a maximum of 24 hours is used.
+//
+// The queue only holds data which was sampled for export, and data the
+// endpoint rejects is discarded rather than queued. Metrics are not queued.
+// Log records exported from the queue do not report how many of their
+// attributes were dropped.
+func WithPersistentQueue(dir string, maxBytes int64, maxAge time.Duration) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.persistentQueueDir = dir
+		if maxBytes <= 0 {
+			maxBytes = defaultPersistentQueueMaxBytes
+		}
+		conf.persistentQueueMaxBytes = maxBytes
+		if maxAge <= 0 {
+			maxAge = defaultPersistentQueueMaxAge
+		}
+		conf.persistentQueueMaxAge = maxAge
+	})
+}
+
+// WithoutGlobalProviders stops the plugin from setting the global OpenTelemetry
+// tracer, logger and meter providers and propagator when it starts. Functions
+// such as StartSpan and RecordLog still export to LaunchDarkly, but
+// instrumentation which uses the global providers, including the tracing hook of
+// the LaunchDarkly SDK, does not. Use NewComponents to export the data of
+// providers owned by the application to LaunchDarkly.
+func WithoutGlobalProviders() Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.skipGlobalProviders = true
+	})
+}
+
+// WithAdditionalSpanExporter sends spans to the exporter as well as to
+// LaunchDarkly, for instance to send them to another collector. The exporter
+// has its own batch processor, which is configured with the options. When
+// sampled is true, the exporter receives the spans which the sampling
+// configuration exports to LaunchDarkly, and otherwise it receives every span.
+// Head sampling applies to every exporter.
+//
+// The exporter is shut down when the plugin is shut down. The option can be
+// used more than once to add several exporters.
+func WithAdditionalSpanExporter(
+	exporter sdktrace.SpanExporter,
+	sampled bool,
+	opts ...sdktrace.BatchSpanProcessorOption,
+) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.additionalSpanExporters = append(conf.additionalSpanExporters, otel.AdditionalSpanExporter{
+			Exporter:     exporter,
+			BatchOptions: opts,
+			Sampled:      sampled,
+		})
+	})
+}
+
+// WithAdditionalLogExporter sends log records to the exporter as well as to
+// LaunchDarkly. The exporter has its own batch processor, which is configured
+// with the options. When sampled is true, the exporter receives the log records
+// which the sampling configuration exports to LaunchDarkly, and otherwise it
+// receives every record.
+//
+// The exporter is shut down when the plugin is shut down. The option can be
+// used more than once to add several exporters.
+func WithAdditionalLogExporter(exporter sdklog.Exporter, sampled bool, opts ...sdklog.BatchProcessorOption) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.additionalLogExporters = append(conf.additionalLogExporters, otel.AdditionalLogExporter{
+			Exporter:     exporter,
+			BatchOptions: opts,
+			Sampled:      sampled,
+		})
+	})
+}
+
+// WithAdditionalMetricReader registers the reader with the meter provider
+// alongside the reader which exports to LaunchDarkly. The reader receives every
+// metric, as the metric sampling configuration is applied when metrics are
+// exported to LaunchDarkly.
+//
+// The reader is shut down when the plugin is shut down. The option can be used
+// more than once to add several readers.
+func WithAdditionalMetricReader(reader sdkmetric.Reader) Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.additionalMetricReaders = append(conf.additionalMetricReaders, reader)
+	})
+}
+
+// WithoutSelfTelemetry stops the plugin from exporting metrics about its export
+// pipelines, such as the number of spans and logs which are exported, dropped
+// because a queue is full or sampled out, and the failures and duration of
+// exports. The counts are still available from Stats.
+func WithoutSelfTelemetry() Option {
+	return Option(func(conf *observabilityConfig) {
+		conf.disableSelfTelemetry = true
+	})
+}
@@ -2,10 +2,18 @@ package ldobserve
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
 	"testing"
+	"time"
 
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/defaults"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -35,7 +43,24 @@ func TestDefaultConfig(t *testing.T) {
 	}
 
 	if config.logMaxQueueSize != 2048 {
-		t.Errorf("Expected default logMaxQueueSize to be %d, got %d", defaultLogMaxQueueSize, config.logMaxQueueSize)
+		t.Errorf("Expected default logMaxQueueSize to be %d, got %d", defaults.DefaultLogMaxQueueSize, config.logMaxQueueSize)
+	}
+
+	if config.spanBatchTimeout != time.Second || config.logBatchTimeout != time.Second {
+		t.Errorf("Expected default batch timeouts to be 1s, got %v and %v", config.spanBatchTimeout, config.logBatchTimeout)
+	}
+
+	if config.spanExportTimeout != 30*time.Second || config.logExportTimeout != 30*time.Second || config.metricTimeout != 30*time.Second {
+		t.Errorf("Expected default export timeouts to be 30s, got %v, %v and %v",
+			config.spanExportTimeout, config.logExportTimeout, config.metricTimeout)
+	}
+
+	if config.metricInterval != 5*time.Second {
+		t.Errorf("Expected default metricInterval to be 5s, got %v", config.metricInterval)
+	}
+
+	if config.otlpCompression != "gzip" {
+		t.Errorf("Expected default otlpCompression to be 'gzip', got '%s'", config.otlpCompression)
 	}
 
 	// Test that optional fields are zero values
@@ -123,6 +148,97 @@ func TestWithOTLPEndpoint(t *testing.T) {
 	}
 }
 
+func TestWithOTLPProtocol(t *testing.T) {
+	config := defaultConfig()
+
+	if config.otlpProtocol != "" {
+		t.Errorf("Expected default otlpProtocol to be empty, got '%s'", config.otlpProtocol)
+	}
+
+	WithOTLPProtocol(OTLPProtocolGRPC)(&config)
+
+	if config.otlpProtocol != OTLPProtocolGRPC {
+		t.Errorf("Expected otlpProtocol to be '%s', got '%s'", OTLPProtocolGRPC, config.otlpProtocol)
+	}
+}
+
+func TestResolveOTLPProtocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   OTLPProtocol
+		env      string
+		expected OTLPProtocol
+	}{
+		{name: "default", expected: OTLPProtocolHTTPProtobuf},
+		{name: "environment", env: "grpc", expected: OTLPProtocolGRPC},
+		{name: "option", option: OTLPProtocolGRPC, expected: OTLPProtocolGRPC},
+		{name: "option overrides environment", option: OTLPProtocolHTTPProtobuf, env: "grpc", expected: OTLPProtocolHTTPProtobuf},
+		{name: "unsupported environment", env: "http/json", expected: OTLPProtocolHTTPProtobuf},
+		{name: "unsupported option", option: "http/json", env: "grpc", expected: OTLPProtocolHTTPProtobuf},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", tt.env)
+
+			if protocol := resolveOTLPProtocol(tt.option); protocol != tt.expected {
+				t.Errorf("Expected protocol '%s', got '%s'", tt.expected, protocol)
+			}
+		})
+	}
+}
+
+func TestWithOTLPHeaders(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=from-environment")
+	config := newConfig(WithOTLPHeaders(map[string]string{"api-key": "from-option"}))
+
+	if config.otlpHeaders["api-key"] != "from-option" {
+		t.Errorf("Expected the headers option to take precedence, got %v", config.otlpHeaders)
+	}
+}
+
+func TestWithTLSConfigAndProxy(t *testing.T) {
+	config := defaultConfig()
+
+	if backendHTTPClient(config) != http.DefaultClient {
+		t.Errorf("Expected the default client to be used without TLS or proxy configuration")
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS13}
+	proxyURL, _ := url.Parse("http://proxy.example.com:3128")
+	WithTLSConfig(tlsConfig)(&config)
+	WithProxy(http.ProxyURL(proxyURL))(&config)
+
+	if config.tlsConfig != tlsConfig {
+		t.Errorf("Expected tlsConfig to be set")
+	}
+	transport, ok := backendHTTPClient(config).Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected the backend client to have an HTTP transport")
+	}
+	if transport.TLSClientConfig != tlsConfig {
+		t.Errorf("Expected the backend client to use the TLS configuration")
+	}
+	proxy, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "example.com"}})
+	if err != nil || proxy.String() != proxyURL.String() {
+		t.Errorf("Expected the backend client to use the proxy, got %v %v", proxy, err)
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	config := defaultConfig()
+	client := &http.Client{Timeout: time.Second}
+
+	WithTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12})(&config)
+	WithHTTPClient(client)(&config)
+
+	if config.httpClient != client {
+		t.Errorf("Expected httpClient to be set")
+	}
+	if backendHTTPClient(config) != client {
+		t.Errorf("Expected the HTTP client to take precedence for the backend client")
+	}
+}
+
 func TestWithManualStart(t *testing.T) {
 	config := defaultConfig()
 
@@ -181,6 +297,32 @@ func TestWithSamplingRateMap(t *testing.T) {
 	}
 }
 
+func TestWithHeadSamplingRules(t *testing.T) {
+	config := defaultConfig()
+
+	WithHeadSamplingRules(
+		HeadSamplingRule{Name: MatchRegex("^GET /health"), Rate: 0.01},
+		HeadSamplingRule{Name: MatchValue("POST /checkout"), Rate: 1},
+	)(&config)
+
+	if len(config.headSamplingRules) != 2 {
+		t.Fatalf("Expected 2 head sampling rules, got %d", len(config.headSamplingRules))
+	}
+	if config.headSamplingRules[0].Rate != 0.01 || config.headSamplingRules[1].Name.MatchValue != "POST /checkout" {
+		t.Errorf("Expected head sampling rules to be kept in order, got %+v", config.headSamplingRules)
+	}
+}
+
+func TestWithSamplingRateLimit(t *testing.T) {
+	config := defaultConfig()
+
+	WithSamplingRateLimit(50)(&config)
+
+	if config.samplingRateLimit != 50 {
+		t.Errorf("Expected samplingRateLimit to be 50, got %v", config.samplingRateLimit)
+	}
+}
+
 func TestWithSpanMaxExportBatchSize(t *testing.T) {
 	config := defaultConfig()
 	size := 1000
@@ -225,6 +367,53 @@ func TestWithLogMaxQueueSize(t *testing.T) {
 	}
 }
 
+func TestWithBatchAndExportTimeouts(t *testing.T) {
+	config := defaultConfig()
+
+	WithSpanBatchTimeout(100 * time.Millisecond)(&config)
+	WithSpanExportTimeout(5 * time.Second)(&config)
+	WithLogBatchTimeout(200 * time.Millisecond)(&config)
+	WithLogExportTimeout(10 * time.Second)(&config)
+	WithMetricExportInterval(time.Minute)(&config)
+	WithMetricExportTimeout(20 * time.Second)(&config)
+
+	if config.spanBatchTimeout != 100*time.Millisecond || config.spanExportTimeout != 5*time.Second {
+		t.Errorf("Unexpected span timeouts %v %v", config.spanBatchTimeout, config.spanExportTimeout)
+	}
+	if config.logBatchTimeout != 200*time.Millisecond || config.logExportTimeout != 10*time.Second {
+		t.Errorf("Unexpected log timeouts %v %v", config.logBatchTimeout, config.logExportTimeout)
+	}
+	if config.metricInterval != time.Minute || config.metricTimeout != 20*time.Second {
+		t.Errorf("Unexpected metric interval and timeout %v %v", config.metricInterval, config.metricTimeout)
+	}
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	config := defaultConfig()
+
+	if config.spanRetryPolicy.toRetryConfig() != nil {
+		t.Errorf("Expected no retry configuration by default")
+	}
+
+	WithSpanRetryPolicy(RetryPolicy{InitialInterval: time.Second})(&config)
+	WithLogRetryPolicy(RetryPolicy{Disabled: true})(&config)
+	WithMetricRetryPolicy(RetryPolicy{MaxInterval: 10 * time.Second, MaxElapsedTime: 5 * time.Minute})(&config)
+
+	spanRetry := config.spanRetryPolicy.toRetryConfig()
+	if !spanRetry.Enabled || spanRetry.InitialInterval != time.Second ||
+		spanRetry.MaxInterval != 30*time.Second || spanRetry.MaxElapsedTime != time.Minute {
+		t.Errorf("Unexpected span retry configuration %+v", spanRetry)
+	}
+	if logRetry := config.logRetryPolicy.toRetryConfig(); logRetry.Enabled {
+		t.Errorf("Expected log retries to be disabled, got %+v", logRetry)
+	}
+	metricRetry := config.metricRetryPolicy.toRetryConfig()
+	if !metricRetry.Enabled || metricRetry.InitialInterval != 5*time.Second ||
+		metricRetry.MaxInterval != 10*time.Second || metricRetry.MaxElapsedTime != 5*time.Minute {
+		t.Errorf("Unexpected metric retry configuration %+v", metricRetry)
+	}
+}
+
 func TestMultipleOptions(t *testing.T) {
 	config := defaultConfig()
 	serviceName := "multi-test-service"
@@ -318,3 +507,185 @@ func TestNilSamplingRateMap(t *testing.T) {
 		t.Errorf("Expected samplingRateMap to be nil when nil is passed, got %v", config.samplingRateMap)
 	}
 }
+
+func TestWithSamplingConfigRefreshInterval(t *testing.T) {
+	config := defaultConfig()
+
+	if config.samplingConfigRefreshInterval != 0 {
+		t.Errorf("Expected default samplingConfigRefreshInterval to be 0, got %v", config.samplingConfigRefreshInterval)
+	}
+
+	WithSamplingConfigRefreshInterval(time.Minute)(&config)
+
+	if config.samplingConfigRefreshInterval != time.Minute {
+		t.Errorf("Expected samplingConfigRefreshInterval to be %v, got %v", time.Minute, config.samplingConfigRefreshInterval)
+	}
+}
+
+func TestWithSamplingConfigCache(t *testing.T) {
+	config := defaultConfig()
+
+	if config.samplingConfigCachePath != "" {
+		t.Errorf("Expected default samplingConfigCachePath to be empty, got '%s'", config.samplingConfigCachePath)
+	}
+
+	WithSamplingConfigCache("/var/cache/ldobserve/sampling.json")(&config)
+
+	if config.samplingConfigCachePath != "/var/cache/ldobserve/sampling.json" {
+		t.Errorf("Expected samplingConfigCachePath to be set, got '%s'", config.samplingConfigCachePath)
+	}
+}
+
+func TestWithSamplingRules(t *testing.T) {
+	config := defaultConfig()
+
+	if config.samplingRules != nil {
+		t.Errorf("Expected default samplingRules to be nil, got %v", config.samplingRules)
+	}
+
+	rules := SamplingRules{Spans: []SpanSamplingRule{{Name: MatchValue("span"), SamplingRatio: 10}}}
+	WithSamplingRules(rules)(&config)
+
+	if config.samplingRules == nil || len(config.samplingRules.Spans) != 1 {
+		t.Errorf("Expected samplingRules to be set, got %v", config.samplingRules)
+	}
+}
+
+func TestWithoutRemoteSamplingConfig(t *testing.T) {
+	config := defaultConfig()
+
+	if config.disableRemoteSamplingConfig != false {
+		t.Errorf("Expected default disableRemoteSamplingConfig to be false, got %t", config.disableRemoteSamplingConfig)
+	}
+
+	WithoutRemoteSamplingConfig()(&config)
+
+	if config.disableRemoteSamplingConfig != true {
+		t.Errorf("Expected disableRemoteSamplingConfig to be true, got %t", config.disableRemoteSamplingConfig)
+	}
+}
+
+func TestWithKeepErroredTraces(t *testing.T) {
+	config := defaultConfig()
+
+	if config.keepErroredTraces != false {
+		t.Errorf("Expected default keepErroredTraces to be false, got %t", config.keepErroredTraces)
+	}
+
+	WithKeepErroredTraces()(&config)
+
+	if config.keepErroredTraces != true {
+		t.Errorf("Expected keepErroredTraces to be true, got %t", config.keepErroredTraces)
+	}
+}
+
+func TestWithTraceBuffer(t *testing.T) {
+	config := defaultConfig()
+
+	if config.traceBufferTimeout != 0 {
+		t.Errorf("Expected default traceBufferTimeout to be 0, got %v", config.traceBufferTimeout)
+	}
+
+	WithTraceBuffer(10*time.Second, 1000)(&config)
+
+	if config.traceBufferTimeout != 10*time.Second {
+		t.Errorf("Expected traceBufferTimeout to be 10s, got %v", config.traceBufferTimeout)
+	}
+	if config.traceBufferMaxSpans != 1000 {
+		t.Errorf("Expected traceBufferMaxSpans to be 1000, got %d", config.traceBufferMaxSpans)
+	}
+
+	WithTraceBuffer(10*time.Second, 0)(&config)
+
+	if config.traceBufferMaxSpans != defaultTraceBufferMaxSpans {
+		t.Errorf("Expected traceBufferMaxSpans to default to %d, got %d", defaultTraceBufferMaxSpans, config.traceBufferMaxSpans)
+	}
+}
+
+func TestWithTraceIDExportSampling(t *testing.T) {
+	config := defaultConfig()
+
+	if config.traceIDExportSampling != false {
+		t.Errorf("Expected default traceIDExportSampling to be false, got %t", config.traceIDExportSampling)
+	}
+
+	WithTraceIDExportSampling()(&config)
+
+	if config.traceIDExportSampling != true {
+		t.Errorf("Expected traceIDExportSampling to be true, got %t", config.traceIDExportSampling)
+	}
+}
+
+func TestWithPersistentQueue(t *testing.T) {
+	config := defaultConfig()
+
+	WithPersistentQueue("/var/lib/app/queue", 1<<20, time.Hour)(&config)
+
+	if config.persistentQueueDir != "/var/lib/app/queue" || config.persistentQueueMaxBytes != 1<<20 ||
+		config.persistentQueueMaxAge != time.Hour {
+		t.Errorf("Unexpected persistent queue configuration %q %d %v",
+			config.persistentQueueDir, config.persistentQueueMaxBytes, config.persistentQueueMaxAge)
+	}
+
+	WithPersistentQueue("/var/lib/app/queue", 0, 0)(&config)
+
+	if config.persistentQueueMaxBytes != defaultPersistentQueueMaxBytes ||
+		config.persistentQueueMaxAge != defaultPersistentQueueMaxAge {
+		t.Errorf("Expected the persistent queue limits to default, got %d %v",
+			config.persistentQueueMaxBytes, config.persistentQueueMaxAge)
+	}
+}
+
+func TestWithoutGlobalProviders(t *testing.T) {
+	config := defaultConfig()
+
+	if config.skipGlobalProviders != false {
+		t.Errorf("Expected default skipGlobalProviders to be false, got %t", config.skipGlobalProviders)
+	}
+
+	WithoutGlobalProviders()(&config)
+
+	if config.skipGlobalProviders != true {
+		t.Errorf("Expected skipGlobalProviders to be true, got %t", config.skipGlobalProviders)
+	}
+}
+
+func TestWithAdditionalExporters(t *testing.T) {
+	config := defaultConfig()
+	spanExporter := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+
+	WithAdditionalSpanExporter(spanExporter, true, sdktrace.WithMaxExportBatchSize(10))(&config)
+	WithAdditionalSpanExporter(spanExporter, false)(&config)
+	WithAdditionalMetricReader(reader)(&config)
+
+	if len(config.additionalSpanExporters) != 2 {
+		t.Fatalf("Expected 2 additional span exporters, got %d", len(config.additionalSpanExporters))
+	}
+	if !config.additionalSpanExporters[0].Sampled || len(config.additionalSpanExporters[0].BatchOptions) != 1 {
+		t.Errorf("Unexpected additional span exporter %+v", config.additionalSpanExporters[0])
+	}
+	if config.additionalSpanExporters[1].Sampled {
+		t.Errorf("Expected the second exporter not to be sampled")
+	}
+	if len(config.additionalMetricReaders) != 1 || config.additionalMetricReaders[0] != reader {
+		t.Errorf("Unexpected additional metric readers %v", config.additionalMetricReaders)
+	}
+	if len(config.additionalLogExporters) != 0 {
+		t.Errorf("Expected no additional log exporters, got %d", len(config.additionalLogExporters))
+	}
+}
+
+func TestWithoutSelfTelemetry(t *testing.T) {
+	config := defaultConfig()
+
+	if config.disableSelfTelemetry != false {
+		t.Errorf("Expected default disableSelfTelemetry to be false, got %t", config.disableSelfTelemetry)
+	}
+
+	WithoutSelfTelemetry()(&config)
+
+	if config.disableSelfTelemetry != true {
+		t.Errorf("Expected disableSelfTelemetry to be true, got %t", config.disableSelfTelemetry)
+	}
+}
@@ -0,0 +1,163 @@
+package ldobserve
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// The OpenTelemetry environment variables which are applied to the configuration.
+// https://opentelemetry.io/docs/specs/otel/configuration/sdk-environment-variables/
+const (
+	envOTLPEndpoint        = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	envOTLPHeaders         = "OTEL_EXPORTER_OTLP_HEADERS"
+	envOTLPTimeout         = "OTEL_EXPORTER_OTLP_TIMEOUT"
+	envOTLPCompression     = "OTEL_EXPORTER_OTLP_COMPRESSION"
+	envServiceName         = "OTEL_SERVICE_NAME"
+	envResourceAttributes  = "OTEL_RESOURCE_ATTRIBUTES"
+	envSpanScheduleDelay   = "OTEL_BSP_SCHEDULE_DELAY"
+	envSpanExportTimeout   = "OTEL_BSP_EXPORT_TIMEOUT"
+	envSpanMaxQueueSize    = "OTEL_BSP_MAX_QUEUE_SIZE"
+	envSpanMaxExportBatch  = "OTEL_BSP_MAX_EXPORT_BATCH_SIZE"
+	envLogScheduleDelay    = "OTEL_BLRP_SCHEDULE_DELAY"
+	envLogExportTimeout    = "OTEL_BLRP_EXPORT_TIMEOUT"
+	envLogMaxQueueSize     = "OTEL_BLRP_MAX_QUEUE_SIZE"
+	envLogMaxExportBatch   = "OTEL_BLRP_MAX_EXPORT_BATCH_SIZE"
+	envMetricInterval      = "OTEL_METRIC_EXPORT_INTERVAL"
+	envMetricExportTimeout = "OTEL_METRIC_EXPORT_TIMEOUT"
+)
+
+// applyEnvironment applies the OpenTelemetry environment variables to the
+// configuration. Options are applied after the environment, so they take
+// precedence. Invalid values are ignored, and the errors are kept so that they
+// can be logged once the logger has been configured.
+func applyEnvironment(c *observabilityConfig) {
+	env := environment{config: c}
+
+	env.readString(envOTLPEndpoint, &c.otlpEndpoint)
+	if value, ok := os.LookupEnv(envOTLPHeaders); ok && value != "" {
+		headers, err := parseKeyValues(value)
+		if err != nil {
+			env.errorf(envOTLPHeaders, err)
+		}
+		c.otlpHeaders = headers
+	}
+	env.readDuration(envOTLPTimeout, &c.otlpTimeout)
+	if value, ok := os.LookupEnv(envOTLPCompression); ok && value != "" {
+		switch value {
+		case otel.CompressionGzip, otel.CompressionNone:
+			c.otlpCompression = value
+		default:
+			env.errorf(envOTLPCompression, fmt.Errorf("unsupported compression %q", value))
+		}
+	}
+
+	if value, ok := os.LookupEnv(envResourceAttributes); ok && value != "" {
+		attrs, err := parseKeyValues(value)
+		if err != nil {
+			env.errorf(envResourceAttributes, err)
+		}
+		for _, key := range slices.Sorted(maps.Keys(attrs)) {
+			c.resourceAttributes = append(c.resourceAttributes, attribute.String(key, attrs[key]))
+		}
+		if name, ok := attrs[string(semconv.ServiceNameKey)]; ok {
+			c.serviceName = name
+		}
+	}
+	env.readString(envServiceName, &c.serviceName)
+
+	env.readDuration(envSpanScheduleDelay, &c.spanBatchTimeout)
+	env.readDuration(envSpanExportTimeout, &c.spanExportTimeout)
+	env.readInt(envSpanMaxQueueSize, &c.spanMaxQueueSize)
+	env.readInt(envSpanMaxExportBatch, &c.spanMaxExportBatchSize)
+	env.readDuration(envLogScheduleDelay, &c.logBatchTimeout)
+	env.readDuration(envLogExportTimeout, &c.logExportTimeout)
+	env.readInt(envLogMaxQueueSize, &c.logMaxQueueSize)
+	env.readInt(envLogMaxExportBatch, &c.logMaxExportBatchSize)
+	env.readDuration(envMetricInterval, &c.metricInterval)
+	env.readDuration(envMetricExportTimeout, &c.metricTimeout)
+}
+
+type environment struct {
+	config *observabilityConfig
+}
+
+func (e environment) errorf(name string, err error) {
+	e.config.environmentErrors = append(e.config.environmentErrors, fmt.Errorf("%s: %w", name, err))
+}
+
+func (e environment) readString(name string, target *string) {
+	if value, ok := os.LookupEnv(name); ok && value != "" {
+		*target = value
+	}
+}
+
+// readDuration reads a duration in milliseconds, which must be greater than zero.
+func (e environment) readDuration(name string, target *time.Duration) {
+	var milliseconds int
+	if e.positiveInt(name, &milliseconds) {
+		*target = time.Duration(milliseconds) * time.Millisecond
+	}
+}
+
+func (e environment) readInt(name string, target *int) {
+	e.positiveInt(name, target)
+}
+
+func (e environment) positiveInt(name string, target *int) bool {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		e.errorf(name, err)
+		return false
+	}
+	if n <= 0 {
+		e.errorf(name, fmt.Errorf("value must be greater than zero, got %d", n))
+		return false
+	}
+	*target = n
+	return true
+}
+
+// parseKeyValues parses a comma separated list of key=value pairs with
+// percent encoded values, as used for headers and resource attributes.
+// Invalid pairs are skipped, and an error describing them is returned along
+// with the valid pairs.
+func parseKeyValues(value string) (map[string]string, error) {
+	result := make(map[string]string)
+	var invalid []string
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, encoded, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			invalid = append(invalid, pair)
+			continue
+		}
+		decoded, err := url.PathUnescape(strings.TrimSpace(encoded))
+		if err != nil {
+			invalid = append(invalid, pair)
+			continue
+		}
+		result[key] = decoded
+	}
+	if len(invalid) > 0 {
+		return result, fmt.Errorf("invalid key value pairs %q", invalid)
+	}
+	return result, nil
+}
@@ -0,0 +1,121 @@
+package ldobserve
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestNewConfig_Environment(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret%20value, x-tenant=a")
+	t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "2500")
+	t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "none")
+	t.Setenv("OTEL_SERVICE_NAME", "checkout")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "service.name=ignored,team=payments")
+	t.Setenv("OTEL_BSP_SCHEDULE_DELAY", "200")
+	t.Setenv("OTEL_BSP_EXPORT_TIMEOUT", "10000")
+	t.Setenv("OTEL_BSP_MAX_QUEUE_SIZE", "100")
+	t.Setenv("OTEL_BSP_MAX_EXPORT_BATCH_SIZE", "10")
+	t.Setenv("OTEL_BLRP_SCHEDULE_DELAY", "300")
+	t.Setenv("OTEL_BLRP_EXPORT_TIMEOUT", "20000")
+	t.Setenv("OTEL_BLRP_MAX_QUEUE_SIZE", "200")
+	t.Setenv("OTEL_BLRP_MAX_EXPORT_BATCH_SIZE", "20")
+	t.Setenv("OTEL_METRIC_EXPORT_INTERVAL", "60000")
+	t.Setenv("OTEL_METRIC_EXPORT_TIMEOUT", "15000")
+
+	config := newConfig()
+
+	if config.otlpEndpoint != "http://localhost:4318" {
+		t.Errorf("Expected otlpEndpoint from the environment, got '%s'", config.otlpEndpoint)
+	}
+	if config.otlpHeaders["api-key"] != "secret value" || config.otlpHeaders["x-tenant"] != "a" {
+		t.Errorf("Expected decoded headers from the environment, got %v", config.otlpHeaders)
+	}
+	if config.otlpTimeout != 2500*time.Millisecond {
+		t.Errorf("Expected otlpTimeout to be 2.5s, got %v", config.otlpTimeout)
+	}
+	if config.otlpCompression != "none" {
+		t.Errorf("Expected otlpCompression to be 'none', got '%s'", config.otlpCompression)
+	}
+	if config.serviceName != "checkout" {
+		t.Errorf("Expected OTEL_SERVICE_NAME to take precedence, got '%s'", config.serviceName)
+	}
+	expectedAttributes := []attribute.KeyValue{
+		attribute.String("service.name", "ignored"),
+		attribute.String("team", "payments"),
+	}
+	if len(config.resourceAttributes) != len(expectedAttributes) {
+		t.Fatalf("Expected resource attributes %v, got %v", expectedAttributes, config.resourceAttributes)
+	}
+	for i, attr := range expectedAttributes {
+		if config.resourceAttributes[i] != attr {
+			t.Errorf("Expected resource attribute %v, got %v", attr, config.resourceAttributes[i])
+		}
+	}
+	if config.spanBatchTimeout != 200*time.Millisecond || config.spanExportTimeout != 10*time.Second ||
+		config.spanMaxQueueSize != 100 || config.spanMaxExportBatchSize != 10 {
+		t.Errorf("Unexpected span batch settings %v %v %d %d", config.spanBatchTimeout, config.spanExportTimeout,
+			config.spanMaxQueueSize, config.spanMaxExportBatchSize)
+	}
+	if config.logBatchTimeout != 300*time.Millisecond || config.logExportTimeout != 20*time.Second ||
+		config.logMaxQueueSize != 200 || config.logMaxExportBatchSize != 20 {
+		t.Errorf("Unexpected log batch settings %v %v %d %d", config.logBatchTimeout, config.logExportTimeout,
+			config.logMaxQueueSize, config.logMaxExportBatchSize)
+	}
+	if config.metricInterval != time.Minute || config.metricTimeout != 15*time.Second {
+		t.Errorf("Unexpected metric settings %v %v", config.metricInterval, config.metricTimeout)
+	}
+	if len(config.environmentErrors) != 0 {
+		t.Errorf("Expected no environment errors, got %v", config.environmentErrors)
+	}
+}
+
+func TestNewConfig_OptionsTakePrecedence(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_SERVICE_NAME", "from-environment")
+	t.Setenv("OTEL_BSP_MAX_QUEUE_SIZE", "100")
+
+	config := newConfig(
+		WithOTLPEndpoint("https://collector.example.com:4318"),
+		WithServiceName("from-option"),
+		WithSpanMaxQueueSize(50),
+	)
+
+	if config.otlpEndpoint != "https://collector.example.com:4318" {
+		t.Errorf("Expected the endpoint option to take precedence, got '%s'", config.otlpEndpoint)
+	}
+	if config.serviceName != "from-option" {
+		t.Errorf("Expected the service name option to take precedence, got '%s'", config.serviceName)
+	}
+	if config.spanMaxQueueSize != 50 {
+		t.Errorf("Expected the queue size option to take precedence, got %d", config.spanMaxQueueSize)
+	}
+}
+
+func TestNewConfig_InvalidEnvironment(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "zstd")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=secret,invalid")
+	t.Setenv("OTEL_BSP_MAX_QUEUE_SIZE", "lots")
+	t.Setenv("OTEL_METRIC_EXPORT_INTERVAL", "-1")
+
+	config := newConfig()
+	defaults := defaultConfig()
+
+	if config.otlpCompression != defaults.otlpCompression {
+		t.Errorf("Expected an unsupported compression to be ignored, got '%s'", config.otlpCompression)
+	}
+	if len(config.otlpHeaders) != 1 || config.otlpHeaders["api-key"] != "secret" {
+		t.Errorf("Expected the valid headers to be kept, got %v", config.otlpHeaders)
+	}
+	if config.spanMaxQueueSize != defaults.spanMaxQueueSize {
+		t.Errorf("Expected an invalid queue size to be ignored, got %d", config.spanMaxQueueSize)
+	}
+	if config.metricInterval != defaults.metricInterval {
+		t.Errorf("Expected a negative interval to be ignored, got %v", config.metricInterval)
+	}
+	if len(config.environmentErrors) != 4 {
+		t.Errorf("Expected 4 environment errors, got %v", config.environmentErrors)
+	}
+}
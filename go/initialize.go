@@ -2,7 +2,12 @@ package ldobserve
 
 import (
 	"context"
+	"fmt"
+	"maps"
 	"net/http"
+	"os"
+	"slices"
+	"sync/atomic"
 
 	"github.com/Khan/genqlient/graphql"
 	"go.opentelemetry.io/otel/attribute"
@@ -16,72 +21,287 @@ import (
 	"github.com/launchdarkly/observability-sdk/go/internal/otel"
 )
 
-func getSamplingConfig(projectId string, config observabilityConfig) (*gql.GetSamplingConfigResponse, error) {
-	var ctx context.Context
+func getSamplingConfig(
+	ctx context.Context,
+	projectId string,
+	config observabilityConfig,
+) (*gql.GetSamplingConfigResponse, error) {
+	client := graphql.NewClient(config.backendURL, backendHTTPClient(config))
+	return gql.GetSamplingConfig(ctx, client, projectId)
+}
+
+// backendHTTPClient returns the client used for requests to LaunchDarkly.
+func backendHTTPClient(config observabilityConfig) *http.Client {
+	if config.httpClient != nil {
+		return config.httpClient
+	}
+	if config.tlsConfig == nil && config.proxy == nil {
+		return http.DefaultClient
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.tlsConfig != nil {
+		transport.TLSClientConfig = config.tlsConfig
+	}
+	if config.proxy != nil {
+		transport.Proxy = config.proxy
+	}
+	return &http.Client{Transport: transport}
+}
+
+// setupState is the configuration of the most recent initialization, which
+// Reconfigure builds on.
+type setupState struct {
+	sdkKey string
+	config observabilityConfig
+}
+
+// The state of the most recent initialization.
+//
+//nolint:gochecknoglobals
+var lastSetup atomic.Pointer[setupState]
+
+func setupOtel(sdkKey string, config observabilityConfig) {
+	configureOtel(sdkKey, config)
 	if config.context != nil {
-		ctx = config.context
+		go func() {
+			<-config.context.Done()
+			otel.Shutdown()
+		}()
+	}
+}
+
+// configureOtel applies the configuration to the plugin, and starts it unless
+// it is started manually. It is the part of the setup which Reconfigure
+// repeats.
+func configureOtel(sdkKey string, config observabilityConfig) {
+	lastSetup.Store(&setupState{sdkKey: sdkKey, config: config})
+	protocol := resolveOTLPProtocol(config.otlpProtocol)
+	setupLogging(config, protocol)
+	setLocalSamplingRules(otel.GetCustomSampler(), config.samplingRules)
+	otel.GetCustomSampler().SetTraceIDSampling(config.traceIDExportSampling)
+	otel.SetConfig(newOtelConfig(sdkKey, config, protocol))
+	if !config.manualStart {
+		err := otel.StartOTLP()
+		if err != nil {
+			logging.GetLogger().Errorf("failed to start otel: %v", err)
+		}
+	}
+	if config.disableRemoteSamplingConfig {
+		stopSamplingConfigRefresh()
 	} else {
-		ctx = context.Background()
+		startRemoteSamplingConfig(sdkKey, config)
 	}
-	client := graphql.NewClient(config.backendURL, http.DefaultClient)
-	return gql.GetSamplingConfig(ctx, client, projectId)
 }
 
-func setupOtel(sdkKey string, config observabilityConfig) {
-	attributes := []attribute.KeyValue{
+// setupLogging enables debug logging when it is configured, and reports the
+// problems with the configuration.
+func setupLogging(config observabilityConfig, protocol OTLPProtocol) {
+	if config.debug {
+		logging.SetLogger(logging.ConsoleLogger{})
+	}
+	reportConfig(config, protocol)
+}
+
+// reportConfig reports the problems with the configuration, and describes it
+// when debug logging is configured.
+func reportConfig(config observabilityConfig, protocol OTLPProtocol) {
+	for _, err := range config.environmentErrors {
+		logging.GetLogger().Errorf("ignoring invalid environment variable %v", err)
+	}
+	if config.debug {
+		logging.GetLogger().Infof("observability config: %s", describeConfig(config, protocol))
+	}
+}
+
+// newOtelConfig returns the configuration of the export pipelines for the
+// options.
+func newOtelConfig(sdkKey string, config observabilityConfig, protocol OTLPProtocol) otel.Config {
+	// Attributes from the environment come first, so that the attributes set
+	// by the plugin take precedence.
+	resourceAttributes := append([]attribute.KeyValue{}, config.resourceAttributes...)
+	resourceAttributes = append(resourceAttributes,
 		semconv.TelemetryDistroNameKey.String(metadata.InstrumentationName),
 		semconv.TelemetryDistroVersionKey.String(metadata.InstrumentationVersion),
 		attribute.String(attributes.ProjectIDAttribute, sdkKey),
-	}
+	)
 	if config.environment != "" {
-		attributes = append(attributes, semconv.DeploymentEnvironmentName(config.environment))
+		resourceAttributes = append(resourceAttributes, semconv.DeploymentEnvironmentName(config.environment))
 	}
 	if config.serviceName != "" {
-		attributes = append(attributes, semconv.ServiceNameKey.String(config.serviceName))
+		resourceAttributes = append(resourceAttributes, semconv.ServiceNameKey.String(config.serviceName))
 	}
 	if config.serviceVersion != "" {
-		attributes = append(attributes, semconv.ServiceVersionKey.String(config.serviceVersion))
-	}
-	if config.debug {
-		logging.SetLogger(logging.ConsoleLogger{})
+		resourceAttributes = append(resourceAttributes, semconv.ServiceVersionKey.String(config.serviceVersion))
 	}
 
 	var s trace.Sampler
-	if len(config.samplingRateMap) > 0 {
-		s = getSampler(config.samplingRateMap)
+	if len(config.samplingRateMap) > 0 || len(config.headSamplingRules) > 0 || config.samplingRateLimit > 0 {
+		s = getSampler(config.samplingRateMap, config.headSamplingRules...).withRateLimit(config.samplingRateLimit)
 	} else {
 		s = nil
 	}
-	otel.SetConfig(otel.Config{
-		OtlpEndpoint:           config.otlpEndpoint,
-		ResourceAttributes:     attributes,
-		Sampler:                s,
-		SpanMaxExportBatchSize: config.spanMaxExportBatchSize,
-		SpanMaxQueueSize:       config.spanMaxQueueSize,
-		LogMaxExportBatchSize:  config.logMaxExportBatchSize,
-		LogMaxQueueSize:        config.logMaxQueueSize,
-	})
-	if !config.manualStart {
-		err := otel.StartOTLP()
-		if err != nil {
-			logging.GetLogger().Errorf("failed to start otel: %v", err)
-		}
+	return otel.Config{
+		OtlpEndpoint:            config.otlpEndpoint,
+		OtlpProtocol:            string(protocol),
+		OtlpHeaders:             config.otlpHeaders,
+		OtlpTimeout:             config.otlpTimeout,
+		OtlpCompression:         config.otlpCompression,
+		OtlpTLSConfig:           config.tlsConfig,
+		OtlpProxy:               config.proxy,
+		OtlpHTTPClient:          config.httpClient,
+		ResourceAttributes:      resourceAttributes,
+		Sampler:                 s,
+		SpanMaxExportBatchSize:  config.spanMaxExportBatchSize,
+		SpanMaxQueueSize:        config.spanMaxQueueSize,
+		LogMaxExportBatchSize:   config.logMaxExportBatchSize,
+		LogMaxQueueSize:         config.logMaxQueueSize,
+		SpanBatchTimeout:        config.spanBatchTimeout,
+		SpanExportTimeout:       config.spanExportTimeout,
+		LogBatchTimeout:         config.logBatchTimeout,
+		LogExportTimeout:        config.logExportTimeout,
+		MetricInterval:          config.metricInterval,
+		MetricTimeout:           config.metricTimeout,
+		SpanRetry:               config.spanRetryPolicy.toRetryConfig(),
+		LogRetry:                config.logRetryPolicy.toRetryConfig(),
+		MetricRetry:             config.metricRetryPolicy.toRetryConfig(),
+		KeepErroredTraces:       config.keepErroredTraces,
+		TraceBufferTimeout:      config.traceBufferTimeout,
+		TraceBufferMaxSpans:     config.traceBufferMaxSpans,
+		PersistentQueueDir:      config.persistentQueueDir,
+		PersistentQueueMaxBytes: config.persistentQueueMaxBytes,
+		PersistentQueueMaxAge:   config.persistentQueueMaxAge,
+		SkipGlobalProviders:     config.skipGlobalProviders,
+		AdditionalSpanExporters: config.additionalSpanExporters,
+		AdditionalLogExporters:  config.additionalLogExporters,
+		AdditionalMetricReaders: config.additionalMetricReaders,
+		DisableTelemetry:        config.disableSelfTelemetry,
 	}
-	go func() {
-		cfg, err := getSamplingConfig(sdkKey, config)
-		if err != nil {
-			logging.GetLogger().Errorf("failed to get sampling config: %v", err)
-			return
+}
+
+// resolveOTLPProtocol returns the protocol to export data with. A protocol set
+// with an option takes precedence over the OTEL_EXPORTER_OTLP_PROTOCOL
+// environment variable.
+func resolveOTLPProtocol(protocol OTLPProtocol) OTLPProtocol {
+	source := "option"
+	if protocol == "" {
+		protocol = OTLPProtocol(os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"))
+		source = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	}
+	switch protocol {
+	case "":
+		return OTLPProtocolHTTPProtobuf
+	case OTLPProtocolHTTPProtobuf, OTLPProtocolGRPC:
+		return protocol
+	default:
+		logging.GetLogger().Errorf("unsupported OTLP protocol %q from %s, using %s",
+			protocol, source, OTLPProtocolHTTPProtobuf)
+		return OTLPProtocolHTTPProtobuf
+	}
+}
+
+// describeConfig describes the resolved export configuration for debugging.
+// Header values are omitted, as they often contain credentials.
+func describeConfig(config observabilityConfig, protocol OTLPProtocol) string {
+	headers := slices.Sorted(maps.Keys(config.otlpHeaders))
+	resourceAttributes := make([]string, 0, len(config.resourceAttributes))
+	for _, attr := range config.resourceAttributes {
+		resourceAttributes = append(resourceAttributes, fmt.Sprintf("%s=%s", attr.Key, attr.Value.Emit()))
+	}
+	return fmt.Sprintf("endpoint=%s protocol=%s headers=%v timeout=%v compression=%s "+
+		"tlsConfig=%t proxy=%t httpClient=%t "+
+		"serviceName=%q resourceAttributes=%v "+
+		"spans={batchTimeout=%v exportTimeout=%v maxQueueSize=%d maxExportBatchSize=%d} "+
+		"logs={batchTimeout=%v exportTimeout=%v maxQueueSize=%d maxExportBatchSize=%d} "+
+		"metrics={interval=%v timeout=%v} persistentQueue=%q",
+		config.otlpEndpoint, protocol, headers, config.otlpTimeout, config.otlpCompression,
+		config.tlsConfig != nil, config.proxy != nil, config.httpClient != nil,
+		config.serviceName, resourceAttributes,
+		config.spanBatchTimeout, config.spanExportTimeout, config.spanMaxQueueSize, config.spanMaxExportBatchSize,
+		config.logBatchTimeout, config.logExportTimeout, config.logMaxQueueSize, config.logMaxExportBatchSize,
+		config.metricInterval, config.metricTimeout, config.persistentQueueDir,
+	)
+}
+
+// setLocalSamplingRules applies the sampling rules provided by the application
+// to the sampler.
+func setLocalSamplingRules(sampler *otel.CustomSampler, rules *SamplingRules) {
+	if rules == nil {
+		sampler.SetLocalConfig(nil)
+		sampler.SetLocalRateLimits(otel.RateLimits{})
+		sampler.SetMetricConfig(nil)
+		return
+	}
+	if err := rules.Validate(); err != nil {
+		logging.GetLogger().Errorf("invalid sampling rules: %v", err)
+	}
+	local, err := rules.toSamplingConfig()
+	if err != nil {
+		logging.GetLogger().Errorf("failed to apply sampling rules: %v", err)
+		return
+	}
+	metrics, err := rules.toMetricSamplingConfig()
+	if err != nil {
+		logging.GetLogger().Errorf("failed to apply metric sampling rules: %v", err)
+		return
+	}
+	sampler.SetLocalConfig(local)
+	sampler.SetLocalRateLimits(rules.rateLimits())
+	sampler.SetMetricConfig(metrics)
+}
+
+// startRemoteSamplingConfig starts fetching the sampling configuration from
+// LaunchDarkly for the plugin, replacing the refresher of a previous
+// initialization.
+func startRemoteSamplingConfig(sdkKey string, config observabilityConfig) {
+	ctx := config.context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	startSamplingConfigRefresh(ctx, newRemoteSamplingConfigRefresher(sdkKey, config, otel.SetSamplingConfig))
+}
+
+// newRemoteSamplingConfigRefresher returns a refresher which fetches the
+// sampling configuration from LaunchDarkly and applies it, using the cache
+// of the configuration when one is configured.
+func newRemoteSamplingConfigRefresher(
+	sdkKey string,
+	config observabilityConfig,
+	apply func(*gql.GetSamplingConfigResponse),
+) *samplingConfigRefresher {
+	interval := config.samplingConfigRefreshInterval
+	if interval > 0 && interval < minSamplingConfigRefreshInterval {
+		interval = minSamplingConfigRefreshInterval
+	}
+	if config.samplingConfigCachePath != "" {
+		cache := newSamplingConfigCache(config.samplingConfigCachePath, sdkKey)
+		// The cached configuration is applied before the first fetch, so that
+		// sampling is in effect even when the backend cannot be reached.
+		if cfg, err := cache.load(); err != nil {
+			logging.GetLogger().Infof("not using cached sampling config: %v", err)
+		} else {
+			logging.GetLogger().Infof("using cached sampling config: %v", cfg)
+			apply(cfg)
+		}
+		applyFetched := apply
+		apply = func(cfg *gql.GetSamplingConfigResponse) {
+			applyFetched(cfg)
+			if err := cache.store(cfg); err != nil {
+				logging.GetLogger().Errorf("failed to cache sampling config: %v", err)
+			}
 		}
-		logging.GetLogger().Infof("got sampling config: %v", cfg)
-		otel.SetSamplingConfig(cfg)
-	}()
-	if config.context != nil {
-		go func() {
-			<-config.context.Done()
-			otel.Shutdown()
-		}()
 	}
+	return newSamplingConfigRefresher(
+		func(ctx context.Context) (*gql.GetSamplingConfigResponse, error) {
+			cfg, err := getSamplingConfig(ctx, sdkKey, config)
+			// Fetches which are stopped with the refresher are not counted.
+			if ctx.Err() == nil {
+				otel.RecordSamplingConfigFetch(err)
+			}
+			return cfg, err
+		},
+		apply,
+		interval,
+	)
 }
 
 // PreInitialize initializes the observability plugin independently of the
@@ -95,10 +315,5 @@ func setupOtel(sdkKey string, config observabilityConfig) {
 // readily available, or when observability needs to be initialized earlier than
 // the LaunchDarkly client.
 func PreInitialize(sdkKey string, opts ...Option) {
-	config := defaultConfig()
-	for _, opt := range opts {
-		opt(&config)
-	}
-
-	setupOtel(sdkKey, config)
+	setupOtel(sdkKey, newConfig(opts...))
 }
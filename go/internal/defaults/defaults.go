@@ -1,7 +1,33 @@
 package defaults
 
+import "time"
+
 // DefaultOTLPEndpoint is the default endpoint for the OTLP exporter.
 const DefaultOTLPEndpoint = "https://otel.observability.app.launchdarkly.com:4318"
 
 // DefaultBackendURL is the default endpoint for the backend.
 const DefaultBackendURL = "https://pub.observability.app.launchdarkly.com"
+
+// DefaultBatchTimeout is the maximum delay before a batch of spans or logs is exported.
+const DefaultBatchTimeout = time.Second
+
+// DefaultExportTimeout is the maximum time an export may take.
+const DefaultExportTimeout = 30 * time.Second
+
+// DefaultMetricInterval is the interval between metric exports.
+const DefaultMetricInterval = 5 * time.Second
+
+// DefaultLogMaxQueueSize is the maximum queue size for the log SDK. If more
+// events than this are queued, then events will be dropped until a flush.
+// The trace SDK exports its defaults, but the log SDK does not, so this is
+// copied from the log SDK.
+const DefaultLogMaxQueueSize = 2048
+
+// DurationOrDefault returns the duration when it is greater than zero, and
+// otherwise the default.
+func DurationOrDefault(d, defaultDuration time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return defaultDuration
+}
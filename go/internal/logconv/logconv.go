@@ -0,0 +1,45 @@
+// Package logconv converts the values and levels of logging libraries to
+// OpenTelemetry log values and severities, for the logging integrations.
+package logconv
+
+import (
+	"math"
+	"strconv"
+
+	"go.opentelemetry.io/otel/log"
+
+	o "github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// Logger returns the logger, or the current logger of the plugin when it is
+// nil. The integrations look up the logger of the plugin for each record, as
+// the plugin can be started after them, and restarted.
+func Logger(logger log.Logger) log.Logger {
+	if logger != nil {
+		return logger
+	}
+	return o.GetLogger()
+}
+
+// Uint64Value converts a value to an integer, or to a string when it is too
+// large for an integer.
+func Uint64Value(value uint64) log.Value {
+	if value > math.MaxInt64 {
+		return log.StringValue(strconv.FormatUint(value, 10))
+	}
+	return log.Int64Value(int64(value))
+}
+
+// Severities maps the levels of a logging library to log severities. The
+// libraries order the levels which end the program differently, so each
+// integration has its own.
+type Severities[L comparable] map[L]log.Severity
+
+// Convert converts a level to a log severity. Levels which are not in the
+// map are undefined.
+func (s Severities[L]) Convert(level L) log.Severity {
+	if severity, ok := s[level]; ok {
+		return severity
+	}
+	return log.SeverityUndefined
+}
@@ -0,0 +1,27 @@
+package logconv
+
+import (
+	"math"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+func TestUint64Value(t *testing.T) {
+	if v := Uint64Value(math.MaxInt64); v.Kind() != log.KindInt64 || v.AsInt64() != math.MaxInt64 {
+		t.Errorf("expected values which fit an integer to be integers, got %v", v)
+	}
+	if v := Uint64Value(math.MaxUint64); v.Kind() != log.KindString || v.AsString() != "18446744073709551615" {
+		t.Errorf("expected values too large for an integer to be strings, got %v", v)
+	}
+}
+
+func TestSeverities_Convert(t *testing.T) {
+	severities := Severities[string]{"info": log.SeverityInfo}
+	if severity := severities.Convert("info"); severity != log.SeverityInfo {
+		t.Errorf("expected the severity of the level, got %v", severity)
+	}
+	if severity := severities.Convert("unknown"); severity != log.SeverityUndefined {
+		t.Errorf("expected unknown levels to be undefined, got %v", severity)
+	}
+}
@@ -0,0 +1,171 @@
+// Package logtest provides a logger which keeps the records it emits, and
+// checks of the records, for the tests of the logging integrations.
+package logtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	o "github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// RecordingExporter keeps the records it exports.
+type RecordingExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+// NewRecordingLogger returns a logger which exports its records to a recording
+// exporter as they are emitted.
+func NewRecordingLogger() (log.Logger, *RecordingExporter) {
+	exporter := &RecordingExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	return provider.Logger("test"), exporter
+}
+
+// Export implements sdklog.Exporter.
+func (e *RecordingExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		e.records = append(e.records, r.Clone())
+	}
+	return nil
+}
+
+// Shutdown implements sdklog.Exporter.
+func (e *RecordingExporter) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements sdklog.Exporter.
+func (e *RecordingExporter) ForceFlush(context.Context) error { return nil }
+
+// Exported returns the records which have been exported.
+func (e *RecordingExporter) Exported() []sdklog.Record {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.records
+}
+
+// RecordAttributes returns the attributes of a record by key.
+func RecordAttributes(r sdklog.Record) map[string]log.Value {
+	attrs := map[string]log.Value{}
+	r.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	return attrs
+}
+
+// Expected describes a record which a logging integration is expected to
+// emit. Only the fields which are set are checked.
+type Expected struct {
+	Body         string
+	Severity     log.Severity
+	SeverityText string
+	Timestamp    time.Time
+	// The span the record is associated with.
+	SpanContext trace.SpanContext
+	// Attributes the record has, with their values.
+	Attributes map[string]log.Value
+	// The keys of attributes the record does not have.
+	Absent []string
+}
+
+// CheckRecords checks that the records match the expected records, in order.
+func CheckRecords(t testing.TB, records []sdklog.Record, expected ...Expected) {
+	t.Helper()
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+	for i := range expected {
+		CheckRecord(t, records[i], expected[i])
+	}
+}
+
+// CheckRecord checks that the record has the expected fields.
+func CheckRecord(t testing.TB, r sdklog.Record, expected Expected) {
+	t.Helper()
+	if body := r.Body(); expected.Body != "" && (body.Kind() != log.KindString || body.AsString() != expected.Body) {
+		t.Errorf("expected the body %q, got %v", expected.Body, r.Body())
+	}
+	if expected.Severity != log.SeverityUndefined && r.Severity() != expected.Severity {
+		t.Errorf("expected the severity %v, got %v", expected.Severity, r.Severity())
+	}
+	if expected.SeverityText != "" && r.SeverityText() != expected.SeverityText {
+		t.Errorf("expected the severity text %q, got %q", expected.SeverityText, r.SeverityText())
+	}
+	if !expected.Timestamp.IsZero() && !r.Timestamp().Equal(expected.Timestamp) {
+		t.Errorf("expected the timestamp %v, got %v", expected.Timestamp, r.Timestamp())
+	}
+	if expected.SpanContext.IsValid() &&
+		(r.TraceID() != expected.SpanContext.TraceID() || r.SpanID() != expected.SpanContext.SpanID()) {
+		t.Errorf("expected the record to be associated with span %v, got trace %v and span %v",
+			expected.SpanContext.SpanID(), r.TraceID(), r.SpanID())
+	}
+	attrs := RecordAttributes(r)
+	for key, value := range expected.Attributes {
+		if actual, ok := attrs[key]; !ok || !actual.Equal(value) {
+			t.Errorf("expected the attribute %s to be %v, got %v", key, value, actual)
+		}
+	}
+	for _, key := range expected.Absent {
+		if value, ok := attrs[key]; ok {
+			t.Errorf("expected no attribute %s, got %v", key, value)
+		}
+	}
+}
+
+// StartPlugin starts the export pipelines of the plugin, which export to a
+// test server. Records are only exported when they are flushed. It returns a
+// function which returns the number of export requests for logs the server
+// has received. The pipelines are shut down when the test ends.
+func StartPlugin(t testing.TB) func() int64 {
+	t.Helper()
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/logs" {
+			requests.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	o.SetConfig(o.Config{
+		OtlpEndpoint:     server.URL,
+		OtlpCompression:  o.CompressionNone,
+		SpanMaxQueueSize: sdktrace.DefaultMaxQueueSize,
+		LogBatchTimeout:  time.Hour,
+		DisableTelemetry: true,
+	})
+	if err := o.StartOTLP(); err != nil {
+		t.Fatalf("failed to start the plugin: %v", err)
+	}
+	t.Cleanup(o.Shutdown)
+	return requests.Load
+}
+
+// CheckFlushesFatal checks that an integration exports the pending records
+// when it records a fatal log, before the logging library exits or panics, and
+// does not export them when it records an error. It starts the plugin.
+func CheckFlushesFatal(t *testing.T, logError, logFatal func()) {
+	t.Helper()
+	exportRequests := StartPlugin(t)
+	logError()
+	if n := exportRequests(); n != 0 {
+		t.Errorf("expected errors not to be flushed, got %d export requests", n)
+	}
+	logFatal()
+	if n := exportRequests(); n == 0 {
+		t.Error("expected the records to be exported before the logging library exits or panics")
+	}
+}
@@ -0,0 +1,99 @@
+package otel
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+)
+
+func TestNewComponents(t *testing.T) {
+	ctx := context.Background()
+	server := newTestOTLPServer(t)
+	server.healthy.Store(true)
+	stored := &Config{OtlpEndpoint: "http://stored"}
+	config.Store(stored)
+	defer config.Store(nil)
+	sampler := NewCustomSampler(DefaultSampler)
+	sampler.SetConfig(&gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{{
+			Name: gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfigNameMatchConfig{
+				MatchParts: gql.MatchParts{MatchValue: "dropped-span"},
+			},
+			SamplingRatio: 0,
+		}},
+	})
+
+	components, err := NewComponents(ctx, &Config{
+		OtlpEndpoint:       "http://" + server.endpoint(),
+		OtlpCompression:    CompressionNone,
+		SpanMaxQueueSize:   sdktrace.DefaultMaxQueueSize,
+		ResourceAttributes: []attribute.KeyValue{attribute.String("highlight.project_id", "project")},
+	}, sampler)
+	if err != nil {
+		t.Fatalf("NewComponents failed: %v", err)
+	}
+	if config.Load() != stored {
+		t.Error("expected the stored configuration not to be changed")
+	}
+	if components.Sampler != nil {
+		t.Errorf("expected no sampler without a head sampling configuration, got %v", components.Sampler)
+	}
+	if value, ok := components.Resource.Set().Value("highlight.project_id"); !ok || value.AsString() != "project" {
+		t.Errorf("expected the resource to include the configured attributes, got %v", components.Resource)
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(components.SpanProcessor))
+	for _, name := range []string{"user-span", "dropped-span"} {
+		_, span := tracerProvider.Tracer("test").Start(ctx, name)
+		span.End()
+	}
+	loggerProvider := sdklog.NewLoggerProvider(sdklog.WithProcessor(components.LogProcessor))
+	var record log.Record
+	record.SetBody(log.StringValue("user-log"))
+	loggerProvider.Logger("test").Emit(ctx, record)
+
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		t.Errorf("failed to shut down the tracer provider: %v", err)
+	}
+	if err := loggerProvider.Shutdown(ctx); err != nil {
+		t.Errorf("failed to shut down the logger provider: %v", err)
+	}
+	if err := components.MetricReader.Shutdown(ctx); err != nil {
+		t.Errorf("failed to shut down the metric reader: %v", err)
+	}
+	spans, logs := server.received()
+	if !slices.Equal(spans, []string{"user-span"}) || !slices.Equal(logs, []string{"user-log"}) {
+		t.Errorf("expected the data of the providers to be exported, got %v and %v", spans, logs)
+	}
+}
+
+func TestStartOTLP_SkipGlobalProviders(t *testing.T) {
+	server := newTestOTLPServer(t)
+	server.healthy.Store(true)
+	SetConfig(Config{
+		OtlpEndpoint:        "http://" + server.endpoint(),
+		OtlpCompression:     CompressionNone,
+		SkipGlobalProviders: true,
+	})
+	globalTracerProvider := otel.GetTracerProvider()
+
+	if err := StartOTLP(); err != nil {
+		t.Fatalf("StartOTLP failed: %v", err)
+	}
+	defer Shutdown()
+
+	if otel.GetTracerProvider() != globalTracerProvider {
+		t.Error("expected the global tracer provider not to be set")
+	}
+	if o := otlp.Load(); o == nil || o.tracerProvider == otel.GetTracerProvider() {
+		t.Error("expected the plugin to use its own providers")
+	}
+}
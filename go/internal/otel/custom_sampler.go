@@ -1,16 +1,20 @@
 package otel
 
 import (
+	"math"
 	"math/rand"
 	"regexp"
 	"sync"
+	"sync/atomic"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/log"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
 	"go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
 
 	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+	"github.com/launchdarkly/observability-sdk/go/internal/sampling"
 
 	"github.com/launchdarkly/observability-sdk/go/attributes"
 )
@@ -29,6 +33,8 @@ type ReadonlySpanSubset interface {
 	// Attributes returns the defining attributes of the span.
 	// The order of the returned attributes is not guaranteed to be stable across invocations.
 	Attributes() []attribute.KeyValue
+	// SpanContext returns the unique SpanContext that identifies the span.
+	SpanContext() oteltrace.SpanContext
 }
 
 // SamplingResult represents the result of sampling a span or log
@@ -37,6 +43,10 @@ type SamplingResult struct {
 	Sample bool
 	// Attributes contains additional attributes to add to the span/log
 	Attributes []attribute.KeyValue
+	// ByTraceID indicates the decision was made with the randomness of the
+	// trace ID, so it is not independent of a consistent head sampling
+	// decision for the same trace.
+	ByTraceID bool
 }
 
 // LogSamplingResult represents the result of sampling a log record
@@ -58,8 +68,12 @@ type ExportSampler interface {
 	SampleSpan(span ReadonlySpanSubset) SamplingResult
 	// SampleLog samples a log record and returns the result
 	SampleLog(record sdklog.Record) LogSamplingResult
+	// SampleMetric samples a data point of a metric and returns the result
+	SampleMetric(name string, attrs attribute.Set) MetricSamplingResult
 	// IsSamplingEnabled returns true if sampling is enabled
 	IsSamplingEnabled() bool
+	// IsMetricSamplingEnabled returns true if metric sampling is enabled
+	IsMetricSamplingEnabled() bool
 	// SetConfig sets the sampling configuration
 	SetConfig(config *gql.GetSamplingConfigSamplingSamplingConfig)
 }
@@ -88,6 +102,23 @@ func DefaultSampler(ratio int) bool {
 	return int(rand.Float64()*float64(ratio)) == 0
 }
 
+// TraceIDSampler determines if an item should be sampled based on the sampling
+// ratio and the ID of the trace it belongs to. Every item of a trace receives
+// the same decision for a given ratio, including in other services which use
+// the same method. The decision uses the consistent probability sampling of
+// OpenTelemetry, so an item sampled at a ratio is also sampled at any lower
+// ratio, and by a head sampler with a higher probability.
+func TraceIDSampler(traceID oteltrace.TraceID, ratio int) bool {
+	if ratio == 1 {
+		return true
+	}
+	if ratio <= 0 {
+		return false
+	}
+	threshold := sampling.ThresholdForProbability(1 / float64(ratio))
+	return sampling.Sampled(sampling.TraceIDRandomness(traceID), threshold)
+}
+
 // implMatchParts is a wrapper for use when matching raw value types.
 // Such as wrapping the values in a slice.
 type implMatchParts struct {
@@ -112,11 +143,31 @@ func toMatchParts(value interface{}) matchParts {
 // CustomSampler is a custom sampler that uses sampling configuration to
 // determine if a span should be sampled
 type CustomSampler struct {
-	sampler     SamplerFunc
-	config      *gql.GetSamplingConfigSamplingSamplingConfig
-	regexCache  map[string]*regexp.Regexp
-	configMutex sync.RWMutex
-	regexMutex  sync.RWMutex
+	sampler SamplerFunc
+	// The configuration used for sampling decisions. This combines the remote
+	// and local configurations.
+	config       *gql.GetSamplingConfigSamplingSamplingConfig
+	remoteConfig *gql.GetSamplingConfigSamplingSamplingConfig
+	localConfig  *gql.GetSamplingConfigSamplingSamplingConfig
+	regexCache   map[string]*regexp.Regexp
+	configMutex  sync.RWMutex
+	regexMutex   sync.RWMutex
+	// When set, items with a trace ID are sampled with the TraceIDSampler
+	// instead of the sampler function.
+	useTraceID atomic.Bool
+	// Rate limiters for the rules of the local configuration, in the same
+	// order as the rules. A nil limiter means the rule is not rate limited.
+	localSpanLimiters []*sampling.RateLimiter
+	localLogLimiters  []*sampling.RateLimiter
+	metricConfig      []MetricSamplingConfig
+}
+
+// RateLimits are the maximum number of items each second for the rules of a
+// local sampling configuration, in the same order as the rules. A limit of
+// zero means the rule is not rate limited.
+type RateLimits struct {
+	Spans []float64
+	Logs  []float64
 }
 
 // NewCustomSampler creates a new CustomSampler with the given sampler function
@@ -130,11 +181,114 @@ func NewCustomSampler(sampler SamplerFunc) *CustomSampler {
 	}
 }
 
-// SetConfig sets the sampling configuration
+// SetConfig sets the sampling configuration received from LaunchDarkly
 func (cs *CustomSampler) SetConfig(config *gql.GetSamplingConfigSamplingSamplingConfig) {
 	cs.configMutex.Lock()
 	defer cs.configMutex.Unlock()
-	cs.config = config
+	cs.remoteConfig = config
+	cs.config = mergeSamplingConfigs(cs.remoteConfig, cs.localConfig)
+}
+
+// SetLocalConfig sets the sampling configuration provided by the application.
+// The configuration set with SetConfig is layered on top of the local
+// configuration, so its rules are matched first.
+func (cs *CustomSampler) SetLocalConfig(config *gql.GetSamplingConfigSamplingSamplingConfig) {
+	cs.configMutex.Lock()
+	defer cs.configMutex.Unlock()
+	cs.localConfig = config
+	cs.config = mergeSamplingConfigs(cs.remoteConfig, cs.localConfig)
+}
+
+// SetLocalRateLimits sets the rate limits for the rules of the local configuration.
+// The items matching a rate limited rule are sampled with the ratio of the rule,
+// and then at most the limit of those items are sampled each second.
+func (cs *CustomSampler) SetLocalRateLimits(limits RateLimits) {
+	cs.configMutex.Lock()
+	defer cs.configMutex.Unlock()
+	cs.localSpanLimiters = newRateLimiters(limits.Spans)
+	cs.localLogLimiters = newRateLimiters(limits.Logs)
+}
+
+func newRateLimiters(limits []float64) []*sampling.RateLimiter {
+	limiters := make([]*sampling.RateLimiter, len(limits))
+	for i, limit := range limits {
+		if limit > 0 {
+			limiters[i] = sampling.NewRateLimiter(limit)
+		}
+	}
+	return limiters
+}
+
+// localLimiter returns the rate limiter for a rule of the merged configuration.
+// The rules of the local configuration follow the rules of the remote one.
+// Must be called with the config lock held.
+func localLimiter(limiters []*sampling.RateLimiter, index int, remoteRules int) *sampling.RateLimiter {
+	index -= remoteRules
+	if index < 0 || index >= len(limiters) {
+		return nil
+	}
+	return limiters[index]
+}
+
+// sampleLimited makes the sampling decision for an item matching a rule with
+// the given ratio and limiter. It returns the decision and the effective
+// ratio the item was sampled with.
+func (cs *CustomSampler) sampleLimited(
+	ratio int,
+	limiter *sampling.RateLimiter,
+	traceID oteltrace.TraceID,
+) (bool, int64) {
+	sample := cs.sample(ratio, traceID)
+	if !sample || limiter == nil {
+		return sample, int64(ratio)
+	}
+	allowed, probability := limiter.Allow()
+	return allowed, int64(math.Round(float64(ratio) / probability))
+}
+
+// mergeSamplingConfigs combines two configurations. The first matching rule
+// decides how an item is sampled, so the rules of the upper configuration
+// take precedence over the rules of the lower configuration.
+func mergeSamplingConfigs(
+	upper *gql.GetSamplingConfigSamplingSamplingConfig,
+	lower *gql.GetSamplingConfigSamplingSamplingConfig,
+) *gql.GetSamplingConfigSamplingSamplingConfig {
+	if lower == nil {
+		return upper
+	}
+	if upper == nil {
+		return lower
+	}
+	merged := &gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: make([]gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig, 0,
+			len(upper.Spans)+len(lower.Spans)),
+		Logs: make([]gql.GetSamplingConfigSamplingSamplingConfigLogsLogSamplingConfig, 0,
+			len(upper.Logs)+len(lower.Logs)),
+	}
+	merged.Spans = append(append(merged.Spans, upper.Spans...), lower.Spans...)
+	merged.Logs = append(append(merged.Logs, upper.Logs...), lower.Logs...)
+	return merged
+}
+
+// SetTraceIDSampling sets whether items with a trace ID are sampled based on
+// that ID, so that every item of a trace receives the same decision. Items
+// without a trace ID continue to use the sampler function.
+func (cs *CustomSampler) SetTraceIDSampling(enabled bool) {
+	cs.useTraceID.Store(enabled)
+}
+
+// samplesByTraceID returns true if an item with the trace ID is sampled based
+// on that ID.
+func (cs *CustomSampler) samplesByTraceID(traceID oteltrace.TraceID) bool {
+	return cs.useTraceID.Load() && traceID.IsValid()
+}
+
+// sample makes the sampling decision for an item with the given ratio.
+func (cs *CustomSampler) sample(ratio int, traceID oteltrace.TraceID) bool {
+	if cs.samplesByTraceID(traceID) {
+		return TraceIDSampler(traceID, ratio)
+	}
+	return cs.sampler(ratio)
 }
 
 // IsSamplingEnabled returns true if sampling is enabled
@@ -332,7 +486,7 @@ func matchAttributeValue(v attribute.Value, matchValue interface{}) bool {
 			return false
 		}
 		// JSON match value will be a float64.
-		return compareNumeric(asFloat64, v.AsFloat64())
+		return compareNumeric(asFloat64, v.AsInt64())
 	case attribute.FLOAT64:
 		// Only float64 supported from the config.
 		asFloat64, ok := matchValue.(float64)
@@ -649,13 +803,23 @@ func (cs *CustomSampler) SampleSpan(span ReadonlySpanSubset) SamplingResult {
 	defer cs.configMutex.RUnlock()
 
 	if cs.config != nil && len(cs.config.Spans) > 0 {
-		for _, spanConfig := range cs.config.Spans {
+		remoteRules := 0
+		if cs.remoteConfig != nil {
+			remoteRules = len(cs.remoteConfig.Spans)
+		}
+		for i, spanConfig := range cs.config.Spans {
 			if cs.matchesSpanConfig(&spanConfig, span) {
+				sample, ratio := cs.sampleLimited(
+					spanConfig.GetSamplingRatio(),
+					localLimiter(cs.localSpanLimiters, i, remoteRules),
+					span.SpanContext().TraceID(),
+				)
 				return SamplingResult{
-					Sample: cs.sampler(spanConfig.GetSamplingRatio()),
+					Sample: sample,
 					Attributes: []attribute.KeyValue{
-						attribute.Int64(attributes.AttrSamplingRatio, int64(spanConfig.GetSamplingRatio())),
+						attribute.Int64(attributes.AttrSamplingRatio, ratio),
 					},
+					ByTraceID: cs.samplesByTraceID(span.SpanContext().TraceID()),
 				}
 			}
 		}
@@ -665,20 +829,47 @@ func (cs *CustomSampler) SampleSpan(span ReadonlySpanSubset) SamplingResult {
 	return SamplingResult{Sample: true}
 }
 
+// MatchSpan returns the index of the first span rule of the configuration
+// which matches the span, or -1 if no rule matches. This allows the matching
+// of the sampler to be used for rules which are sampled in other ways.
+func (cs *CustomSampler) MatchSpan(span ReadonlySpanSubset) int {
+	cs.configMutex.RLock()
+	defer cs.configMutex.RUnlock()
+
+	if cs.config == nil {
+		return -1
+	}
+	for i, spanConfig := range cs.config.Spans {
+		if cs.matchesSpanConfig(&spanConfig, span) {
+			return i
+		}
+	}
+	return -1
+}
+
 // SampleLog samples a log record based on the sampling configuration
 func (cs *CustomSampler) SampleLog(record sdklog.Record) LogSamplingResult {
 	cs.configMutex.RLock()
 	defer cs.configMutex.RUnlock()
 
 	if cs.config != nil && len(cs.config.Logs) > 0 {
-		for _, logConfig := range cs.config.Logs {
+		remoteRules := 0
+		if cs.remoteConfig != nil {
+			remoteRules = len(cs.remoteConfig.Logs)
+		}
+		for i, logConfig := range cs.config.Logs {
 			if cs.matchesLogConfig(&logConfig, record) {
+				sample, ratio := cs.sampleLimited(
+					logConfig.GetSamplingRatio(),
+					localLimiter(cs.localLogLimiters, i, remoteRules),
+					record.TraceID(),
+				)
 				return LogSamplingResult{
-					Sample: cs.sampler(logConfig.GetSamplingRatio()),
+					Sample: sample,
 					Attributes: []log.KeyValue{
 						// The log stores Int and Int64 as an Int64.
 						// We are being explicit here in case that detail changes and ints are directly supported.
-						log.Int64(attributes.AttrSamplingRatio, int64(logConfig.GetSamplingRatio())),
+						log.Int64(attributes.AttrSamplingRatio, ratio),
 					},
 				}
 			}
@@ -1,6 +1,7 @@
 package otel
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -11,6 +12,7 @@ import (
 	"go.opentelemetry.io/otel/log"
 	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/launchdarkly/observability-sdk/go/internal/gql"
 	"go.opentelemetry.io/otel/sdk/log/logtest"
@@ -58,9 +60,14 @@ type logTestScenario struct {
 }
 
 type readonlySpanSubsetImpl struct {
-	name       string
-	attributes []attribute.KeyValue
-	events     []sdktrace.Event
+	name        string
+	attributes  []attribute.KeyValue
+	events      []sdktrace.Event
+	spanContext trace.SpanContext
+}
+
+func (s *readonlySpanSubsetImpl) SpanContext() trace.SpanContext {
+	return s.spanContext
 }
 
 func (s *readonlySpanSubsetImpl) Name() string {
@@ -350,3 +357,202 @@ func TestLogScenarios(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchAttributeValue_Int64(t *testing.T) {
+	// Integer attributes were read as floats, which reinterprets their bits, so
+	// they never matched the value in the configuration.
+	if !matchAttributeValue(attribute.Int64Value(42), float64(42)) {
+		t.Error("expected an integer attribute to match an equal value")
+	}
+	if matchAttributeValue(attribute.Int64Value(42), float64(43)) {
+		t.Error("expected an integer attribute not to match a different value")
+	}
+}
+
+func TestCustomSampler_RemoteConfigLayeredOnLocalConfig(t *testing.T) {
+	sampler := NewCustomSampler(neverSampler)
+
+	local := &gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{
+			{SamplingRatio: 10},
+		},
+	}
+	local.Spans[0].Name.MatchValue = "shared"
+	remote := &gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{
+			{SamplingRatio: 1},
+		},
+	}
+	remote.Spans[0].Name.MatchValue = "shared"
+
+	sampler.SetLocalConfig(local)
+	if !sampler.IsSamplingEnabled() {
+		t.Fatal("expected sampling to be enabled with only a local config")
+	}
+	res := sampler.SampleSpan(&readonlySpanSubsetImpl{name: "shared"})
+	if res.Sample || res.Attributes[0].Value.AsInt64() != 10 {
+		t.Errorf("expected local rule to apply, got %+v", res)
+	}
+
+	sampler.SetConfig(remote)
+	res = sampler.SampleSpan(&readonlySpanSubsetImpl{name: "shared"})
+	if res.Attributes[0].Value.AsInt64() != 1 {
+		t.Errorf("expected remote rule to take precedence, got %+v", res)
+	}
+
+	sampler.SetLocalConfig(nil)
+	res = sampler.SampleSpan(&readonlySpanSubsetImpl{name: "shared"})
+	if res.Attributes[0].Value.AsInt64() != 1 {
+		t.Errorf("expected remote rule to remain after clearing local config, got %+v", res)
+	}
+}
+
+func traceIDWithLowerBits(lower uint64) trace.TraceID {
+	var id trace.TraceID
+	id[0] = 1
+	binary.BigEndian.PutUint64(id[8:16], lower)
+	return id
+}
+
+func TestTraceIDSampler(t *testing.T) {
+	// The lower 56 bits of the ID are compared against the rejection
+	// threshold of 2^56 - 2^56 / ratio.
+	low := traceIDWithLowerBits(0)
+	high := traceIDWithLowerBits(^uint64(0))
+	belowHalf := traceIDWithLowerBits(uint64(1)<<55 - 2)
+	aboveHalf := traceIDWithLowerBits(uint64(1)<<55 + 2)
+
+	cases := []struct {
+		name     string
+		traceID  trace.TraceID
+		ratio    int
+		expected bool
+	}{
+		{"ratio 1 always samples", high, 1, true},
+		{"ratio 0 never samples", low, 0, false},
+		{"high ID sampled at ratio 100", high, 100, true},
+		{"low ID not sampled at ratio 2", low, 2, false},
+		{"ID below threshold not sampled at ratio 2", belowHalf, 2, false},
+		{"ID above threshold sampled at ratio 2", aboveHalf, 2, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := TraceIDSampler(c.traceID, c.ratio); actual != c.expected {
+				t.Errorf("expected %t, got %t", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTraceIDSampler_Distribution(t *testing.T) {
+	// Sequential lower bits spread evenly across the range of the ID.
+	sampled := 0
+	const total = 10000
+	step := ^uint64(0) / total
+	for i := uint64(0); i < total; i++ {
+		if TraceIDSampler(traceIDWithLowerBits(i*step), 10) {
+			sampled++
+		}
+	}
+	if sampled < 950 || sampled > 1050 {
+		t.Errorf("expected about 1000 of %d IDs to be sampled at ratio 10, got %d", total, sampled)
+	}
+}
+
+func TestCustomSampler_TraceIDSampling(t *testing.T) {
+	// The sampler function would sample out everything, so any sampled item
+	// shows the trace ID was used.
+	sampler := NewCustomSampler(neverSampler)
+	sampler.SetTraceIDSampling(true)
+	config := &gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{{SamplingRatio: 2}},
+		Logs:  []gql.GetSamplingConfigSamplingSamplingConfigLogsLogSamplingConfig{{SamplingRatio: 2}},
+	}
+	sampler.SetConfig(config)
+
+	keep := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceIDWithLowerBits(^uint64(0)),
+		SpanID:  trace.SpanID{1},
+	})
+	drop := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceIDWithLowerBits(0),
+		SpanID:  trace.SpanID{1},
+	})
+
+	if !sampler.SampleSpan(&readonlySpanSubsetImpl{name: "a", spanContext: keep}).Sample {
+		t.Error("expected span in a kept trace to be sampled")
+	}
+	if !sampler.SampleSpan(&readonlySpanSubsetImpl{name: "b", spanContext: keep}).Sample {
+		t.Error("expected every span in a kept trace to be sampled")
+	}
+	if sampler.SampleSpan(&readonlySpanSubsetImpl{name: "a", spanContext: drop}).Sample {
+		t.Error("expected span in a dropped trace not to be sampled")
+	}
+
+	keptLog := logtest.RecordFactory{TraceID: keep.TraceID(), SpanID: keep.SpanID()}.NewRecord()
+	if !sampler.SampleLog(keptLog).Sample {
+		t.Error("expected log in a kept trace to be sampled")
+	}
+	droppedLog := logtest.RecordFactory{TraceID: drop.TraceID(), SpanID: drop.SpanID()}.NewRecord()
+	if sampler.SampleLog(droppedLog).Sample {
+		t.Error("expected log in a dropped trace not to be sampled")
+	}
+	// A log without a trace falls back to the sampler function.
+	if sampler.SampleLog(logtest.RecordFactory{}.NewRecord()).Sample {
+		t.Error("expected log without a trace to use the sampler function")
+	}
+
+	sampler.SetTraceIDSampling(false)
+	if sampler.SampleSpan(&readonlySpanSubsetImpl{name: "a", spanContext: keep}).Sample {
+		t.Error("expected the sampler function to be used when trace ID sampling is disabled")
+	}
+}
+
+func TestCustomSampler_LocalRateLimits(t *testing.T) {
+	sampler := NewCustomSampler(nil)
+	local := &gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{
+			{
+				Name: gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfigNameMatchConfig{
+					MatchParts: gql.MatchParts{MatchValue: "limited"},
+				},
+				SamplingRatio: 1,
+			},
+		},
+		Logs: []gql.GetSamplingConfigSamplingSamplingConfigLogsLogSamplingConfig{{SamplingRatio: 1}},
+	}
+	remote := &gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{
+			{
+				Name: gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfigNameMatchConfig{
+					MatchParts: gql.MatchParts{MatchValue: "remote"},
+				},
+				SamplingRatio: 1,
+			},
+		},
+	}
+	sampler.SetLocalConfig(local)
+	sampler.SetConfig(remote)
+	sampler.SetLocalRateLimits(RateLimits{Spans: []float64{1}, Logs: []float64{1}})
+
+	if !sampler.SampleSpan(&readonlySpanSubsetImpl{name: "limited"}).Sample {
+		t.Error("expected the first span within the limit to be sampled")
+	}
+	if sampler.SampleSpan(&readonlySpanSubsetImpl{name: "limited"}).Sample {
+		t.Error("expected a span over the limit not to be sampled")
+	}
+	// The limits belong to the local rules, so the remote rule is not limited.
+	for range 3 {
+		if !sampler.SampleSpan(&readonlySpanSubsetImpl{name: "remote"}).Sample {
+			t.Error("expected spans matching the remote rule not to be limited")
+		}
+	}
+
+	record := logtest.RecordFactory{}.NewRecord()
+	if res := sampler.SampleLog(record); !res.Sample || res.Attributes[0].Value.AsInt64() != 1 {
+		t.Errorf("expected the first log within the limit to be sampled with ratio 1, got %+v", res)
+	}
+	if sampler.SampleLog(record).Sample {
+		t.Error("expected a log over the limit not to be sampled")
+	}
+}
@@ -0,0 +1,96 @@
+package otel
+
+import (
+	"context"
+	"errors"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// The additional exporters which only receive sampled data are sent the batches
+// exported to LaunchDarkly, after the sampling configuration has been applied,
+// so that sampling is only applied once and every exporter receives the same
+// data. Each exporter keeps its own batch processor, which the exported data is
+// emitted to.
+
+// fanOutSpanExporter emits the spans it exports to the processors.
+type fanOutSpanExporter struct {
+	sdktrace.SpanExporter
+	processors []sdktrace.SpanProcessor
+}
+
+// ExportSpans implements trace.SpanExporter.
+func (e fanOutSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	for _, processor := range e.processors {
+		for _, s := range spans {
+			processor.OnEnd(s)
+		}
+	}
+	return e.SpanExporter.ExportSpans(ctx, spans)
+}
+
+// fanOutSpanProcessor flushes and shuts down the processors which its exporter
+// emits spans to, after the spans it holds have been exported.
+type fanOutSpanProcessor struct {
+	sdktrace.SpanProcessor
+	processors []sdktrace.SpanProcessor
+}
+
+func (p fanOutSpanProcessor) ForceFlush(ctx context.Context) error {
+	err := p.SpanProcessor.ForceFlush(ctx)
+	for _, processor := range p.processors {
+		err = errors.Join(err, processor.ForceFlush(ctx))
+	}
+	return err
+}
+
+func (p fanOutSpanProcessor) Shutdown(ctx context.Context) error {
+	err := p.SpanProcessor.Shutdown(ctx)
+	for _, processor := range p.processors {
+		err = errors.Join(err, processor.Shutdown(ctx))
+	}
+	return err
+}
+
+// fanOutLogExporter emits the log records it exports to the processors.
+type fanOutLogExporter struct {
+	sdklog.Exporter
+	processors []sdklog.Processor
+}
+
+// Export implements log.Exporter.
+func (e fanOutLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	for _, processor := range e.processors {
+		for i := range records {
+			// The processors may modify the records they are emitted, so each
+			// is emitted a copy.
+			record := records[i].Clone()
+			_ = processor.OnEmit(ctx, &record)
+		}
+	}
+	return e.Exporter.Export(ctx, records)
+}
+
+// fanOutLogProcessor flushes and shuts down the processors which its exporter
+// emits log records to, after the records it holds have been exported.
+type fanOutLogProcessor struct {
+	sdklog.Processor
+	processors []sdklog.Processor
+}
+
+func (p fanOutLogProcessor) ForceFlush(ctx context.Context) error {
+	err := p.Processor.ForceFlush(ctx)
+	for _, processor := range p.processors {
+		err = errors.Join(err, processor.ForceFlush(ctx))
+	}
+	return err
+}
+
+func (p fanOutLogProcessor) Shutdown(ctx context.Context) error {
+	err := p.Processor.Shutdown(ctx)
+	for _, processor := range p.processors {
+		err = errors.Join(err, processor.Shutdown(ctx))
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package otel
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func testServerConfig(server *testOTLPServer) Config {
+	return Config{
+		OtlpEndpoint:     "http://" + server.endpoint(),
+		OtlpCompression:  CompressionNone,
+		SpanMaxQueueSize: sdktrace.DefaultMaxQueueSize,
+	}
+}
+
+func exportSpan(t *testing.T, name string) {
+	t.Helper()
+	_, span := GetTracer().Start(context.Background(), name)
+	span.End()
+	if err := Flush(context.Background()); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+}
+
+func TestStartOTLP_AfterShutdown(t *testing.T) {
+	server := newTestOTLPServer(t)
+	server.healthy.Store(true)
+	customSampler.SetConfig(nil)
+	SetConfig(testServerConfig(server))
+
+	if err := StartOTLP(); err != nil {
+		t.Fatalf("StartOTLP failed: %v", err)
+	}
+	t.Cleanup(Shutdown)
+	exportSpan(t, "first")
+	RecordCount(context.Background(), "requests", 1)
+	Shutdown()
+
+	if err := StartOTLP(); err != nil {
+		t.Fatalf("StartOTLP after Shutdown failed: %v", err)
+	}
+	int64CountersLock.RLock()
+	cached := len(int64Counters)
+	int64CountersLock.RUnlock()
+	if cached != 0 {
+		t.Errorf("expected the instruments of the previous meter to be discarded, got %d", cached)
+	}
+	exportSpan(t, "second")
+
+	if spans, _ := server.received(); !slices.Equal(spans, []string{"first", "second"}) {
+		t.Errorf("expected spans to be exported after starting again, got %v", spans)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	first := newTestOTLPServer(t)
+	second := newTestOTLPServer(t)
+	first.healthy.Store(true)
+	second.healthy.Store(true)
+	customSampler.SetConfig(nil)
+
+	// Restarting before starting only changes the configuration.
+	SetConfig(testServerConfig(first))
+	if err := Restart(context.Background()); err != nil || otlp.Load() != nil {
+		t.Fatalf("expected Restart not to start OTLP, got %v", err)
+	}
+
+	if err := StartOTLP(); err != nil {
+		t.Fatalf("StartOTLP failed: %v", err)
+	}
+	t.Cleanup(Shutdown)
+	additional := &syncTestExporter{}
+	conf := testServerConfig(first)
+	conf.AdditionalSpanExporters = []AdditionalSpanExporter{{Exporter: additional}}
+	SetConfig(conf)
+	if err := Restart(context.Background()); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	exportSpan(t, "before")
+
+	SetConfig(testServerConfig(second))
+	if err := Restart(context.Background()); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	exportSpan(t, "after")
+
+	if spans, _ := first.received(); !slices.Equal(spans, []string{"before"}) {
+		t.Errorf("expected the first endpoint to receive spans before the restart, got %v", spans)
+	}
+	if spans, _ := second.received(); !slices.Equal(spans, []string{"after"}) {
+		t.Errorf("expected the second endpoint to receive spans after the restart, got %v", spans)
+	}
+	if names := additional.names(); !slices.Equal(names, []string{"before"}) || !additional.shutdown {
+		t.Errorf("expected the additional exporter to be shut down by the restart, got %v", names)
+	}
+}
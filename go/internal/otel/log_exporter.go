@@ -9,6 +9,8 @@ import (
 type logExporter struct {
 	sdklog.Exporter
 	sampler ExportSampler
+	// When set, the log records which are not exported are counted.
+	telemetry *signalTelemetry
 }
 
 func newLogExporter(exporter sdklog.Exporter, sampler ExportSampler) *logExporter {
@@ -31,6 +33,9 @@ func (e *logExporter) Export(ctx context.Context, records []sdklog.Record) error
 			}
 		}
 	}
+	if e.telemetry != nil {
+		e.telemetry.sampledOut.Add(int64(len(records) - len(exportedRecords)))
+	}
 	return e.Exporter.Export(ctx, exportedRecords)
 }
 
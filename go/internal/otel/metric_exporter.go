@@ -0,0 +1,203 @@
+package otel
+
+import (
+	"context"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// metricExporter is a metric exporter which applies the metric configuration
+// of the sampler to data points before they are exported. Data points can be
+// dropped, or have attributes removed. When removing attributes makes data
+// points of a metric identical, they are combined: sums are added, the latest
+// gauge value is kept and histograms with the same bounds are merged.
+// Exponential histograms and summaries are not combined.
+type metricExporter struct {
+	sdkmetric.Exporter
+	sampler ExportSampler
+}
+
+func newMetricExporter(exporter sdkmetric.Exporter, sampler ExportSampler) *metricExporter {
+	return &metricExporter{Exporter: exporter, sampler: sampler}
+}
+
+// Export implements sdkmetric.Exporter.
+func (e *metricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	if !e.sampler.IsMetricSamplingEnabled() {
+		return e.Exporter.Export(ctx, rm)
+	}
+	// The reader reuses the data it passes to the exporter, so the sampled
+	// data is built separately rather than modified in place.
+	sampled := &metricdata.ResourceMetrics{
+		Resource:     rm.Resource,
+		ScopeMetrics: make([]metricdata.ScopeMetrics, 0, len(rm.ScopeMetrics)),
+	}
+	for _, sm := range rm.ScopeMetrics {
+		metrics := make([]metricdata.Metrics, 0, len(sm.Metrics))
+		for _, m := range sm.Metrics {
+			if data, ok := e.sampleData(m.Name, m.Data); ok {
+				m.Data = data
+				metrics = append(metrics, m)
+			}
+		}
+		if len(metrics) > 0 {
+			sampled.ScopeMetrics = append(sampled.ScopeMetrics, metricdata.ScopeMetrics{
+				Scope:   sm.Scope,
+				Metrics: metrics,
+			})
+		}
+	}
+	return e.Exporter.Export(ctx, sampled)
+}
+
+// sampleData applies the sampler to the data points of a metric. It returns
+// false if no data points remain.
+func (e *metricExporter) sampleData(name string, data metricdata.Aggregation) (metricdata.Aggregation, bool) {
+	switch d := data.(type) {
+	case metricdata.Gauge[int64]:
+		d.DataPoints = sampleDataPoints(e.sampler, name, d.DataPoints, dataPointAttributes[int64], mergeGauge[int64])
+		return d, len(d.DataPoints) > 0
+	case metricdata.Gauge[float64]:
+		d.DataPoints = sampleDataPoints(e.sampler, name, d.DataPoints, dataPointAttributes[float64], mergeGauge[float64])
+		return d, len(d.DataPoints) > 0
+	case metricdata.Sum[int64]:
+		d.DataPoints = sampleDataPoints(e.sampler, name, d.DataPoints, dataPointAttributes[int64], mergeSum[int64])
+		return d, len(d.DataPoints) > 0
+	case metricdata.Sum[float64]:
+		d.DataPoints = sampleDataPoints(e.sampler, name, d.DataPoints, dataPointAttributes[float64], mergeSum[float64])
+		return d, len(d.DataPoints) > 0
+	case metricdata.Histogram[int64]:
+		d.DataPoints = sampleDataPoints(e.sampler, name, d.DataPoints, histogramAttributes[int64], mergeHistogram[int64])
+		return d, len(d.DataPoints) > 0
+	case metricdata.Histogram[float64]:
+		d.DataPoints = sampleDataPoints(e.sampler, name, d.DataPoints, histogramAttributes[float64], mergeHistogram[float64])
+		return d, len(d.DataPoints) > 0
+	case metricdata.ExponentialHistogram[int64]:
+		d.DataPoints = sampleDataPoints(e.sampler, name, d.DataPoints, exponentialHistogramAttributes[int64], nil)
+		return d, len(d.DataPoints) > 0
+	case metricdata.ExponentialHistogram[float64]:
+		d.DataPoints = sampleDataPoints(e.sampler, name, d.DataPoints, exponentialHistogramAttributes[float64], nil)
+		return d, len(d.DataPoints) > 0
+	case metricdata.Summary:
+		d.DataPoints = sampleDataPoints(e.sampler, name, d.DataPoints, summaryAttributes, nil)
+		return d, len(d.DataPoints) > 0
+	default:
+		return data, true
+	}
+}
+
+// sampleDataPoints applies the sampler to data points. Data points which have
+// the same attributes after attributes are removed are combined with merge.
+// The merge function returns false if the data points cannot be combined, and
+// a nil merge function never combines data points.
+func sampleDataPoints[DP any](
+	sampler ExportSampler,
+	name string,
+	points []DP,
+	attrs func(*DP) *attribute.Set,
+	merge func(into *DP, from DP) bool,
+) []DP {
+	sampled := make([]DP, 0, len(points))
+	indexes := make(map[attribute.Distinct]int, len(points))
+	for _, point := range points {
+		set := attrs(&point)
+		res := sampler.SampleMetric(name, *set)
+		if !res.Sample {
+			continue
+		}
+		if res.Filter != nil {
+			*set, _ = set.Filter(res.Filter)
+		}
+		if merge != nil {
+			if i, ok := indexes[set.Equivalent()]; ok && merge(&sampled[i], point) {
+				continue
+			}
+			indexes[set.Equivalent()] = len(sampled)
+		}
+		sampled = append(sampled, point)
+	}
+	return sampled
+}
+
+func dataPointAttributes[N int64 | float64](p *metricdata.DataPoint[N]) *attribute.Set {
+	return &p.Attributes
+}
+
+func histogramAttributes[N int64 | float64](p *metricdata.HistogramDataPoint[N]) *attribute.Set {
+	return &p.Attributes
+}
+
+func exponentialHistogramAttributes[N int64 | float64](p *metricdata.ExponentialHistogramDataPoint[N]) *attribute.Set {
+	return &p.Attributes
+}
+
+func summaryAttributes(p *metricdata.SummaryDataPoint) *attribute.Set {
+	return &p.Attributes
+}
+
+// mergeGauge keeps the latest value of a gauge.
+func mergeGauge[N int64 | float64](into *metricdata.DataPoint[N], from metricdata.DataPoint[N]) bool {
+	if from.Time.After(into.Time) {
+		*into = from
+	}
+	return true
+}
+
+// mergeSum adds the values of a sum.
+func mergeSum[N int64 | float64](into *metricdata.DataPoint[N], from metricdata.DataPoint[N]) bool {
+	into.Value += from.Value
+	if from.StartTime.Before(into.StartTime) {
+		into.StartTime = from.StartTime
+	}
+	if from.Time.After(into.Time) {
+		into.Time = from.Time
+	}
+	into.Exemplars = append(slices.Clip(into.Exemplars), from.Exemplars...)
+	return true
+}
+
+// mergeHistogram combines histograms with the same bucket bounds.
+func mergeHistogram[N int64 | float64](
+	into *metricdata.HistogramDataPoint[N],
+	from metricdata.HistogramDataPoint[N],
+) bool {
+	if !slices.Equal(into.Bounds, from.Bounds) || len(into.BucketCounts) != len(from.BucketCounts) {
+		return false
+	}
+	bucketCounts := slices.Clone(into.BucketCounts)
+	for i, count := range from.BucketCounts {
+		bucketCounts[i] += count
+	}
+	into.BucketCounts = bucketCounts
+	into.Count += from.Count
+	into.Sum += from.Sum
+	into.Min = mergeExtrema(into.Min, from.Min, func(a, b N) bool { return b < a })
+	into.Max = mergeExtrema(into.Max, from.Max, func(a, b N) bool { return b > a })
+	if from.StartTime.Before(into.StartTime) {
+		into.StartTime = from.StartTime
+	}
+	if from.Time.After(into.Time) {
+		into.Time = from.Time
+	}
+	into.Exemplars = append(slices.Clip(into.Exemplars), from.Exemplars...)
+	return true
+}
+
+// mergeExtrema returns the extreme of two values, where replace reports
+// whether the second value is more extreme than the first.
+func mergeExtrema[N int64 | float64](a, b metricdata.Extrema[N], replace func(a, b N) bool) metricdata.Extrema[N] {
+	av, aok := a.Value()
+	bv, bok := b.Value()
+	if !bok {
+		return a
+	}
+	if !aok || replace(av, bv) {
+		return b
+	}
+	return a
+}
+
+var _ sdkmetric.Exporter = &metricExporter{}
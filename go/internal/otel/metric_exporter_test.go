@@ -0,0 +1,209 @@
+package otel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+)
+
+type testMetricExporter struct {
+	exported []*metricdata.ResourceMetrics
+}
+
+func (e *testMetricExporter) Temporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(kind)
+}
+
+func (e *testMetricExporter) Aggregation(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
+
+func (e *testMetricExporter) Export(_ context.Context, rm *metricdata.ResourceMetrics) error {
+	e.exported = append(e.exported, rm)
+	return nil
+}
+
+func (e *testMetricExporter) ForceFlush(_ context.Context) error { return nil }
+
+func (e *testMetricExporter) Shutdown(_ context.Context) error { return nil }
+
+var _ sdkmetric.Exporter = &testMetricExporter{}
+
+func testResourceMetrics() *metricdata.ResourceMetrics {
+	start := time.Unix(100, 0)
+	end := time.Unix(200, 0)
+	return &metricdata.ResourceMetrics{
+		ScopeMetrics: []metricdata.ScopeMetrics{
+			{
+				Metrics: []metricdata.Metrics{
+					{
+						Name: "requests",
+						Data: metricdata.Sum[int64]{
+							Temporality: metricdata.CumulativeTemporality,
+							IsMonotonic: true,
+							DataPoints: []metricdata.DataPoint[int64]{
+								{
+									Attributes: attribute.NewSet(attribute.String("route", "/a"), attribute.String("user", "1")),
+									StartTime:  start, Time: end, Value: 2,
+								},
+								{
+									Attributes: attribute.NewSet(attribute.String("route", "/a"), attribute.String("user", "2")),
+									StartTime:  start, Time: end, Value: 3,
+								},
+								{
+									Attributes: attribute.NewSet(attribute.String("route", "/b"), attribute.String("user", "1")),
+									StartTime:  start, Time: end, Value: 5,
+								},
+							},
+						},
+					},
+					{
+						Name: "latency",
+						Data: metricdata.Histogram[float64]{
+							Temporality: metricdata.CumulativeTemporality,
+							DataPoints: []metricdata.HistogramDataPoint[float64]{
+								{
+									Attributes:   attribute.NewSet(attribute.String("user", "1")),
+									StartTime:    start,
+									Time:         end,
+									Count:        2,
+									Bounds:       []float64{1, 10},
+									BucketCounts: []uint64{1, 1, 0},
+									Min:          metricdata.NewExtrema(0.5),
+									Max:          metricdata.NewExtrema(5.0),
+									Sum:          5.5,
+								},
+								{
+									Attributes:   attribute.NewSet(attribute.String("user", "2")),
+									StartTime:    start,
+									Time:         end,
+									Count:        1,
+									Bounds:       []float64{1, 10},
+									BucketCounts: []uint64{0, 0, 1},
+									Min:          metricdata.NewExtrema(20.0),
+									Max:          metricdata.NewExtrema(20.0),
+									Sum:          20,
+								},
+							},
+						},
+					},
+					{
+						Name: "debug.queue_depth",
+						Data: metricdata.Gauge[float64]{
+							DataPoints: []metricdata.DataPoint[float64]{{Time: end, Value: 7}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestMetricExporter_NoConfig(t *testing.T) {
+	exporter := &testMetricExporter{}
+	metricExporter := newMetricExporter(exporter, NewCustomSampler(alwaysSampler))
+
+	rm := testResourceMetrics()
+	if err := metricExporter.Export(context.Background(), rm); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(exporter.exported) != 1 || exporter.exported[0] != rm {
+		t.Errorf("expected metrics to be exported unchanged without a configuration")
+	}
+}
+
+func TestMetricExporter_DropAndDropAttributes(t *testing.T) {
+	sampler := NewCustomSampler(alwaysSampler)
+	sampler.SetMetricConfig([]MetricSamplingConfig{
+		{Name: gql.MatchParts{RegexValue: "^debug\\."}, Drop: true},
+		{
+			Name:           gql.MatchParts{RegexValue: "^(requests|latency)$"},
+			DropAttributes: []gql.MatchParts{{MatchValue: "user"}},
+		},
+	})
+	exporter := &testMetricExporter{}
+	metricExporter := newMetricExporter(exporter, sampler)
+
+	rm := testResourceMetrics()
+	if err := metricExporter.Export(context.Background(), rm); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	metrics := exporter.exported[0].ScopeMetrics[0].Metrics
+	if len(metrics) != 2 {
+		t.Fatalf("expected the debug metric to be dropped, got %d metrics", len(metrics))
+	}
+
+	sum := metrics[0].Data.(metricdata.Sum[int64])
+	if len(sum.DataPoints) != 2 {
+		t.Fatalf("expected data points without the user attribute to be combined, got %d", len(sum.DataPoints))
+	}
+	for _, dp := range sum.DataPoints {
+		if _, ok := dp.Attributes.Value("user"); ok {
+			t.Errorf("expected the user attribute to be removed, got %v", dp.Attributes)
+		}
+		route, _ := dp.Attributes.Value("route")
+		if route.AsString() == "/a" && dp.Value != 5 {
+			t.Errorf("expected the values for /a to be added, got %d", dp.Value)
+		}
+	}
+
+	histogram := metrics[1].Data.(metricdata.Histogram[float64])
+	if len(histogram.DataPoints) != 1 {
+		t.Fatalf("expected histograms to be combined, got %d", len(histogram.DataPoints))
+	}
+	dp := histogram.DataPoints[0]
+	if dp.Count != 3 || dp.Sum != 25.5 || dp.BucketCounts[0] != 1 || dp.BucketCounts[2] != 1 {
+		t.Errorf("unexpected combined histogram %+v", dp)
+	}
+	if minimum, _ := dp.Min.Value(); minimum != 0.5 {
+		t.Errorf("expected minimum 0.5, got %v", minimum)
+	}
+	if maximum, _ := dp.Max.Value(); maximum != 20 {
+		t.Errorf("expected maximum 20, got %v", maximum)
+	}
+
+	// The data passed to the exporter is reused by the reader, so it must not be modified.
+	original := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64])
+	if len(rm.ScopeMetrics[0].Metrics) != 3 || len(original.DataPoints) != 3 || original.DataPoints[0].Attributes.Len() != 2 {
+		t.Error("expected the original metrics not to be modified")
+	}
+}
+
+func TestMetricExporter_AttributeMatch(t *testing.T) {
+	sampler := NewCustomSampler(alwaysSampler)
+	sampler.SetMetricConfig([]MetricSamplingConfig{
+		{
+			Attributes: []MetricAttributeMatchConfig{
+				{Key: gql.MatchParts{MatchValue: "route"}, Attribute: gql.MatchParts{MatchValue: "/b"}},
+			},
+			Drop: true,
+		},
+	})
+	exporter := &testMetricExporter{}
+	metricExporter := newMetricExporter(exporter, sampler)
+
+	if err := metricExporter.Export(context.Background(), testResourceMetrics()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	metrics := exporter.exported[0].ScopeMetrics[0].Metrics
+	if len(metrics) != 3 {
+		t.Fatalf("expected all metrics to remain, got %d", len(metrics))
+	}
+	sum := metrics[0].Data.(metricdata.Sum[int64])
+	if len(sum.DataPoints) != 2 {
+		t.Errorf("expected the data point for /b to be dropped, got %d data points", len(sum.DataPoints))
+	}
+	for _, dp := range sum.DataPoints {
+		if route, _ := dp.Attributes.Value("route"); route.AsString() == "/b" {
+			t.Errorf("expected the data point for /b to be dropped")
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package otel
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+)
+
+// MetricAttributeMatchConfig matches an attribute of a metric data point by its key and value.
+type MetricAttributeMatchConfig struct {
+	Key       gql.MatchParts `json:"key"`
+	Attribute gql.MatchParts `json:"attribute"`
+}
+
+// MetricSamplingConfig describes how the data points of matching metrics are exported.
+// Metrics are aggregated, so their data points are not sampled by ratio. Instead
+// matching data points can be dropped, or have attributes removed to reduce
+// their cardinality.
+type MetricSamplingConfig struct {
+	// Matches the name of the instrument.
+	Name gql.MatchParts `json:"name"`
+	// Each attribute listed must match an attribute of the data point.
+	Attributes []MetricAttributeMatchConfig `json:"attributes"`
+	// When set, matching data points are not exported.
+	Drop bool `json:"drop"`
+	// Attributes with keys matching any of these are removed from matching
+	// data points. Data points which become identical are combined.
+	DropAttributes []gql.MatchParts `json:"dropAttributes"`
+}
+
+// MetricSamplingResult represents the result of sampling a metric data point
+type MetricSamplingResult struct {
+	// Sample indicates whether the data point should be exported
+	Sample bool
+	// Filter returns true for the attributes of the data point which should be
+	// kept. A nil filter keeps all attributes.
+	Filter attribute.Filter
+}
+
+// SetMetricConfig sets the configuration for the export of metrics. The first
+// matching configuration decides how a data point is exported.
+func (cs *CustomSampler) SetMetricConfig(config []MetricSamplingConfig) {
+	cs.configMutex.Lock()
+	defer cs.configMutex.Unlock()
+	cs.metricConfig = config
+}
+
+// IsMetricSamplingEnabled returns true if metric sampling is enabled
+func (cs *CustomSampler) IsMetricSamplingEnabled() bool {
+	cs.configMutex.RLock()
+	defer cs.configMutex.RUnlock()
+	return len(cs.metricConfig) > 0
+}
+
+// matchesMetricConfig matches a metric data point against the configuration
+func (cs *CustomSampler) matchesMetricConfig(config *MetricSamplingConfig, name string, attrs attribute.Set) bool {
+	if !isMatchConfigEmpty(&config.Name) && !matchesValue(cs, &config.Name, name) {
+		return false
+	}
+	for _, attrConfig := range config.Attributes {
+		matched := false
+		iter := attrs.Iter()
+		for iter.Next() {
+			attr := iter.Attribute()
+			if matchesValue(cs, &attrConfig.Key, attr.Key) && matchesValue(cs, &attrConfig.Attribute, attr.Value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// SampleMetric samples a data point of a metric based on the metric configuration
+func (cs *CustomSampler) SampleMetric(name string, attrs attribute.Set) MetricSamplingResult {
+	cs.configMutex.RLock()
+	defer cs.configMutex.RUnlock()
+
+	for i := range cs.metricConfig {
+		config := &cs.metricConfig[i]
+		if !cs.matchesMetricConfig(config, name, attrs) {
+			continue
+		}
+		if config.Drop {
+			return MetricSamplingResult{Sample: false}
+		}
+		if len(config.DropAttributes) == 0 {
+			return MetricSamplingResult{Sample: true}
+		}
+		dropAttributes := config.DropAttributes
+		return MetricSamplingResult{
+			Sample: true,
+			Filter: func(kv attribute.KeyValue) bool {
+				for j := range dropAttributes {
+					if matchesValue(cs, &dropAttributes[j], kv.Key) {
+						return false
+					}
+				}
+				return true
+			},
+		}
+	}
+
+	// Didn't match any metric config, or there were no configs, so we export it
+	return MetricSamplingResult{Sample: true}
+}
@@ -28,101 +28,100 @@ var int64CountersLock = sync.RWMutex{}
 //nolint:gochecknoglobals
 var int64Counters = make(map[string]metric.Int64Counter, 1000)
 
+// resetInstruments discards the cached instruments, so that instruments are
+// created with the current meter. This is called when the meter is replaced.
+// An instrument which is being created concurrently is created after the reset,
+// with the current meter, as it is created while holding the lock. A value
+// which is being recorded concurrently is recorded with the instrument that
+// was looked up or created before the reset.
+func resetInstruments() {
+	float64GaugesLock.Lock()
+	float64Gauges = make(map[string]metric.Float64Gauge, 1000)
+	float64GaugesLock.Unlock()
+
+	float64HistogramsLock.Lock()
+	float64Histograms = make(map[string]metric.Float64Histogram, 1000)
+	float64HistogramsLock.Unlock()
+
+	int64CountersLock.Lock()
+	int64Counters = make(map[string]metric.Int64Counter, 1000)
+	int64CountersLock.Unlock()
+}
+
 // RecordMetric is used to record arbitrary metrics in your golang backend.
 func RecordMetric(ctx context.Context, name string, value float64, tags ...attribute.KeyValue) {
-	var err error
 	float64GaugesLock.RLock()
-	if g := float64Gauges[name]; g == nil {
-		float64GaugesLock.RUnlock()
+	g := float64Gauges[name]
+	float64GaugesLock.RUnlock()
+	if g == nil {
+		var err error
 		float64GaugesLock.Lock()
 		// Between releasing the read lock and acquiring the write lock,
 		// another goroutine could have created the gauge.
-		if g := float64Gauges[name]; g == nil {
-			float64Gauges[name], err = GetMeter().Float64Gauge(name)
+		if g = float64Gauges[name]; g == nil {
+			g, err = GetMeter().Float64Gauge(name)
+			float64Gauges[name] = g
 		}
 		float64GaugesLock.Unlock()
 		if err != nil {
 			logging.GetLogger().Errorf("error creating float64 gauge %s: %v", name, err)
 			return
 		}
-		// Re-lock for reading.
-		float64GaugesLock.RLock()
 	}
 
-	if g := float64Gauges[name]; g != nil {
-		g.Record(ctx, value, metric.WithAttributes(tags...))
-	} else {
-		// This condition would represent an implementation error.
-		// Something would have to remove the item from the map, which
-		// is not part of the public API.
-		logging.GetLogger().Errorf("float64 gauge %s not found", name)
-	}
-
-	float64GaugesLock.RUnlock()
+	// The gauge is used directly rather than looked up again, as the
+	// instruments can be reset in the meantime.
+	g.Record(ctx, value, metric.WithAttributes(tags...))
 }
 
 // RecordHistogram is used to record arbitrary histograms in your golang backend.
 func RecordHistogram(ctx context.Context, name string, value float64, tags ...attribute.KeyValue) {
-	var err error
 	float64HistogramsLock.RLock()
-	if h := float64Histograms[name]; h == nil {
-		float64HistogramsLock.RUnlock()
+	h := float64Histograms[name]
+	float64HistogramsLock.RUnlock()
+	if h == nil {
+		var err error
 		float64HistogramsLock.Lock()
 		// Between releasing the read lock and acquiring the write lock,
 		// another goroutine could have created the histogram.
-		if h := float64Histograms[name]; h == nil {
-			float64Histograms[name], err = GetMeter().Float64Histogram(name)
+		if h = float64Histograms[name]; h == nil {
+			h, err = GetMeter().Float64Histogram(name)
+			float64Histograms[name] = h
 		}
 		float64HistogramsLock.Unlock()
 		if err != nil {
 			logging.GetLogger().Errorf("error creating float64 histogram %s: %v", name, err)
 			return
 		}
-		// Re-lock for reading.
-		float64HistogramsLock.RLock()
 	}
 
-	if h := float64Histograms[name]; h != nil {
-		h.Record(ctx, value, metric.WithAttributes(tags...))
-	} else {
-		// This condition would represent an implementation error.
-		// Something would have to remove the item from the map, which
-		// is not part of the public API.
-		logging.GetLogger().Errorf("float64 histogram %s not found", name)
-	}
-
-	float64HistogramsLock.RUnlock()
+	// The histogram is used directly rather than looked up again, as the
+	// instruments can be reset in the meantime.
+	h.Record(ctx, value, metric.WithAttributes(tags...))
 }
 
 // RecordCount is used to record arbitrary counts in your golang backend.
 func RecordCount(ctx context.Context, name string, value int64, tags ...attribute.KeyValue) {
-	var err error
 	int64CountersLock.RLock()
-	if c := int64Counters[name]; c == nil {
-		int64CountersLock.RUnlock()
+	c := int64Counters[name]
+	int64CountersLock.RUnlock()
+	if c == nil {
+		var err error
 		int64CountersLock.Lock()
 		// Between releasing the read lock and acquiring the write lock,
 		// another goroutine could have created the counter.
-		if c := int64Counters[name]; c == nil {
-			int64Counters[name], err = GetMeter().Int64Counter(name)
+		if c = int64Counters[name]; c == nil {
+			c, err = GetMeter().Int64Counter(name)
+			int64Counters[name] = c
 		}
 		int64CountersLock.Unlock()
 		if err != nil {
 			logging.GetLogger().Errorf("error creating int64 counter %s: %v", name, err)
 			return
 		}
-		// Re-lock for reading.
-		int64CountersLock.RLock()
 	}
 
-	if c := int64Counters[name]; c != nil {
-		c.Add(ctx, value, metric.WithAttributes(tags...))
-	} else {
-		// This condition would represent an implementation error.
-		// Something would have to remove the item from the map, which
-		// is not part of the public API.
-		logging.GetLogger().Errorf("int64 counter %s not found", name)
-	}
-
-	int64CountersLock.RUnlock()
+	// The counter is used directly rather than looked up again, as the
+	// instruments can be reset in the meantime.
+	c.Add(ctx, value, metric.WithAttributes(tags...))
 }
@@ -2,10 +2,13 @@ package otel
 
 import (
 	"context"
+	"sync"
 	"testing"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 )
 
 // These tests do not test metrics end-to-end, but they do verify that the functions
@@ -458,3 +461,64 @@ func TestRecordCountWithEmptyName(t *testing.T) {
 	// Test that functions handle empty metric names gracefully
 	RecordCount(ctx, "", 1)
 }
+
+func TestRecordCount_ConcurrentWithReset(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+	previous := instances.Load().(*otelInstances)
+	instances.Store(&otelInstances{tracer: previous.tracer, logger: previous.logger, meter: provider.Meter("test")})
+	defer instances.Store(previous)
+	resetInstruments()
+
+	const writers, adds = 4, 200
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < adds; j++ {
+				RecordCount(ctx, "test.concurrent.counter", 1)
+				RecordMetric(ctx, "test.concurrent.gauge", 1)
+				RecordHistogram(ctx, "test.concurrent.histogram", 1)
+			}
+		}()
+	}
+	stop := make(chan struct{})
+	resetDone := make(chan struct{})
+	go func() {
+		defer close(resetDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				resetInstruments()
+			}
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	<-resetDone
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &data); err != nil {
+		t.Fatalf("failed to collect: %v", err)
+	}
+	// The instruments created after each reset have the same meter, so they
+	// share their data.
+	var total int64
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok && m.Name == "test.concurrent.counter" {
+				for _, point := range sum.DataPoints {
+					total += point.Value
+				}
+			}
+		}
+	}
+	if total != writers*adds {
+		t.Errorf("expected every count to be recorded, got %d of %d", total, writers*adds)
+	}
+}
@@ -2,7 +2,12 @@ package otel
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -10,9 +15,12 @@ import (
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/log"
 	"go.opentelemetry.io/otel/log/global"
@@ -24,7 +32,10 @@ import (
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
+	"github.com/launchdarkly/observability-sdk/go/internal/defaults"
 	"github.com/launchdarkly/observability-sdk/go/internal/gql"
 	"github.com/launchdarkly/observability-sdk/go/internal/logging"
 	"github.com/launchdarkly/observability-sdk/go/internal/metadata"
@@ -34,6 +45,11 @@ type providerInstances struct {
 	tracerProvider trace.TracerProvider
 	loggerProvider log.LoggerProvider
 	meterProvider  metric.MeterProvider
+	// The configuration the providers were created with.
+	config *Config
+	// Stops reporting the telemetry of the export pipelines, when it is
+	// reported.
+	stopTelemetry func()
 }
 
 type otelInstances struct {
@@ -42,15 +58,121 @@ type otelInstances struct {
 	meter  metric.Meter
 }
 
+// The OTLP protocols which can be used to export data.
+const (
+	ProtocolHTTPProtobuf = "http/protobuf"
+	ProtocolGRPC         = "grpc"
+)
+
+// The compression which can be applied to exported data.
+const (
+	CompressionGzip = "gzip"
+	CompressionNone = "none"
+)
+
+// The timeout of the OTLP exporters when it is not configured.
+const defaultOTLPTimeout = 10 * time.Second
+
+// The time spent exporting pending data when it is flushed by a logging
+// integration.
+const logFlushTimeout = 5 * time.Second
+
+// RetryConfig configures the retry of exports which fail with a transient
+// error. The fields match the retry configuration of the OTLP exporters.
+type RetryConfig struct {
+	Enabled         bool
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	MaxElapsedTime  time.Duration
+}
+
+// AdditionalSpanExporter is an exporter which receives spans alongside
+// LaunchDarkly, through its own batch processor.
+type AdditionalSpanExporter struct {
+	Exporter     sdktrace.SpanExporter
+	BatchOptions []sdktrace.BatchSpanProcessorOption
+	// When set, the exporter only receives the spans which the sampling
+	// configuration exports.
+	Sampled bool
+}
+
+// AdditionalLogExporter is an exporter which receives log records alongside
+// LaunchDarkly, through its own batch processor.
+type AdditionalLogExporter struct {
+	Exporter     sdklog.Exporter
+	BatchOptions []sdklog.BatchProcessorOption
+	// When set, the exporter only receives the log records which the sampling
+	// configuration exports.
+	Sampled bool
+}
+
 // Config contains the configuration for the OTLP provider.
 type Config struct {
-	OtlpEndpoint           string
+	OtlpEndpoint string
+	// The protocol used to export data. Either ProtocolHTTPProtobuf, which is
+	// the default, or ProtocolGRPC.
+	OtlpProtocol string
+	// Headers sent with every export request.
+	OtlpHeaders map[string]string
+	// The timeout of each export request. The exporter default is used when
+	// this is not greater than zero.
+	OtlpTimeout time.Duration
+	// Either CompressionGzip, which is the default, or CompressionNone.
+	OtlpCompression string
+	// The TLS configuration used for endpoints which do not have an http://
+	// scheme. The system configuration is used when this is nil.
+	OtlpTLSConfig *tls.Config
+	// The proxy used by the HTTP exporters. When this is nil, the proxy is
+	// read from the environment.
+	OtlpProxy func(*http.Request) (*url.URL, error)
+	// The client used by the HTTP exporters. This takes precedence over
+	// OtlpTLSConfig, OtlpProxy and OtlpTimeout for the HTTP exporters.
+	OtlpHTTPClient         *http.Client
 	ResourceAttributes     []attribute.KeyValue
 	Sampler                sdktrace.Sampler
 	SpanMaxExportBatchSize int
 	SpanMaxQueueSize       int
 	LogMaxExportBatchSize  int
 	LogMaxQueueSize        int
+	// The batch timeouts are the maximum delay before a batch is exported, and
+	// the export timeouts are the maximum time an export may take. Defaults
+	// are used for values which are not greater than zero.
+	SpanBatchTimeout  time.Duration
+	SpanExportTimeout time.Duration
+	LogBatchTimeout   time.Duration
+	LogExportTimeout  time.Duration
+	// The interval between metric exports, and the maximum time an export may
+	// take. Defaults are used for values which are not greater than zero.
+	MetricInterval time.Duration
+	MetricTimeout  time.Duration
+	// When set, spans and log records which fail to export are queued in
+	// this directory, and exported when the endpoint recovers. The queue
+	// for each signal may use half of PersistentQueueMaxBytes, and queued
+	// data older than PersistentQueueMaxAge is discarded.
+	PersistentQueueDir      string
+	PersistentQueueMaxBytes int64
+	PersistentQueueMaxAge   time.Duration
+	// The retry configuration of the exporter for each signal. The exporter
+	// default is used when this is nil.
+	SpanRetry         *RetryConfig
+	LogRetry          *RetryConfig
+	MetricRetry       *RetryConfig
+	KeepErroredTraces bool
+	// When greater than zero, spans are buffered until their trace is complete,
+	// for at most this long, before being exported.
+	TraceBufferTimeout time.Duration
+	// The maximum number of spans held in the trace buffer.
+	TraceBufferMaxSpans int
+	// When set, StartOTLP does not set the global propagator and providers.
+	SkipGlobalProviders bool
+	// Exporters and readers which are registered with the providers in
+	// addition to the LaunchDarkly pipelines.
+	AdditionalSpanExporters []AdditionalSpanExporter
+	AdditionalLogExporters  []AdditionalLogExporter
+	AdditionalMetricReaders []sdkmetric.Reader
+	// When set, the telemetry of the export pipelines is not reported as
+	// metrics. It is still counted, and is returned by GetStats.
+	DisableTelemetry bool
 }
 
 func defaultInstancesValue() *atomic.Value {
@@ -120,69 +242,108 @@ func SetSamplingConfig(config *gql.GetSamplingConfigResponse) {
 	customSampler.SetConfig(&config.Sampling)
 }
 
+// GetCustomSampler returns the sampler which applies the sampling
+// configuration to the data exported by the plugin.
+func GetCustomSampler() *CustomSampler {
+	return customSampler
+}
+
 // Shutdown flushes pending data and shuts down the OTLP instances.
 func Shutdown() {
+	if err := ShutdownWithContext(context.Background()); err != nil {
+		logging.GetLogger().Error(err)
+	}
+}
+
+// ShutdownWithContext flushes pending data and shuts down the OTLP instances.
+// The providers are shut down in parallel, and the errors of each are joined.
+// When the context is done, pending data is dropped.
+func ShutdownWithContext(ctx context.Context) error {
 	writeLock.Lock()
-	end := func() {
-		writeLock.Unlock()
+	defer writeLock.Unlock()
+	return shutdown(ctx)
+}
+
+// Flush exports pending data without shutting down the OTLP instances. The
+// providers are flushed in parallel, and the errors of each are joined.
+func Flush(ctx context.Context) error {
+	o := otlp.Load()
+	if o == nil {
+		return nil
 	}
-	shutdown()
-	end()
+	return o.forEachProvider(ctx, func(ctx context.Context, p sdkProvider) error {
+		return p.ForceFlush(ctx)
+	})
+}
+
+// FlushWithTimeout exports pending data like Flush, waiting at most
+// logFlushTimeout. The logging integrations use it to export the records of a
+// fatal log before the process exits.
+func FlushWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), logFlushTimeout)
+	defer cancel()
+	return Flush(ctx)
 }
 
-func shutdown() {
+func shutdown(ctx context.Context) error {
 	// Get the current OTLP instance and set it to nil.
 	o := otlp.Swap(nil)
 	if o == nil {
-		return
+		return nil
 	}
-	ctx := context.Background()
-	tp := o.tracerProvider.(*sdktrace.TracerProvider)
-	mp := o.meterProvider.(*sdkmetric.MeterProvider)
-	lp := o.loggerProvider.(*sdklog.LoggerProvider)
-
-	// The SDK instances of the various providers have additional methods versus the
-	// interfaces. The default implementations only implement the interface, and
-	// we cast to these concrete types to be able to call the additional methods.
-	if tp != nil {
-		err := tp.ForceFlush(ctx)
-		if err != nil {
-			logging.GetLogger().Error(err)
-		}
-		err = tp.Shutdown(ctx)
-		if err != nil {
-			logging.GetLogger().Error(err)
-		}
+	// The additional exporters and readers are shut down with the providers,
+	// and cannot be registered with the providers of a later start. A
+	// configuration set since the providers were created is kept as it is.
+	if conf := config.Load(); conf == o.config && conf != nil &&
+		len(conf.AdditionalSpanExporters)+len(conf.AdditionalLogExporters)+len(conf.AdditionalMetricReaders) > 0 {
+		withoutAdditional := *conf
+		withoutAdditional.AdditionalSpanExporters = nil
+		withoutAdditional.AdditionalLogExporters = nil
+		withoutAdditional.AdditionalMetricReaders = nil
+		config.Store(&withoutAdditional)
 	}
-
-	if lp != nil {
-		err := lp.ForceFlush(ctx)
-		if err != nil {
-			logging.GetLogger().Error(err)
-		}
-		err = lp.Shutdown(ctx)
-		if err != nil {
-			logging.GetLogger().Error(err)
-		}
+	err := o.forEachProvider(ctx, func(ctx context.Context, p sdkProvider) error {
+		return errors.Join(p.ForceFlush(ctx), p.Shutdown(ctx))
+	})
+	if o.stopTelemetry != nil {
+		o.stopTelemetry()
 	}
+	return err
+}
 
-	if mp != nil {
-		err := mp.ForceFlush(ctx)
-		if err != nil {
-			logging.GetLogger().Error(err)
-		}
-		err = mp.Shutdown(ctx)
-		if err != nil {
-			logging.GetLogger().Error(err)
+// sdkProvider is implemented by the SDK instances of the various providers,
+// which have additional methods versus the interfaces. The default
+// implementations only implement the interfaces.
+type sdkProvider interface {
+	ForceFlush(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+// forEachProvider calls the function for each SDK provider in parallel, so that
+// a slow endpoint for one signal does not delay the others, and joins the errors.
+func (o *providerInstances) forEachProvider(ctx context.Context, f func(context.Context, sdkProvider) error) error {
+	providers := []any{o.tracerProvider, o.loggerProvider, o.meterProvider}
+	errs := make([]error, len(providers))
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		p, ok := provider.(sdkProvider)
+		if !ok {
+			continue
 		}
+		wg.Go(func() {
+			errs[i] = f(ctx, p)
+		})
 	}
+	wg.Wait()
+	return errors.Join(errs...)
 }
 
-func getOTLPOptions(endpoint string) (
+func getOTLPOptions(config *Config) (
 	traceOpts []otlptracehttp.Option,
 	logOpts []otlploghttp.Option,
 	metricOpts []otlpmetrichttp.Option,
 ) {
+	endpoint := config.OtlpEndpoint
 	switch {
 	case strings.HasPrefix(endpoint, "http://"):
 		traceOpts = append(traceOpts, otlptracehttp.WithEndpoint(endpoint[7:]), otlptracehttp.WithInsecure())
@@ -195,12 +356,281 @@ func getOTLPOptions(endpoint string) (
 	default:
 		logging.GetLogger().Errorf("an invalid otlp endpoint was configured %s", endpoint)
 	}
-	traceOpts = append(traceOpts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
-	logOpts = append(logOpts, otlploghttp.WithCompression(otlploghttp.GzipCompression))
-	metricOpts = append(metricOpts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+	if config.OtlpCompression != CompressionNone {
+		traceOpts = append(traceOpts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		logOpts = append(logOpts, otlploghttp.WithCompression(otlploghttp.GzipCompression))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+	} else {
+		traceOpts = append(traceOpts, otlptracehttp.WithCompression(otlptracehttp.NoCompression))
+		logOpts = append(logOpts, otlploghttp.WithCompression(otlploghttp.NoCompression))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithCompression(otlpmetrichttp.NoCompression))
+	}
+	if len(config.OtlpHeaders) > 0 {
+		traceOpts = append(traceOpts, otlptracehttp.WithHeaders(config.OtlpHeaders))
+		logOpts = append(logOpts, otlploghttp.WithHeaders(config.OtlpHeaders))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithHeaders(config.OtlpHeaders))
+	}
+	if config.OtlpTimeout > 0 {
+		traceOpts = append(traceOpts, otlptracehttp.WithTimeout(config.OtlpTimeout))
+		logOpts = append(logOpts, otlploghttp.WithTimeout(config.OtlpTimeout))
+		metricOpts = append(metricOpts, otlpmetrichttp.WithTimeout(config.OtlpTimeout))
+	}
+	// The clients count failed requests, and have the TLS configuration,
+	// proxy and timeout of the configuration, or are copies of the configured
+	// client.
+	traceOpts = append(traceOpts, otlptracehttp.WithHTTPClient(telemetryHTTPClient(config, spanTelemetry)))
+	logOpts = append(logOpts, otlploghttp.WithHTTPClient(telemetryHTTPClient(config, logTelemetry)))
+	metricOpts = append(metricOpts, otlpmetrichttp.WithHTTPClient(telemetryHTTPClient(config, metricTelemetry)))
+	if config.SpanRetry != nil {
+		traceOpts = append(traceOpts, otlptracehttp.WithRetry(otlptracehttp.RetryConfig(*config.SpanRetry)))
+	}
+	if config.LogRetry != nil {
+		logOpts = append(logOpts, otlploghttp.WithRetry(otlploghttp.RetryConfig(*config.LogRetry)))
+	}
+	if config.MetricRetry != nil {
+		metricOpts = append(metricOpts, otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig(*config.MetricRetry)))
+	}
+	return
+}
+
+// getOTLPGRPCOptions returns the options for the gRPC exporters. Endpoints with
+// an http:// scheme use an insecure connection, and all other endpoints use TLS.
+// The gRPC exporters do not use the proxy or HTTP client of the configuration,
+// and instead use the proxy from the HTTPS_PROXY environment variable.
+func getOTLPGRPCOptions(config *Config) (
+	traceOpts []otlptracegrpc.Option,
+	logOpts []otlploggrpc.Option,
+	metricOpts []otlpmetricgrpc.Option,
+) {
+	endpoint := config.OtlpEndpoint
+	switch {
+	case strings.HasPrefix(endpoint, "http://"):
+		traceOpts = append(traceOpts, otlptracegrpc.WithEndpoint(endpoint[7:]), otlptracegrpc.WithInsecure())
+		logOpts = append(logOpts, otlploggrpc.WithEndpoint(endpoint[7:]), otlploggrpc.WithInsecure())
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithEndpoint(endpoint[7:]), otlpmetricgrpc.WithInsecure())
+	case strings.HasPrefix(endpoint, "https://"):
+		traceOpts = append(traceOpts, otlptracegrpc.WithEndpoint(endpoint[8:]))
+		logOpts = append(logOpts, otlploggrpc.WithEndpoint(endpoint[8:]))
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithEndpoint(endpoint[8:]))
+	case endpoint != "":
+		traceOpts = append(traceOpts, otlptracegrpc.WithEndpoint(endpoint))
+		logOpts = append(logOpts, otlploggrpc.WithEndpoint(endpoint))
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithEndpoint(endpoint))
+	default:
+		logging.GetLogger().Errorf("an invalid otlp endpoint was configured %s", endpoint)
+	}
+	if config.OtlpCompression != CompressionNone {
+		traceOpts = append(traceOpts, otlptracegrpc.WithCompressor(CompressionGzip))
+		logOpts = append(logOpts, otlploggrpc.WithCompressor(CompressionGzip))
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithCompressor(CompressionGzip))
+	}
+	if len(config.OtlpHeaders) > 0 {
+		traceOpts = append(traceOpts, otlptracegrpc.WithHeaders(config.OtlpHeaders))
+		logOpts = append(logOpts, otlploggrpc.WithHeaders(config.OtlpHeaders))
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithHeaders(config.OtlpHeaders))
+	}
+	if config.OtlpTimeout > 0 {
+		traceOpts = append(traceOpts, otlptracegrpc.WithTimeout(config.OtlpTimeout))
+		logOpts = append(logOpts, otlploggrpc.WithTimeout(config.OtlpTimeout))
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithTimeout(config.OtlpTimeout))
+	}
+	if config.OtlpTLSConfig != nil && !strings.HasPrefix(endpoint, "http://") {
+		creds := credentials.NewTLS(config.OtlpTLSConfig)
+		traceOpts = append(traceOpts, otlptracegrpc.WithTLSCredentials(creds))
+		logOpts = append(logOpts, otlploggrpc.WithTLSCredentials(creds))
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithTLSCredentials(creds))
+	}
+	traceOpts = append(traceOpts,
+		otlptracegrpc.WithDialOption(grpc.WithChainUnaryInterceptor(telemetryInterceptor(spanTelemetry))))
+	logOpts = append(logOpts,
+		otlploggrpc.WithDialOption(grpc.WithChainUnaryInterceptor(telemetryInterceptor(logTelemetry))))
+	metricOpts = append(metricOpts,
+		otlpmetricgrpc.WithDialOption(grpc.WithChainUnaryInterceptor(telemetryInterceptor(metricTelemetry))))
+	if config.SpanRetry != nil {
+		traceOpts = append(traceOpts, otlptracegrpc.WithRetry(otlptracegrpc.RetryConfig(*config.SpanRetry)))
+	}
+	if config.LogRetry != nil {
+		logOpts = append(logOpts, otlploggrpc.WithRetry(otlploggrpc.RetryConfig(*config.LogRetry)))
+	}
+	if config.MetricRetry != nil {
+		metricOpts = append(metricOpts, otlpmetricgrpc.WithRetry(otlpmetricgrpc.RetryConfig(*config.MetricRetry)))
+	}
 	return
 }
 
+func createSpanExporter(ctx context.Context, config *Config) (sdktrace.SpanExporter, error) {
+	if config.OtlpProtocol == ProtocolGRPC {
+		options, _, _ := getOTLPGRPCOptions(config)
+		return otlptracegrpc.New(ctx, options...)
+	}
+	options, _, _ := getOTLPOptions(config)
+	return otlptrace.New(ctx, otlptracehttp.NewClient(options...))
+}
+
+func createLogExporter(ctx context.Context, config *Config) (sdklog.Exporter, error) {
+	if config.OtlpProtocol == ProtocolGRPC {
+		_, options, _ := getOTLPGRPCOptions(config)
+		return otlploggrpc.New(ctx, options...)
+	}
+	_, options, _ := getOTLPOptions(config)
+	return otlploghttp.New(ctx, options...)
+}
+
+func createMetricExporter(ctx context.Context, config *Config) (sdkmetric.Exporter, error) {
+	if config.OtlpProtocol == ProtocolGRPC {
+		_, _, options := getOTLPGRPCOptions(config)
+		return otlpmetricgrpc.New(ctx, options...)
+	}
+	_, _, options := getOTLPOptions(config)
+	return otlpmetrichttp.New(ctx, options...)
+}
+
+// openPersistentQueue opens the persistent queue of a signal, if a persistent
+// queue is configured. Data is exported without a queue if it cannot be opened.
+func openPersistentQueue(config *Config, signal string) *persistentQueue {
+	if config.PersistentQueueDir == "" {
+		return nil
+	}
+	queue, err := newPersistentQueue(
+		filepath.Join(config.PersistentQueueDir, signal),
+		config.PersistentQueueMaxBytes/2,
+		config.PersistentQueueMaxAge,
+	)
+	if err != nil {
+		logging.GetLogger().Errorf("failed to open the persistent queue for %s: %v", signal, err)
+		return nil
+	}
+	return queue
+}
+
+// createSpanProcessor creates the processor which exports spans to
+// LaunchDarkly, applying the sampling configuration. The spans which are
+// exported are also emitted to the sampled processors.
+func createSpanProcessor(
+	ctx context.Context,
+	config *Config,
+	sampler *CustomSampler,
+	sampled ...sdktrace.SpanProcessor,
+) (sdktrace.SpanProcessor, error) {
+	exporter, err := createSpanExporter(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP trace exporter: %w", err)
+	}
+	exportTimeout := defaults.DurationOrDefault(config.SpanExportTimeout, defaults.DefaultExportTimeout)
+	exporter = telemetrySpanExporter{SpanExporter: exporter}
+	if queue := openPersistentQueue(config, "traces"); queue != nil {
+		exporter = newPersistentSpanExporter(exporter, queue, exportTimeout)
+	}
+	if len(sampled) > 0 {
+		exporter = fanOutSpanExporter{SpanExporter: exporter, processors: sampled}
+	}
+	// The batch processor uses its default queue size when the size is not
+	// greater than zero.
+	maxQueueSize := config.SpanMaxQueueSize
+	if maxQueueSize <= 0 {
+		maxQueueSize = sdktrace.DefaultMaxQueueSize
+	}
+	var sampledExporter sdktrace.SpanExporter = sampledSpanExporter(config, sampler, exporter)
+	var buffer *traceBuffer
+	if config.TraceBufferTimeout > 0 {
+		// The buffer exports in batches no larger than those of the batch
+		// processor, which uses its default size when the size is not
+		// greater than zero, and limits it to the queue size.
+		maxBatchSize := config.SpanMaxExportBatchSize
+		if maxBatchSize <= 0 {
+			maxBatchSize = sdktrace.DefaultMaxExportBatchSize
+		}
+		maxBatchSize = min(maxBatchSize, maxQueueSize)
+		buffer = newTraceBuffer(sampledExporter, config.TraceBufferTimeout, config.TraceBufferMaxSpans,
+			exportTimeout, maxBatchSize)
+		buffer.telemetry = spanTelemetry
+		sampledExporter = buffer
+	}
+	limit := &queueLimit{maxQueueSize: int64(maxQueueSize), telemetry: spanTelemetry}
+	processor := sdktrace.NewBatchSpanProcessor(
+		queueReleasingSpanExporter{SpanExporter: sampledExporter, limit: limit},
+		sdktrace.WithBatchTimeout(defaults.DurationOrDefault(config.SpanBatchTimeout, defaults.DefaultBatchTimeout)),
+		sdktrace.WithExportTimeout(exportTimeout),
+		sdktrace.WithMaxExportBatchSize(config.SpanMaxExportBatchSize),
+		sdktrace.WithMaxQueueSize(maxQueueSize),
+	)
+	var spanProcessor sdktrace.SpanProcessor = queueLimitSpanProcessor{SpanProcessor: processor, limit: limit}
+	if buffer != nil {
+		spanProcessor = traceBufferSpanProcessor{SpanProcessor: spanProcessor, buffer: buffer}
+	}
+	if len(sampled) > 0 {
+		spanProcessor = fanOutSpanProcessor{SpanProcessor: spanProcessor, processors: sampled}
+	}
+	return spanProcessor, nil
+}
+
+// sampledSpanExporter wraps an exporter so that it receives the spans which the
+// sampling configuration of the sampler exports. The spans which are not
+// exported are counted.
+func sampledSpanExporter(config *Config, sampler *CustomSampler, exporter sdktrace.SpanExporter) *traceExporter {
+	sampledExporter := newTraceExporter(exporter, sampler)
+	sampledExporter.keepErroredTraces = config.KeepErroredTraces
+	sampledExporter.telemetry = spanTelemetry
+	return sampledExporter
+}
+
+// createLogProcessor creates the processor which exports log records to
+// LaunchDarkly, applying the sampling configuration. The records which are
+// exported are also emitted to the sampled processors.
+func createLogProcessor(
+	ctx context.Context,
+	config *Config,
+	sampler *CustomSampler,
+	sampled ...sdklog.Processor,
+) (sdklog.Processor, error) {
+	exporter, err := createLogExporter(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP logger exporter: %w", err)
+	}
+	exporter = telemetryLogExporter{Exporter: exporter}
+	if queue := openPersistentQueue(config, "logs"); queue != nil {
+		exporter = newPersistentLogExporter(exporter, queue,
+			defaults.DurationOrDefault(config.LogExportTimeout, defaults.DefaultExportTimeout))
+	}
+	if len(sampled) > 0 {
+		exporter = fanOutLogExporter{Exporter: exporter, processors: sampled}
+	}
+	sampledExporter := newLogExporter(exporter, sampler)
+	sampledExporter.telemetry = logTelemetry
+	// The batch processor uses its default queue size when the size is not
+	// greater than zero.
+	maxQueueSize := config.LogMaxQueueSize
+	if maxQueueSize <= 0 {
+		maxQueueSize = defaults.DefaultLogMaxQueueSize
+	}
+	limit := &queueLimit{maxQueueSize: int64(maxQueueSize), telemetry: logTelemetry}
+	processor := sdklog.NewBatchProcessor(queueReleasingLogExporter{Exporter: sampledExporter, limit: limit},
+		sdklog.WithExportInterval(defaults.DurationOrDefault(config.LogBatchTimeout, defaults.DefaultBatchTimeout)),
+		sdklog.WithExportTimeout(defaults.DurationOrDefault(config.LogExportTimeout, defaults.DefaultExportTimeout)),
+		sdklog.WithExportMaxBatchSize(config.LogMaxExportBatchSize),
+		sdklog.WithMaxQueueSize(maxQueueSize),
+	)
+	var logProcessor sdklog.Processor = queueLimitLogProcessor{Processor: processor, limit: limit}
+	if len(sampled) > 0 {
+		logProcessor = fanOutLogProcessor{Processor: logProcessor, processors: sampled}
+	}
+	return logProcessor, nil
+}
+
+// createMetricReader creates the reader which exports metrics to LaunchDarkly,
+// applying the sampling configuration.
+func createMetricReader(ctx context.Context, config *Config, sampler *CustomSampler) (sdkmetric.Reader, error) {
+	exporter, err := createMetricExporter(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP meter exporter: %w", err)
+	}
+	exporter = telemetryMetricExporter{Exporter: exporter}
+	return sdkmetric.NewPeriodicReader(newMetricExporter(exporter, sampler),
+		sdkmetric.WithInterval(defaults.DurationOrDefault(config.MetricInterval, defaults.DefaultMetricInterval)),
+		sdkmetric.WithTimeout(defaults.DurationOrDefault(config.MetricTimeout, defaults.DefaultExportTimeout)),
+	), nil
+}
+
 func createTracerProvider(
 	ctx context.Context,
 	config *Config,
@@ -208,21 +638,30 @@ func createTracerProvider(
 	sampler sdktrace.Sampler,
 	opts ...sdktrace.TracerProviderOption,
 ) (*sdktrace.TracerProvider, error) {
-	options, _, _ := getOTLPOptions(config.OtlpEndpoint)
-	client := otlptracehttp.NewClient(options...)
-	exporter, err := otlptrace.New(ctx, client)
+	// The additional exporters which only receive sampled spans are sent the
+	// spans exported to LaunchDarkly, so that the spans are only sampled once.
+	var sampled []sdktrace.SpanProcessor
+	for _, additional := range config.AdditionalSpanExporters {
+		if additional.Sampled {
+			sampled = append(sampled, sdktrace.NewBatchSpanProcessor(additional.Exporter, additional.BatchOptions...))
+		}
+	}
+	processor, err := createSpanProcessor(ctx, config, customSampler, sampled...)
 	if err != nil {
-		return nil, fmt.Errorf("creating OTLP trace exporter: %w", err)
+		for _, p := range sampled {
+			_ = p.Shutdown(ctx)
+		}
+		return nil, err
 	}
 	opts = append([]sdktrace.TracerProviderOption{
-		sdktrace.WithBatcher(newTraceExporter(exporter, customSampler),
-			sdktrace.WithBatchTimeout(time.Second),
-			sdktrace.WithExportTimeout(30*time.Second),
-			sdktrace.WithMaxExportBatchSize(config.SpanMaxExportBatchSize),
-			sdktrace.WithMaxQueueSize(config.SpanMaxQueueSize),
-		),
+		sdktrace.WithSpanProcessor(processor),
 		sdktrace.WithResource(resources),
 	}, opts...)
+	for _, additional := range config.AdditionalSpanExporters {
+		if !additional.Sampled {
+			opts = append(opts, sdktrace.WithBatcher(additional.Exporter, additional.BatchOptions...))
+		}
+	}
 	// Only configure a sampler when there is a sampling configuration.
 	if sampler != nil {
 		opts = append(opts, sdktrace.WithSampler(sampler))
@@ -236,19 +675,31 @@ func createLoggerProvider(
 	resources *resource.Resource,
 	opts ...sdklog.LoggerProviderOption,
 ) (*sdklog.LoggerProvider, error) {
-	_, options, _ := getOTLPOptions(config.OtlpEndpoint)
-	exporter, err := otlploghttp.New(ctx, options...)
+	// The additional exporters which only receive sampled records are sent the
+	// records exported to LaunchDarkly, so that the records are only sampled
+	// once.
+	var sampled []sdklog.Processor
+	for _, additional := range config.AdditionalLogExporters {
+		if additional.Sampled {
+			sampled = append(sampled, sdklog.NewBatchProcessor(additional.Exporter, additional.BatchOptions...))
+		}
+	}
+	processor, err := createLogProcessor(ctx, config, customSampler, sampled...)
 	if err != nil {
-		return nil, fmt.Errorf("creating OTLP logger exporter: %w", err)
+		for _, p := range sampled {
+			_ = p.Shutdown(ctx)
+		}
+		return nil, err
 	}
 	opts = append([]sdklog.LoggerProviderOption{
-		sdklog.WithProcessor(sdklog.NewBatchProcessor(newLogExporter(exporter, customSampler),
-			sdklog.WithExportTimeout(30*time.Second),
-			sdklog.WithExportMaxBatchSize(config.LogMaxExportBatchSize),
-			sdklog.WithMaxQueueSize(config.LogMaxQueueSize),
-		)),
+		sdklog.WithProcessor(processor),
 		sdklog.WithResource(resources),
 	}, opts...)
+	for _, additional := range config.AdditionalLogExporters {
+		if !additional.Sampled {
+			opts = append(opts, sdklog.WithProcessor(sdklog.NewBatchProcessor(additional.Exporter, additional.BatchOptions...)))
+		}
+	}
 	return sdklog.NewLoggerProvider(opts...), nil
 }
 
@@ -258,21 +709,63 @@ func createMeterProvider(
 	resources *resource.Resource,
 	opts ...sdkmetric.Option,
 ) (*sdkmetric.MeterProvider, error) {
-	_, _, options := getOTLPOptions(config.OtlpEndpoint)
-	exporter, err := otlpmetrichttp.New(ctx, options...)
+	reader, err := createMetricReader(ctx, config, customSampler)
 	if err != nil {
-		return nil, fmt.Errorf("creating OTLP meter exporter: %w", err)
+		return nil, err
 	}
 	opts = append([]sdkmetric.Option{
-		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter,
-			sdkmetric.WithInterval(5*time.Second),
-			sdkmetric.WithTimeout(30*time.Second),
-		)),
+		sdkmetric.WithReader(reader),
 		sdkmetric.WithResource(resources),
 	}, opts...)
+	for _, additional := range config.AdditionalMetricReaders {
+		opts = append(opts, sdkmetric.WithReader(additional))
+	}
 	return sdkmetric.NewMeterProvider(opts...), nil
 }
 
+// Components are the parts of the LaunchDarkly export pipeline, for use with
+// providers which are owned by the application.
+type Components struct {
+	SpanProcessor sdktrace.SpanProcessor
+	LogProcessor  sdklog.Processor
+	MetricReader  sdkmetric.Reader
+	// The head sampler, which is nil when there is no head sampling
+	// configuration.
+	Sampler sdktrace.Sampler
+	// The resource attributes LaunchDarkly requires, including the project ID.
+	Resource *resource.Resource
+}
+
+// NewComponents creates the processors and reader which export to LaunchDarkly
+// with the given configuration, applying the sampling configuration of the
+// sampler. The stored configuration is not used or changed. Each call creates
+// new exporters, which are shut down when the processors and reader are shut
+// down.
+func NewComponents(ctx context.Context, conf *Config, sampler *CustomSampler) (*Components, error) {
+	spanProcessor, err := createSpanProcessor(ctx, conf, sampler)
+	if err != nil {
+		return nil, err
+	}
+	logProcessor, err := createLogProcessor(ctx, conf, sampler)
+	if err != nil {
+		_ = spanProcessor.Shutdown(ctx)
+		return nil, err
+	}
+	metricReader, err := createMetricReader(ctx, conf, sampler)
+	if err != nil {
+		_ = spanProcessor.Shutdown(ctx)
+		_ = logProcessor.Shutdown(ctx)
+		return nil, err
+	}
+	return &Components{
+		SpanProcessor: spanProcessor,
+		LogProcessor:  logProcessor,
+		MetricReader:  metricReader,
+		Sampler:       conf.Sampler,
+		Resource:      resource.NewSchemaless(conf.ResourceAttributes...),
+	}, nil
+}
+
 func getDefaultProviders() *providerInstances {
 	var defaultTracerProvider = otel.GetTracerProvider()
 	var defaultMeterProvider = otel.GetMeterProvider()
@@ -303,7 +796,8 @@ func GetMeter() metric.Meter {
 }
 
 // SetConfig sets the configuration for the OTLP provider.
-// The configuration must be set before calling StartOTLP.
+// The configuration must be set before calling StartOTLP. Setting the
+// configuration does not affect started OTLP instances until Restart is called.
 func SetConfig(conf Config) {
 	writeLock.Lock()
 	defer writeLock.Unlock()
@@ -315,6 +809,8 @@ func SetConfig(conf Config) {
 // Under ideal use this function is called once at startup.
 // The Shutdown function should be called when the application is shutting down
 // to ensure delivery of any pending events.
+//
+// StartOTLP can be called again after Shutdown, which creates new providers.
 func StartOTLP() error {
 	writeLock.Lock()
 	defer writeLock.Unlock()
@@ -323,7 +819,28 @@ func StartOTLP() error {
 	if otlp.Load() != nil {
 		return nil
 	}
+	return startOTLP()
+}
 
+// Restart shuts down the OTLP instances, if they are started, and starts them
+// with the current configuration. Pending data is flushed before the instances
+// are replaced, until the context is done. If the instances are not started,
+// then the configuration is used when StartOTLP is called.
+func Restart(ctx context.Context) error {
+	writeLock.Lock()
+	defer writeLock.Unlock()
+
+	if otlp.Load() == nil {
+		return nil
+	}
+	if err := shutdown(ctx); err != nil {
+		logging.GetLogger().Error(err)
+	}
+	return startOTLP()
+}
+
+// startOTLP creates the OTLP instances. The writeLock must be held.
+func startOTLP() error {
 	conf := config.Load()
 
 	if conf == nil {
@@ -331,11 +848,11 @@ func StartOTLP() error {
 		return fmt.Errorf("ensure plugin is configured before calling StartOTLP")
 	}
 
-	shutdown()
 	ctx := context.Background()
 
+	// The resource attributes from the environment are resolved by the
+	// plugin, and are included in the configured attributes.
 	resources, err := resource.New(ctx,
-		resource.WithFromEnv(),
 		resource.WithHost(),
 		resource.WithContainer(),
 		resource.WithOS(),
@@ -352,23 +869,27 @@ func StartOTLP() error {
 		return err
 	}
 
+	// The providers which were created are shut down when a later one cannot
+	// be, so that their exporters stop and the persistent queue is released.
 	loggerProvider, err := createLoggerProvider(ctx, conf, resources)
 	if err != nil {
+		_ = tracerProvider.Shutdown(ctx)
 		return err
 	}
 
 	meterProvider, err := createMeterProvider(ctx, conf, resources)
 	if err != nil {
+		_ = tracerProvider.Shutdown(ctx)
+		_ = loggerProvider.Shutdown(ctx)
 		return err
 	}
 
-	propagator := propagation.NewCompositeTextMapPropagator(
-		propagation.TraceContext{},
-		propagation.Baggage{},
-	)
-	otel.SetTextMapPropagator(propagator)
-
-	o := &providerInstances{tracerProvider: tracerProvider, loggerProvider: loggerProvider, meterProvider: meterProvider}
+	o := &providerInstances{
+		tracerProvider: tracerProvider,
+		loggerProvider: loggerProvider,
+		meterProvider:  meterProvider,
+		config:         conf,
+	}
 	newInstances := &otelInstances{
 		tracer: o.tracerProvider.Tracer(
 			metadata.InstrumentationName,
@@ -386,9 +907,25 @@ func StartOTLP() error {
 			metric.WithSchemaURL(semconv.SchemaURL),
 		),
 	}
+	if !conf.DisableTelemetry {
+		o.stopTelemetry, err = registerTelemetry(newInstances.meter)
+		if err != nil {
+			logging.GetLogger().Errorf("failed to report the telemetry of the export pipelines: %v", err)
+		}
+	}
 	otlp.Store(o)
 	instances.Store(newInstances)
+	// Instruments created with the previous meter would not be exported.
+	resetInstruments()
 
+	if conf.SkipGlobalProviders {
+		return nil
+	}
+	propagator := propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	)
+	otel.SetTextMapPropagator(propagator)
 	otel.SetTracerProvider(tracerProvider)
 	global.SetLoggerProvider(loggerProvider)
 	otel.SetMeterProvider(meterProvider)
@@ -0,0 +1,355 @@
+package otel
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+)
+
+func TestCreateExporters_Protocol(t *testing.T) {
+	ctx := context.Background()
+
+	grpcConfig := &Config{OtlpEndpoint: "http://localhost:4317", OtlpProtocol: ProtocolGRPC}
+	logExporter, err := createLogExporter(ctx, grpcConfig)
+	if err != nil {
+		t.Fatalf("failed to create the log exporter: %v", err)
+	}
+	if _, ok := logExporter.(*otlploggrpc.Exporter); !ok {
+		t.Errorf("expected a gRPC log exporter, got %T", logExporter)
+	}
+	metricExporter, err := createMetricExporter(ctx, grpcConfig)
+	if err != nil {
+		t.Fatalf("failed to create the metric exporter: %v", err)
+	}
+	if _, ok := metricExporter.(*otlpmetricgrpc.Exporter); !ok {
+		t.Errorf("expected a gRPC metric exporter, got %T", metricExporter)
+	}
+	spanExporter, err := createSpanExporter(ctx, grpcConfig)
+	if err != nil {
+		t.Fatalf("failed to create the span exporter: %v", err)
+	}
+	_ = spanExporter.Shutdown(ctx)
+	_ = logExporter.Shutdown(ctx)
+	_ = metricExporter.Shutdown(ctx)
+
+	httpConfig := &Config{OtlpEndpoint: "http://localhost:4318"}
+	logExporter, err = createLogExporter(ctx, httpConfig)
+	if err != nil {
+		t.Fatalf("failed to create the log exporter: %v", err)
+	}
+	if _, ok := logExporter.(*otlploghttp.Exporter); !ok {
+		t.Errorf("expected an HTTP log exporter by default, got %T", logExporter)
+	}
+	metricExporter, err = createMetricExporter(ctx, httpConfig)
+	if err != nil {
+		t.Fatalf("failed to create the metric exporter: %v", err)
+	}
+	if _, ok := metricExporter.(*otlpmetrichttp.Exporter); !ok {
+		t.Errorf("expected an HTTP metric exporter by default, got %T", metricExporter)
+	}
+	_ = logExporter.Shutdown(ctx)
+	_ = metricExporter.Shutdown(ctx)
+}
+
+func TestCreateExporters_TLSConfig(t *testing.T) {
+	ctx := context.Background()
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	for _, protocol := range []string{ProtocolHTTPProtobuf, ProtocolGRPC} {
+		// The TLS configuration is not applied to insecure endpoints, which the
+		// exporters would otherwise reject.
+		for _, endpoint := range []string{"http://localhost:4318", "https://localhost:4318"} {
+			config := &Config{
+				OtlpEndpoint:  endpoint,
+				OtlpProtocol:  protocol,
+				OtlpTLSConfig: tlsConfig,
+				OtlpHeaders:   map[string]string{"api-key": "secret"},
+			}
+			spanExporter, err := createSpanExporter(ctx, config)
+			if err != nil {
+				t.Fatalf("failed to create the %s span exporter for %s: %v", protocol, endpoint, err)
+			}
+			_ = spanExporter.Shutdown(ctx)
+		}
+	}
+}
+
+func TestCreateTracerProvider_AdditionalExporters(t *testing.T) {
+	ctx := context.Background()
+	server := newTestOTLPServer(t)
+	server.healthy.Store(true)
+	customSampler.SetConfig(&gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{{
+			Name: gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfigNameMatchConfig{
+				MatchParts: gql.MatchParts{MatchValue: "dropped"},
+			},
+			SamplingRatio: 0,
+		}},
+	})
+	defer customSampler.SetConfig(nil)
+
+	sampled := &syncTestExporter{}
+	unsampled := &syncTestExporter{}
+	config := &Config{
+		OtlpEndpoint:     "http://" + server.endpoint(),
+		OtlpCompression:  CompressionNone,
+		SpanMaxQueueSize: sdktrace.DefaultMaxQueueSize,
+		AdditionalSpanExporters: []AdditionalSpanExporter{
+			{Exporter: sampled, Sampled: true},
+			{Exporter: unsampled, BatchOptions: []sdktrace.BatchSpanProcessorOption{sdktrace.WithMaxExportBatchSize(1)}},
+		},
+	}
+	provider, err := createTracerProvider(ctx, config, resource.Empty(), nil)
+	if err != nil {
+		t.Fatalf("failed to create the tracer provider: %v", err)
+	}
+	for _, name := range []string{"kept", "dropped"} {
+		_, span := provider.Tracer("test").Start(ctx, name)
+		span.End()
+	}
+	if err := provider.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shut down the tracer provider: %v", err)
+	}
+
+	if spans, _ := server.received(); !slices.Equal(spans, []string{"kept"}) {
+		t.Errorf("expected LaunchDarkly to receive the sampled spans, got %v", spans)
+	}
+	if names := sampled.names(); !slices.Equal(names, []string{"kept"}) {
+		t.Errorf("expected the sampled exporter to receive the sampled spans, got %v", names)
+	}
+	if names := unsampled.names(); !slices.Equal(names, []string{"kept", "dropped"}) {
+		t.Errorf("expected the unsampled exporter to receive every span, got %v", names)
+	}
+	if !sampled.shutdown || !unsampled.shutdown {
+		t.Error("expected the additional exporters to be shut down with the provider")
+	}
+}
+
+// fanOutSamplingConfig samples one in two of the spans and records named
+// "ratio-*", and rate limits the spans and records named "limited".
+func fanOutSamplingConfig() *gql.GetSamplingConfigSamplingSamplingConfig {
+	return &gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{
+			{
+				Name: gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfigNameMatchConfig{
+					MatchParts: gql.MatchParts{RegexValue: "^ratio-"},
+				},
+				SamplingRatio: 2,
+			},
+			{
+				Name: gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfigNameMatchConfig{
+					MatchParts: gql.MatchParts{RegexValue: "^limited-"},
+				},
+				SamplingRatio: 1,
+			},
+		},
+		Logs: []gql.GetSamplingConfigSamplingSamplingConfigLogsLogSamplingConfig{
+			{
+				Message: gql.GetSamplingConfigSamplingSamplingConfigLogsLogSamplingConfigMessageMatchConfig{
+					MatchParts: gql.MatchParts{RegexValue: "^ratio-"},
+				},
+				SamplingRatio: 2,
+			},
+			{
+				Message: gql.GetSamplingConfigSamplingSamplingConfigLogsLogSamplingConfigMessageMatchConfig{
+					MatchParts: gql.MatchParts{RegexValue: "^limited-"},
+				},
+				SamplingRatio: 1,
+			},
+		},
+	}
+}
+
+func TestCreateTracerProvider_SampledExportersReceiveExportedSpans(t *testing.T) {
+	ctx := context.Background()
+	server := newTestOTLPServer(t)
+	server.healthy.Store(true)
+	customSampler.SetLocalConfig(fanOutSamplingConfig())
+	customSampler.SetLocalRateLimits(RateLimits{Spans: []float64{0, 2}})
+	defer func() {
+		customSampler.SetLocalConfig(nil)
+		customSampler.SetLocalRateLimits(RateLimits{})
+	}()
+
+	sampled := &syncTestExporter{}
+	config := &Config{
+		OtlpEndpoint:            "http://" + server.endpoint(),
+		OtlpCompression:         CompressionNone,
+		SpanMaxQueueSize:        sdktrace.DefaultMaxQueueSize,
+		AdditionalSpanExporters: []AdditionalSpanExporter{{Exporter: sampled, Sampled: true}},
+	}
+	provider, err := createTracerProvider(ctx, config, resource.Empty(), nil)
+	if err != nil {
+		t.Fatalf("failed to create the tracer provider: %v", err)
+	}
+	for i := range 40 {
+		_, span := provider.Tracer("test").Start(ctx, fmt.Sprintf("ratio-%d", i))
+		span.End()
+	}
+	for i := range 10 {
+		_, span := provider.Tracer("test").Start(ctx, fmt.Sprintf("limited-%d", i))
+		span.End()
+	}
+	if err := provider.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shut down the tracer provider: %v", err)
+	}
+
+	exported, _ := server.received()
+	names := sampled.names()
+	slices.Sort(exported)
+	slices.Sort(names)
+	if len(exported) == 0 || len(exported) == 50 {
+		t.Fatalf("expected some of the spans to be sampled, got %v", exported)
+	}
+	if !slices.Equal(names, exported) {
+		t.Errorf("expected the sampled exporter to receive the spans exported to LaunchDarkly %v, got %v",
+			exported, names)
+	}
+	if !sampled.shutdown {
+		t.Error("expected the sampled exporter to be shut down with the provider")
+	}
+}
+
+func TestCreateLoggerProvider_SampledExportersReceiveExportedRecords(t *testing.T) {
+	ctx := context.Background()
+	server := newTestOTLPServer(t)
+	server.healthy.Store(true)
+	customSampler.SetLocalConfig(fanOutSamplingConfig())
+	customSampler.SetLocalRateLimits(RateLimits{Logs: []float64{0, 2}})
+	defer func() {
+		customSampler.SetLocalConfig(nil)
+		customSampler.SetLocalRateLimits(RateLimits{})
+	}()
+
+	sampled := &testLogExporter{}
+	config := &Config{
+		OtlpEndpoint:           "http://" + server.endpoint(),
+		OtlpCompression:        CompressionNone,
+		AdditionalLogExporters: []AdditionalLogExporter{{Exporter: sampled, Sampled: true}},
+	}
+	provider, err := createLoggerProvider(ctx, config, resource.Empty())
+	if err != nil {
+		t.Fatalf("failed to create the logger provider: %v", err)
+	}
+	for _, prefix := range []string{"ratio", "limited"} {
+		for i := range 20 {
+			var record log.Record
+			record.SetBody(log.StringValue(fmt.Sprintf("%s-%d", prefix, i)))
+			provider.Logger("test").Emit(ctx, record)
+		}
+	}
+	if err := provider.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shut down the logger provider: %v", err)
+	}
+
+	_, exported := server.received()
+	var bodies []string
+	for _, record := range sampled.exportedRecords {
+		bodies = append(bodies, record.Body().AsString())
+	}
+	slices.Sort(exported)
+	slices.Sort(bodies)
+	if len(exported) == 0 || len(exported) == 40 {
+		t.Fatalf("expected some of the records to be sampled, got %v", exported)
+	}
+	if !slices.Equal(bodies, exported) {
+		t.Errorf("expected the sampled exporter to receive the records exported to LaunchDarkly %v, got %v",
+			exported, bodies)
+	}
+}
+
+func TestCreateMeterProvider_AdditionalMetricReader(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	config := &Config{
+		OtlpEndpoint:            "http://localhost:4318",
+		AdditionalMetricReaders: []sdkmetric.Reader{reader},
+	}
+	provider, err := createMeterProvider(ctx, config, resource.Empty())
+	if err != nil {
+		t.Fatalf("failed to create the meter provider: %v", err)
+	}
+	defer func() { _ = provider.Shutdown(ctx) }()
+
+	counter, _ := provider.Meter("test").Int64Counter("requests")
+	counter.Add(ctx, 1)
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &data); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	if len(data.ScopeMetrics) != 1 || data.ScopeMetrics[0].Metrics[0].Name != "requests" {
+		t.Errorf("expected the additional reader to collect the metric, got %+v", data.ScopeMetrics)
+	}
+}
+
+func TestShutdownWithContext_Deadline(t *testing.T) {
+	// The endpoint never responds, so exports only end when the deadline is
+	// reached.
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	SetConfig(Config{
+		OtlpEndpoint:     server.URL,
+		SpanMaxQueueSize: sdktrace.DefaultMaxQueueSize,
+		SpanRetry:        &RetryConfig{Enabled: false},
+		LogRetry:         &RetryConfig{Enabled: false},
+		MetricRetry:      &RetryConfig{Enabled: false},
+	})
+	if err := StartOTLP(); err != nil {
+		t.Fatalf("StartOTLP failed: %v", err)
+	}
+	ctx := context.Background()
+	_, span := GetTracer().Start(ctx, "pending")
+	span.End()
+	var record log.Record
+	record.SetBody(log.StringValue("pending"))
+	GetLogger().Emit(ctx, record)
+
+	flushCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := Flush(flushCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the flush to end at the deadline, got %v", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err := ShutdownWithContext(shutdownCtx)
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Errorf("expected the providers to be shut down in parallel within the deadline, took %v", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+	if otlp.Load() != nil {
+		t.Error("expected the OTLP instances to be removed")
+	}
+	if err := Flush(ctx); err != nil {
+		t.Errorf("expected flushing after shutdown to do nothing, got %v", err)
+	}
+}
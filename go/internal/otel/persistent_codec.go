@@ -0,0 +1,712 @@
+package otel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The version of the format of persisted batches. Batches written with a
+// different version are discarded. This must be incremented whenever the
+// format changes incompatibly.
+const persistedBatchVersion = 1
+
+// persistedBatch is a batch of spans or log records as it is written to disk.
+// Resources and instrumentation scopes are usually shared by every item of a
+// batch, so they are stored once and referenced by index.
+type persistedBatch struct {
+	Version   int                 `json:"version"`
+	Resources []persistedResource `json:"resources"`
+	Scopes    []persistedScope    `json:"scopes"`
+	Spans     []persistedSpan     `json:"spans,omitempty"`
+	Logs      []persistedLog      `json:"logs,omitempty"`
+}
+
+type persistedResource struct {
+	SchemaURL  string               `json:"schemaUrl,omitempty"`
+	Attributes []persistedAttribute `json:"attributes,omitempty"`
+}
+
+type persistedScope struct {
+	Name       string               `json:"name"`
+	Version    string               `json:"version,omitempty"`
+	SchemaURL  string               `json:"schemaUrl,omitempty"`
+	Attributes []persistedAttribute `json:"attributes,omitempty"`
+}
+
+// persistedAttribute is an attribute with its type. Floating point values are
+// written as strings, as JSON cannot represent NaN or infinities.
+type persistedAttribute struct {
+	Key   string          `json:"k"`
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+type persistedSpanContext struct {
+	TraceID    string `json:"traceId,omitempty"`
+	SpanID     string `json:"spanId,omitempty"`
+	TraceFlags byte   `json:"flags,omitempty"`
+	TraceState string `json:"state,omitempty"`
+	Remote     bool   `json:"remote,omitempty"`
+}
+
+type persistedEvent struct {
+	Name              string               `json:"name"`
+	Time              time.Time            `json:"time"`
+	Attributes        []persistedAttribute `json:"attributes,omitempty"`
+	DroppedAttributes int                  `json:"droppedAttributes,omitempty"`
+}
+
+type persistedLink struct {
+	SpanContext       persistedSpanContext `json:"spanContext"`
+	Attributes        []persistedAttribute `json:"attributes,omitempty"`
+	DroppedAttributes int                  `json:"droppedAttributes,omitempty"`
+}
+
+type persistedSpan struct {
+	Name              string               `json:"name"`
+	SpanContext       persistedSpanContext `json:"spanContext"`
+	Parent            persistedSpanContext `json:"parent"`
+	Kind              trace.SpanKind       `json:"kind"`
+	StartTime         time.Time            `json:"startTime"`
+	EndTime           time.Time            `json:"endTime"`
+	Attributes        []persistedAttribute `json:"attributes,omitempty"`
+	Events            []persistedEvent     `json:"events,omitempty"`
+	Links             []persistedLink      `json:"links,omitempty"`
+	StatusCode        codes.Code           `json:"statusCode,omitempty"`
+	StatusDescription string               `json:"statusDescription,omitempty"`
+	DroppedAttributes int                  `json:"droppedAttributes,omitempty"`
+	DroppedEvents     int                  `json:"droppedEvents,omitempty"`
+	DroppedLinks      int                  `json:"droppedLinks,omitempty"`
+	ChildSpanCount    int                  `json:"childSpanCount,omitempty"`
+	Resource          int                  `json:"resource"`
+	Scope             int                  `json:"scope"`
+}
+
+type persistedLog struct {
+	EventName         string              `json:"eventName,omitempty"`
+	Timestamp         time.Time           `json:"time"`
+	ObservedTimestamp time.Time           `json:"observedTime"`
+	Severity          log.Severity        `json:"severity,omitempty"`
+	SeverityText      string              `json:"severityText,omitempty"`
+	Body              persistedLogValue   `json:"body"`
+	Attributes        []persistedKeyValue `json:"attributes,omitempty"`
+	TraceID           string              `json:"traceId,omitempty"`
+	SpanID            string              `json:"spanId,omitempty"`
+	TraceFlags        byte                `json:"flags,omitempty"`
+	Resource          int                 `json:"resource"`
+	Scope             int                 `json:"scope"`
+}
+
+// persistedLogValue is a log value with its kind. Slices and maps hold their
+// items, and other values are held like the values of attributes.
+type persistedLogValue struct {
+	Kind  string              `json:"t"`
+	Value json.RawMessage     `json:"v,omitempty"`
+	Slice []persistedLogValue `json:"s,omitempty"`
+	Map   []persistedKeyValue `json:"m,omitempty"`
+}
+
+type persistedKeyValue struct {
+	Key   string            `json:"k"`
+	Value persistedLogValue `json:"v"`
+}
+
+// batchEncoder builds a persisted batch, storing each distinct resource and
+// scope once.
+type batchEncoder struct {
+	batch     persistedBatch
+	resources map[resourceKey]int
+	scopes    map[scopeKey]int
+}
+
+type resourceKey struct {
+	schemaURL string
+	attrs     attribute.Distinct
+}
+
+type scopeKey struct {
+	name, version, schemaURL string
+	attrs                    attribute.Distinct
+}
+
+func newBatchEncoder() *batchEncoder {
+	return &batchEncoder{
+		batch:     persistedBatch{Version: persistedBatchVersion},
+		resources: make(map[resourceKey]int),
+		scopes:    make(map[scopeKey]int),
+	}
+}
+
+func (e *batchEncoder) resource(res *resource.Resource) int {
+	if res == nil {
+		res = resource.Empty()
+	}
+	key := resourceKey{schemaURL: res.SchemaURL(), attrs: res.Equivalent()}
+	if i, ok := e.resources[key]; ok {
+		return i
+	}
+	e.resources[key] = len(e.batch.Resources)
+	e.batch.Resources = append(e.batch.Resources, persistedResource{
+		SchemaURL:  res.SchemaURL(),
+		Attributes: encodeAttributes(res.Attributes()),
+	})
+	return e.resources[key]
+}
+
+func (e *batchEncoder) scope(scope instrumentation.Scope) int {
+	key := scopeKey{
+		name:      scope.Name,
+		version:   scope.Version,
+		schemaURL: scope.SchemaURL,
+		attrs:     scope.Attributes.Equivalent(),
+	}
+	if i, ok := e.scopes[key]; ok {
+		return i
+	}
+	e.scopes[key] = len(e.batch.Scopes)
+	e.batch.Scopes = append(e.batch.Scopes, persistedScope{
+		Name:       scope.Name,
+		Version:    scope.Version,
+		SchemaURL:  scope.SchemaURL,
+		Attributes: encodeAttributes(scope.Attributes.ToSlice()),
+	})
+	return e.scopes[key]
+}
+
+// encodeSpans encodes a batch of spans.
+func encodeSpans(spans []sdktrace.ReadOnlySpan) ([]byte, error) {
+	e := newBatchEncoder()
+	for _, s := range spans {
+		events := make([]persistedEvent, 0, len(s.Events()))
+		for _, event := range s.Events() {
+			events = append(events, persistedEvent{
+				Name:              event.Name,
+				Time:              event.Time,
+				Attributes:        encodeAttributes(event.Attributes),
+				DroppedAttributes: event.DroppedAttributeCount,
+			})
+		}
+		links := make([]persistedLink, 0, len(s.Links()))
+		for _, link := range s.Links() {
+			links = append(links, persistedLink{
+				SpanContext:       encodeSpanContext(link.SpanContext),
+				Attributes:        encodeAttributes(link.Attributes),
+				DroppedAttributes: link.DroppedAttributeCount,
+			})
+		}
+		e.batch.Spans = append(e.batch.Spans, persistedSpan{
+			Name:              s.Name(),
+			SpanContext:       encodeSpanContext(s.SpanContext()),
+			Parent:            encodeSpanContext(s.Parent()),
+			Kind:              s.SpanKind(),
+			StartTime:         s.StartTime(),
+			EndTime:           s.EndTime(),
+			Attributes:        encodeAttributes(s.Attributes()),
+			Events:            events,
+			Links:             links,
+			StatusCode:        s.Status().Code,
+			StatusDescription: s.Status().Description,
+			DroppedAttributes: s.DroppedAttributes(),
+			DroppedEvents:     s.DroppedEvents(),
+			DroppedLinks:      s.DroppedLinks(),
+			ChildSpanCount:    s.ChildSpanCount(),
+			Resource:          e.resource(s.Resource()),
+			Scope:             e.scope(s.InstrumentationScope()),
+		})
+	}
+	return json.Marshal(&e.batch)
+}
+
+// decodeSpans decodes a batch of spans written by encodeSpans.
+func decodeSpans(data []byte) ([]sdktrace.ReadOnlySpan, error) {
+	batch, resources, scopes, err := decodeBatch(data)
+	if err != nil {
+		return nil, err
+	}
+	spans := make([]sdktrace.ReadOnlySpan, 0, len(batch.Spans))
+	for i := range batch.Spans {
+		s := &batch.Spans[i]
+		if s.Resource < 0 || s.Resource >= len(resources) || s.Scope < 0 || s.Scope >= len(scopes) {
+			return nil, fmt.Errorf("span %d references a missing resource or scope", i)
+		}
+		span := tracetest.SpanStub{
+			Name:                 s.Name,
+			SpanKind:             s.Kind,
+			StartTime:            s.StartTime,
+			EndTime:              s.EndTime,
+			Status:               sdktrace.Status{Code: s.StatusCode, Description: s.StatusDescription},
+			DroppedAttributes:    s.DroppedAttributes,
+			DroppedEvents:        s.DroppedEvents,
+			DroppedLinks:         s.DroppedLinks,
+			ChildSpanCount:       s.ChildSpanCount,
+			Resource:             resources[s.Resource],
+			InstrumentationScope: scopes[s.Scope],
+		}
+		if span.SpanContext, err = decodeSpanContext(s.SpanContext); err != nil {
+			return nil, err
+		}
+		if span.Parent, err = decodeSpanContext(s.Parent); err != nil {
+			return nil, err
+		}
+		if span.Attributes, err = decodeAttributes(s.Attributes); err != nil {
+			return nil, err
+		}
+		for _, event := range s.Events {
+			attrs, err := decodeAttributes(event.Attributes)
+			if err != nil {
+				return nil, err
+			}
+			span.Events = append(span.Events, sdktrace.Event{
+				Name:                  event.Name,
+				Time:                  event.Time,
+				Attributes:            attrs,
+				DroppedAttributeCount: event.DroppedAttributes,
+			})
+		}
+		for _, link := range s.Links {
+			sc, err := decodeSpanContext(link.SpanContext)
+			if err != nil {
+				return nil, err
+			}
+			attrs, err := decodeAttributes(link.Attributes)
+			if err != nil {
+				return nil, err
+			}
+			span.Links = append(span.Links, sdktrace.Link{
+				SpanContext:           sc,
+				Attributes:            attrs,
+				DroppedAttributeCount: link.DroppedAttributes,
+			})
+		}
+		spans = append(spans, span.Snapshot())
+	}
+	return spans, nil
+}
+
+// encodeLogs encodes a batch of log records.
+func encodeLogs(records []sdklog.Record) ([]byte, error) {
+	e := newBatchEncoder()
+	for i := range records {
+		r := &records[i]
+		persisted := persistedLog{
+			EventName:         r.EventName(),
+			Timestamp:         r.Timestamp(),
+			ObservedTimestamp: r.ObservedTimestamp(),
+			Severity:          r.Severity(),
+			SeverityText:      r.SeverityText(),
+			Body:              encodeLogValue(r.Body()),
+			TraceFlags:        byte(r.TraceFlags()),
+			Resource:          e.resource(r.Resource()),
+			Scope:             e.scope(r.InstrumentationScope()),
+		}
+		if traceID := r.TraceID(); traceID.IsValid() {
+			persisted.TraceID = traceID.String()
+		}
+		if spanID := r.SpanID(); spanID.IsValid() {
+			persisted.SpanID = spanID.String()
+		}
+		r.WalkAttributes(func(kv log.KeyValue) bool {
+			persisted.Attributes = append(persisted.Attributes, encodeLogKeyValue(kv))
+			return true
+		})
+		e.batch.Logs = append(e.batch.Logs, persisted)
+	}
+	return json.Marshal(&e.batch)
+}
+
+// recordCollector is a log processor which keeps the records emitted to it.
+type recordCollector struct {
+	records []sdklog.Record
+}
+
+func (c *recordCollector) OnEmit(_ context.Context, record *sdklog.Record) error {
+	c.records = append(c.records, record.Clone())
+	return nil
+}
+
+func (c *recordCollector) Enabled(context.Context, sdklog.EnabledParameters) bool { return true }
+func (c *recordCollector) Shutdown(context.Context) error                         { return nil }
+func (c *recordCollector) ForceFlush(context.Context) error                       { return nil }
+
+// decodeLogs decodes a batch of log records.
+// The OTEL SDK only creates log records with a resource and scope through a
+// logger, so the records are emitted with a logger of a provider with the
+// resource of the record, which neither limits nor deduplicates attributes.
+// The providers are shut down once the batch is decoded. The number of
+// attributes the record dropped cannot be set, so it is lost.
+func decodeLogs(data []byte) ([]sdklog.Record, error) {
+	batch, resources, scopes, err := decodeBatch(data)
+	if err != nil {
+		return nil, err
+	}
+	collector := &recordCollector{records: make([]sdklog.Record, 0, len(batch.Logs))}
+	providers := make([]*sdklog.LoggerProvider, len(resources))
+	defer func() {
+		for _, p := range providers {
+			if p != nil {
+				_ = p.Shutdown(context.Background())
+			}
+		}
+	}()
+	loggers := make(map[[2]int]log.Logger)
+	for i := range batch.Logs {
+		l := &batch.Logs[i]
+		if l.Resource < 0 || l.Resource >= len(resources) || l.Scope < 0 || l.Scope >= len(scopes) {
+			return nil, fmt.Errorf("log record %d references a missing resource or scope", i)
+		}
+		logger, ok := loggers[[2]int{l.Resource, l.Scope}]
+		if !ok {
+			if providers[l.Resource] == nil {
+				providers[l.Resource] = sdklog.NewLoggerProvider(
+					sdklog.WithResource(resources[l.Resource]),
+					sdklog.WithProcessor(collector),
+					sdklog.WithAttributeCountLimit(-1),
+					sdklog.WithAttributeValueLengthLimit(-1),
+					sdklog.WithAllowKeyDuplication(),
+				)
+			}
+			scope := scopes[l.Scope]
+			logger = providers[l.Resource].Logger(scope.Name,
+				log.WithInstrumentationVersion(scope.Version),
+				log.WithSchemaURL(scope.SchemaURL),
+				log.WithInstrumentationAttributes(scope.Attributes.ToSlice()...),
+			)
+			loggers[[2]int{l.Resource, l.Scope}] = logger
+		}
+
+		var record log.Record
+		record.SetEventName(l.EventName)
+		record.SetTimestamp(l.Timestamp)
+		record.SetSeverity(l.Severity)
+		record.SetSeverityText(l.SeverityText)
+		body, err := decodeLogValue(l.Body)
+		if err != nil {
+			return nil, err
+		}
+		record.SetBody(body)
+		for _, p := range l.Attributes {
+			kv, err := decodeLogKeyValue(p)
+			if err != nil {
+				return nil, err
+			}
+			record.AddAttributes(kv)
+		}
+		logger.Emit(context.Background(), record)
+
+		// The logger sets the observed timestamp of records which have none.
+		emitted := &collector.records[len(collector.records)-1]
+		emitted.SetObservedTimestamp(l.ObservedTimestamp)
+		if l.TraceID != "" {
+			traceID, err := trace.TraceIDFromHex(l.TraceID)
+			if err != nil {
+				return nil, err
+			}
+			emitted.SetTraceID(traceID)
+		}
+		if l.SpanID != "" {
+			spanID, err := trace.SpanIDFromHex(l.SpanID)
+			if err != nil {
+				return nil, err
+			}
+			emitted.SetSpanID(spanID)
+		}
+		emitted.SetTraceFlags(trace.TraceFlags(l.TraceFlags))
+	}
+	return collector.records, nil
+}
+
+func decodeBatch(data []byte) (*persistedBatch, []*resource.Resource, []instrumentation.Scope, error) {
+	var batch persistedBatch
+	if err := json.Unmarshal(data, &batch); err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding persisted batch: %w", err)
+	}
+	if batch.Version != persistedBatchVersion {
+		return nil, nil, nil, fmt.Errorf("unsupported persisted batch version %d", batch.Version)
+	}
+	resources := make([]*resource.Resource, 0, len(batch.Resources))
+	for _, r := range batch.Resources {
+		attrs, err := decodeAttributes(r.Attributes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		resources = append(resources, resource.NewWithAttributes(r.SchemaURL, attrs...))
+	}
+	scopes := make([]instrumentation.Scope, 0, len(batch.Scopes))
+	for _, s := range batch.Scopes {
+		attrs, err := decodeAttributes(s.Attributes)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		scopes = append(scopes, instrumentation.Scope{
+			Name:       s.Name,
+			Version:    s.Version,
+			SchemaURL:  s.SchemaURL,
+			Attributes: attribute.NewSet(attrs...),
+		})
+	}
+	return &batch, resources, scopes, nil
+}
+
+func encodeSpanContext(sc trace.SpanContext) persistedSpanContext {
+	if !sc.IsValid() {
+		return persistedSpanContext{}
+	}
+	return persistedSpanContext{
+		TraceID:    sc.TraceID().String(),
+		SpanID:     sc.SpanID().String(),
+		TraceFlags: byte(sc.TraceFlags()),
+		TraceState: sc.TraceState().String(),
+		Remote:     sc.IsRemote(),
+	}
+}
+
+func decodeSpanContext(p persistedSpanContext) (trace.SpanContext, error) {
+	if p.TraceID == "" && p.SpanID == "" {
+		return trace.SpanContext{}, nil
+	}
+	traceID, err := trace.TraceIDFromHex(p.TraceID)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+	spanID, err := trace.SpanIDFromHex(p.SpanID)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+	state, err := trace.ParseTraceState(p.TraceState)
+	if err != nil {
+		return trace.SpanContext{}, err
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(p.TraceFlags),
+		TraceState: state,
+		Remote:     p.Remote,
+	}), nil
+}
+
+func encodeAttributes(attrs []attribute.KeyValue) []persistedAttribute {
+	if len(attrs) == 0 {
+		return nil
+	}
+	encoded := make([]persistedAttribute, 0, len(attrs))
+	for _, kv := range attrs {
+		encoded = append(encoded, encodeAttribute(kv))
+	}
+	return encoded
+}
+
+func encodeAttribute(kv attribute.KeyValue) persistedAttribute {
+	var value any
+	switch kv.Value.Type() {
+	case attribute.BOOL:
+		value = kv.Value.AsBool()
+	case attribute.INT64:
+		value = kv.Value.AsInt64()
+	case attribute.FLOAT64:
+		value = formatFloat(kv.Value.AsFloat64())
+	case attribute.STRING:
+		value = kv.Value.AsString()
+	case attribute.BOOLSLICE:
+		value = kv.Value.AsBoolSlice()
+	case attribute.INT64SLICE:
+		value = kv.Value.AsInt64Slice()
+	case attribute.FLOAT64SLICE:
+		floats := kv.Value.AsFloat64Slice()
+		strs := make([]string, len(floats))
+		for i, f := range floats {
+			strs[i] = formatFloat(f)
+		}
+		value = strs
+	case attribute.STRINGSLICE:
+		value = kv.Value.AsStringSlice()
+	default:
+		return persistedAttribute{Key: string(kv.Key), Type: kv.Value.Type().String()}
+	}
+	// Values of these types can always be marshaled.
+	raw, _ := json.Marshal(value)
+	return persistedAttribute{Key: string(kv.Key), Type: kv.Value.Type().String(), Value: raw}
+}
+
+func decodeAttributes(attrs []persistedAttribute) ([]attribute.KeyValue, error) {
+	if len(attrs) == 0 {
+		return nil, nil
+	}
+	decoded := make([]attribute.KeyValue, 0, len(attrs))
+	for _, p := range attrs {
+		kv, err := decodeAttribute(p)
+		if err != nil {
+			return nil, err
+		}
+		decoded = append(decoded, kv)
+	}
+	return decoded, nil
+}
+
+func decodeAttribute(p persistedAttribute) (attribute.KeyValue, error) {
+	key := attribute.Key(p.Key)
+	var err error
+	switch p.Type {
+	case attribute.BOOL.String():
+		var v bool
+		err = json.Unmarshal(p.Value, &v)
+		return key.Bool(v), err
+	case attribute.INT64.String():
+		var v int64
+		err = json.Unmarshal(p.Value, &v)
+		return key.Int64(v), err
+	case attribute.FLOAT64.String():
+		var s string
+		if err = json.Unmarshal(p.Value, &s); err != nil {
+			return attribute.KeyValue{}, err
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		return key.Float64(v), err
+	case attribute.STRING.String():
+		var v string
+		err = json.Unmarshal(p.Value, &v)
+		return key.String(v), err
+	case attribute.BOOLSLICE.String():
+		var v []bool
+		err = json.Unmarshal(p.Value, &v)
+		return key.BoolSlice(v), err
+	case attribute.INT64SLICE.String():
+		var v []int64
+		err = json.Unmarshal(p.Value, &v)
+		return key.Int64Slice(v), err
+	case attribute.FLOAT64SLICE.String():
+		var strs []string
+		if err = json.Unmarshal(p.Value, &strs); err != nil {
+			return attribute.KeyValue{}, err
+		}
+		v := make([]float64, len(strs))
+		for i, s := range strs {
+			if v[i], err = strconv.ParseFloat(s, 64); err != nil {
+				return attribute.KeyValue{}, err
+			}
+		}
+		return key.Float64Slice(v), nil
+	case attribute.STRINGSLICE.String():
+		var v []string
+		err = json.Unmarshal(p.Value, &v)
+		return key.StringSlice(v), err
+	case attribute.EMPTY.String():
+		return attribute.KeyValue{Key: key}, nil
+	default:
+		return attribute.KeyValue{}, fmt.Errorf("unsupported attribute type %q", p.Type)
+	}
+}
+
+func encodeLogKeyValue(kv log.KeyValue) persistedKeyValue {
+	return persistedKeyValue{Key: kv.Key, Value: encodeLogValue(kv.Value)}
+}
+
+func encodeLogValue(v log.Value) persistedLogValue {
+	var value any
+	switch v.Kind() {
+	case log.KindBool:
+		value = v.AsBool()
+	case log.KindInt64:
+		value = v.AsInt64()
+	case log.KindFloat64:
+		value = formatFloat(v.AsFloat64())
+	case log.KindString:
+		value = v.AsString()
+	case log.KindBytes:
+		value = v.AsBytes()
+	case log.KindSlice:
+		items := v.AsSlice()
+		slice := make([]persistedLogValue, 0, len(items))
+		for _, item := range items {
+			slice = append(slice, encodeLogValue(item))
+		}
+		return persistedLogValue{Kind: v.Kind().String(), Slice: slice}
+	case log.KindMap:
+		kvs := v.AsMap()
+		m := make([]persistedKeyValue, 0, len(kvs))
+		for _, kv := range kvs {
+			m = append(m, encodeLogKeyValue(kv))
+		}
+		return persistedLogValue{Kind: v.Kind().String(), Map: m}
+	default:
+		return persistedLogValue{Kind: v.Kind().String()}
+	}
+	// Values of these types can always be marshaled.
+	raw, _ := json.Marshal(value)
+	return persistedLogValue{Kind: v.Kind().String(), Value: raw}
+}
+
+func decodeLogKeyValue(p persistedKeyValue) (log.KeyValue, error) {
+	v, err := decodeLogValue(p.Value)
+	return log.KeyValue{Key: p.Key, Value: v}, err
+}
+
+func decodeLogValue(p persistedLogValue) (log.Value, error) {
+	var err error
+	switch p.Kind {
+	case log.KindBool.String():
+		var v bool
+		err = json.Unmarshal(p.Value, &v)
+		return log.BoolValue(v), err
+	case log.KindInt64.String():
+		var v int64
+		err = json.Unmarshal(p.Value, &v)
+		return log.Int64Value(v), err
+	case log.KindFloat64.String():
+		var s string
+		if err = json.Unmarshal(p.Value, &s); err != nil {
+			return log.Value{}, err
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		return log.Float64Value(v), err
+	case log.KindString.String():
+		var v string
+		err = json.Unmarshal(p.Value, &v)
+		return log.StringValue(v), err
+	case log.KindBytes.String():
+		var v []byte
+		err = json.Unmarshal(p.Value, &v)
+		return log.BytesValue(v), err
+	case log.KindSlice.String():
+		items := make([]log.Value, 0, len(p.Slice))
+		for _, item := range p.Slice {
+			v, err := decodeLogValue(item)
+			if err != nil {
+				return log.Value{}, err
+			}
+			items = append(items, v)
+		}
+		return log.SliceValue(items...), nil
+	case log.KindMap.String():
+		kvs := make([]log.KeyValue, 0, len(p.Map))
+		for _, item := range p.Map {
+			kv, err := decodeLogKeyValue(item)
+			if err != nil {
+				return log.Value{}, err
+			}
+			kvs = append(kvs, kv)
+		}
+		return log.MapValue(kvs...), nil
+	case log.KindEmpty.String():
+		return log.Value{}, nil
+	default:
+		return log.Value{}, fmt.Errorf("unsupported log value kind %q", p.Kind)
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
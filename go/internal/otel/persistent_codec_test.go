@@ -0,0 +1,238 @@
+package otel
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/log/logtest"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func testResource() *resource.Resource {
+	return resource.NewWithAttributes("https://opentelemetry.io/schemas/1.34.0",
+		attribute.String("service.name", "checkout"),
+		attribute.Int64("process.pid", 1234),
+	)
+}
+
+func testScope() instrumentation.Scope {
+	return instrumentation.Scope{
+		Name:       "test-scope",
+		Version:    "1.0.0",
+		Attributes: attribute.NewSet(attribute.Bool("scope.enabled", true)),
+	}
+}
+
+func testSpanStub(name string) tracetest.SpanStub {
+	traceState, _ := trace.ParseTraceState("ot=th:8")
+	start := time.Unix(1000, 123).UTC()
+	return tracetest.SpanStub{
+		Name: name,
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{1, 2, 3},
+			SpanID:     trace.SpanID{4, 5, 6},
+			TraceFlags: trace.FlagsSampled,
+			TraceState: traceState,
+		}),
+		Parent: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{1, 2, 3},
+			SpanID:  trace.SpanID{7, 8, 9},
+			Remote:  true,
+		}),
+		SpanKind:  trace.SpanKindServer,
+		StartTime: start,
+		EndTime:   start.Add(time.Second),
+		Attributes: []attribute.KeyValue{
+			attribute.Bool("bool", true),
+			attribute.Int64("int", math.MaxInt64),
+			attribute.Float64("float", 1.5),
+			attribute.Float64("nan", math.NaN()),
+			attribute.String("string", "value"),
+			attribute.BoolSlice("bools", []bool{true, false}),
+			attribute.Int64Slice("ints", []int64{1, -2}),
+			attribute.Float64Slice("floats", []float64{0.25, math.Inf(1)}),
+			attribute.StringSlice("strings", []string{"a", "b"}),
+		},
+		Events: []sdktrace.Event{{
+			Name:                  "exception",
+			Time:                  start.Add(time.Millisecond),
+			Attributes:            []attribute.KeyValue{attribute.String("exception.message", "failed")},
+			DroppedAttributeCount: 1,
+		}},
+		Links: []sdktrace.Link{{
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: trace.TraceID{9},
+				SpanID:  trace.SpanID{9},
+			}),
+			Attributes: []attribute.KeyValue{attribute.String("link", "value")},
+		}},
+		Status:               sdktrace.Status{Code: codes.Error, Description: "failed"},
+		DroppedAttributes:    2,
+		DroppedEvents:        3,
+		DroppedLinks:         4,
+		ChildSpanCount:       5,
+		Resource:             testResource(),
+		InstrumentationScope: testScope(),
+	}
+}
+
+func TestPersistentCodec_Spans(t *testing.T) {
+	spans := []sdktrace.ReadOnlySpan{testSpanStub("first").Snapshot(), testSpanStub("second").Snapshot()}
+
+	data, err := encodeSpans(spans)
+	if err != nil {
+		t.Fatalf("encodeSpans failed: %v", err)
+	}
+	decoded, err := decodeSpans(data)
+	if err != nil {
+		t.Fatalf("decodeSpans failed: %v", err)
+	}
+	if len(decoded) != len(spans) {
+		t.Fatalf("expected %d spans, got %d", len(spans), len(decoded))
+	}
+
+	for i := range spans {
+		expected := tracetest.SpanStubFromReadOnlySpan(spans[i])
+		actual := tracetest.SpanStubFromReadOnlySpan(decoded[i])
+		// NaN is never equal to itself, so it is compared separately.
+		nan := actual.Attributes[3]
+		if nan.Key != "nan" || !math.IsNaN(nan.Value.AsFloat64()) {
+			t.Errorf("expected the NaN attribute to be preserved, got %v", nan)
+		}
+		expected.Attributes = append(expected.Attributes[:3:3], expected.Attributes[4:]...)
+		actual.Attributes = append(actual.Attributes[:3:3], actual.Attributes[4:]...)
+		if !actual.Resource.Equal(expected.Resource) {
+			t.Errorf("expected resource %v, got %v", expected.Resource, actual.Resource)
+		}
+		expected.Resource, actual.Resource = nil, nil
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected span %+v, got %+v", expected, actual)
+		}
+	}
+}
+
+func TestPersistentCodec_SharesResources(t *testing.T) {
+	spans := []sdktrace.ReadOnlySpan{testSpanStub("first").Snapshot(), testSpanStub("second").Snapshot()}
+	other := testSpanStub("other")
+	other.Resource = resource.NewSchemaless(attribute.String("service.name", "other"))
+	spans = append(spans, other.Snapshot())
+
+	e := newBatchEncoder()
+	for _, s := range spans {
+		e.resource(s.Resource())
+		e.scope(s.InstrumentationScope())
+	}
+	if len(e.batch.Resources) != 2 || len(e.batch.Scopes) != 1 {
+		t.Errorf("expected 2 resources and 1 scope, got %d and %d", len(e.batch.Resources), len(e.batch.Scopes))
+	}
+}
+
+func TestPersistentCodec_Logs(t *testing.T) {
+	scope := testScope()
+	records := []sdklog.Record{
+		logtest.RecordFactory{
+			EventName:         "event",
+			Timestamp:         time.Unix(1000, 0).UTC(),
+			ObservedTimestamp: time.Unix(1001, 0).UTC(),
+			Severity:          log.SeverityWarn,
+			SeverityText:      "WARN",
+			Body: log.MapValue(
+				log.String("message", "hello"),
+				log.Slice("items", log.Int64Value(1), log.Float64Value(2.5), log.BoolValue(true)),
+				log.Bytes("raw", []byte{0, 1, 2}),
+				log.Empty("empty"),
+			),
+			Attributes:           []log.KeyValue{log.String("user", "1"), log.Int64("count", 3)},
+			TraceID:              trace.TraceID{1},
+			SpanID:               trace.SpanID{2},
+			TraceFlags:           trace.FlagsSampled,
+			Resource:             testResource(),
+			InstrumentationScope: &scope,
+		}.NewRecord(),
+		logtest.RecordFactory{
+			Body:                 log.StringValue("without a trace"),
+			Resource:             testResource(),
+			InstrumentationScope: &scope,
+		}.NewRecord(),
+	}
+
+	data, err := encodeLogs(records)
+	if err != nil {
+		t.Fatalf("encodeLogs failed: %v", err)
+	}
+	decoded, err := decodeLogs(data)
+	if err != nil {
+		t.Fatalf("decodeLogs failed: %v", err)
+	}
+	if len(decoded) != len(records) {
+		t.Fatalf("expected %d records, got %d", len(records), len(decoded))
+	}
+
+	for i := range records {
+		expected, actual := &records[i], &decoded[i]
+		if !actual.Resource().Equal(expected.Resource()) {
+			t.Errorf("expected resource %v, got %v", expected.Resource(), actual.Resource())
+		}
+		if !reflect.DeepEqual(actual.InstrumentationScope(), expected.InstrumentationScope()) {
+			t.Errorf("expected scope %v, got %v", expected.InstrumentationScope(), actual.InstrumentationScope())
+		}
+		if actual.EventName() != expected.EventName() ||
+			!actual.Timestamp().Equal(expected.Timestamp()) ||
+			!actual.ObservedTimestamp().Equal(expected.ObservedTimestamp()) ||
+			actual.Severity() != expected.Severity() ||
+			actual.SeverityText() != expected.SeverityText() ||
+			actual.TraceID() != expected.TraceID() ||
+			actual.SpanID() != expected.SpanID() ||
+			actual.TraceFlags() != expected.TraceFlags() {
+			t.Errorf("record %d was not preserved, got %+v", i, actual)
+		}
+		if !actual.Body().Equal(expected.Body()) {
+			t.Errorf("expected body %v, got %v", expected.Body(), actual.Body())
+		}
+		var expectedAttrs, actualAttrs []log.KeyValue
+		expected.WalkAttributes(func(kv log.KeyValue) bool {
+			expectedAttrs = append(expectedAttrs, kv)
+			return true
+		})
+		actual.WalkAttributes(func(kv log.KeyValue) bool {
+			actualAttrs = append(actualAttrs, kv)
+			return true
+		})
+		if len(actualAttrs) != len(expectedAttrs) {
+			t.Fatalf("expected attributes %v, got %v", expectedAttrs, actualAttrs)
+		}
+		for j := range expectedAttrs {
+			if !actualAttrs[j].Equal(expectedAttrs[j]) {
+				t.Errorf("expected attributes %v, got %v", expectedAttrs, actualAttrs)
+			}
+		}
+	}
+	if decoded[1].TraceID().IsValid() || decoded[1].SpanID().IsValid() {
+		t.Errorf("expected a record without a trace, got %+v", decoded[1])
+	}
+}
+
+func TestPersistentCodec_RejectsOtherVersions(t *testing.T) {
+	if _, err := decodeSpans([]byte(`{"version":2}`)); err == nil {
+		t.Error("expected a batch with a different version to be rejected")
+	}
+	if _, err := decodeLogs([]byte(`{"version":2}`)); err == nil {
+		t.Error("expected a batch of logs with a different version to be rejected")
+	}
+	if _, err := decodeLogs([]byte(`{"version":1,"logs":[{"body":{"t":"Empty"},"resource":0,"scope":0}]}`)); err == nil {
+		t.Error("expected a log record referencing a missing resource to be rejected")
+	}
+	if _, err := decodeSpans([]byte(`{"version":1,"spans":[{"name":"a","resource":0,"scope":0}]}`)); err == nil {
+		t.Error("expected a span referencing a missing resource to be rejected")
+	}
+}
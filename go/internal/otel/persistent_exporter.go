@@ -0,0 +1,357 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logging"
+)
+
+// The delays between attempts to export queued batches while the endpoint is
+// failing.
+const (
+	replayInitialInterval = 5 * time.Second
+	replayMaxInterval     = time.Minute
+)
+
+// spiller exports batches, writing those which fail to export to a persistent
+// queue. While the queue holds batches the endpoint is assumed to be failing,
+// so new batches are queued without being exported, which keeps the batch
+// processor from blocking and dropping data. A background goroutine exports
+// the queued batches, oldest first, once the endpoint recovers.
+//
+// Batches are only discarded when the endpoint rejects them, which it would do
+// again if they were exported again. Batches which fail for other reasons,
+// such as a timeout or the endpoint being unavailable, are retried.
+type spiller[T any] struct {
+	queue   *persistentQueue
+	export  func(context.Context, []T) error
+	encode  func([]T) ([]byte, error)
+	decode  func([]byte) ([]T, error)
+	timeout time.Duration
+	// The initial delay between attempts to export queued batches.
+	retryInterval time.Duration
+
+	// Exporters are not required to be safe for concurrent use, and batches
+	// are exported both by the batch processor and while replaying.
+	exportMu sync.Mutex
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// replayResult is the result of an attempt to export a queued batch.
+type replayResult int
+
+const (
+	// The batch was exported and removed from the queue.
+	replayExported replayResult = iota
+	// The batch was removed from the queue without being exported, because it
+	// could not be read or the endpoint rejected it.
+	replayDiscarded
+	// The batch could not be exported and remains in the queue.
+	replayFailed
+)
+
+func newSpiller[T any](
+	queue *persistentQueue,
+	export func(context.Context, []T) error,
+	encode func([]T) ([]byte, error),
+	decode func([]byte) ([]T, error),
+	timeout time.Duration,
+	retryInterval time.Duration,
+) *spiller[T] {
+	s := &spiller[T]{
+		queue:         queue,
+		export:        export,
+		encode:        encode,
+		decode:        decode,
+		timeout:       timeout,
+		retryInterval: retryInterval,
+		wake:          make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go s.replay()
+	return s
+}
+
+// exportBatch exports a batch, or queues it if the endpoint is failing.
+func (s *spiller[T]) exportBatch(ctx context.Context, items []T) error {
+	if len(items) == 0 {
+		return nil
+	}
+	if s.queue.len() == 0 {
+		rejected, err := s.exportSerialized(ctx, items)
+		if err == nil {
+			return nil
+		}
+		if rejected {
+			// The endpoint would reject the batch again, so it is not queued.
+			return err
+		}
+		logging.GetLogger().Errorf("export failed, queueing %d items: %v", len(items), err)
+	}
+	data, err := s.encode(items)
+	if err == nil {
+		err = s.queue.push(data)
+	}
+	if err != nil {
+		return errors.Join(errors.New("failed to queue items which could not be exported"), err)
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// exportSerialized exports a batch, and returns whether the endpoint rejected
+// it when the export fails.
+func (s *spiller[T]) exportSerialized(ctx context.Context, items []T) (bool, error) {
+	s.exportMu.Lock()
+	defer s.exportMu.Unlock()
+	ctx, outcome := withExportOutcome(ctx)
+	err := s.export(ctx, items)
+	return outcome.rejected(err), err
+}
+
+// replay exports queued batches until the spiller is shut down.
+func (s *spiller[T]) replay() {
+	defer close(s.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+
+	interval := s.retryInterval
+	for {
+		if s.queue.len() == 0 {
+			interval = s.retryInterval
+			select {
+			case <-s.wake:
+			case <-s.stop:
+				return
+			}
+			// The batch which woke the goroutine has only just failed, so the
+			// endpoint is given time to recover.
+		} else {
+			switch s.replayOldest(ctx) {
+			case replayExported:
+				interval = s.retryInterval
+				continue
+			case replayDiscarded:
+				continue
+			case replayFailed:
+			}
+		}
+		select {
+		case <-time.After(interval):
+		case <-s.stop:
+			return
+		}
+		interval = max(min(interval*2, replayMaxInterval), s.retryInterval)
+	}
+}
+
+// replayOldest exports the oldest queued batch, and removes it from the queue
+// once it is exported. Batches which cannot be read, or which the endpoint
+// rejects, are removed without being exported.
+func (s *spiller[T]) replayOldest(ctx context.Context) replayResult {
+	batches := s.queue.peek(1)
+	if len(batches) == 0 {
+		return replayDiscarded
+	}
+	batch := batches[0]
+	data, err := s.queue.read(batch)
+	if err != nil {
+		logging.GetLogger().Errorf("discarding an unreadable queued batch: %v", err)
+		s.queue.remove(batch)
+		return replayDiscarded
+	}
+	items, err := s.decode(data)
+	if err != nil {
+		logging.GetLogger().Errorf("discarding an invalid queued batch: %v", err)
+		s.queue.remove(batch)
+		return replayDiscarded
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	rejected, err := s.exportSerialized(ctx, items)
+	switch {
+	case err == nil:
+		s.queue.remove(batch)
+		return replayExported
+	case rejected:
+		logging.GetLogger().Errorf("discarding a queued batch which was rejected: %v", err)
+		s.queue.remove(batch)
+		return replayDiscarded
+	default:
+		return replayFailed
+	}
+}
+
+// shutdown stops exporting queued batches, and closes the queue. Batches which
+// remain in the queue are exported by the next process using the queue.
+func (s *spiller[T]) shutdown() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.queue.close()
+	})
+	<-s.done
+}
+
+// exportOutcome records the response of the endpoint to the last request of an
+// export, so that an export which failed because the endpoint rejected the data
+// can be told apart from one which may succeed when it is retried. It is added
+// to the context of the export, and is set by the telemetry round tripper and
+// interceptor of the exporter.
+type exportOutcome struct {
+	mu sync.Mutex
+	// Whether the endpoint responded to the last request.
+	responded bool
+	// Whether the response asked for the request to be retried.
+	retryable bool
+}
+
+type exportOutcomeKey struct{}
+
+func withExportOutcome(ctx context.Context) (context.Context, *exportOutcome) {
+	outcome := &exportOutcome{}
+	return context.WithValue(ctx, exportOutcomeKey{}, outcome), outcome
+}
+
+// exportOutcomeFromContext returns the outcome of the export the context
+// belongs to, or nil when the outcome is not recorded.
+func exportOutcomeFromContext(ctx context.Context) *exportOutcome {
+	outcome, _ := ctx.Value(exportOutcomeKey{}).(*exportOutcome)
+	return outcome
+}
+
+// recordHTTP records the response to a request of an HTTP exporter. The
+// retryable status codes are those the OTLP specification lists.
+func (o *exportOutcome) recordHTTP(resp *http.Response, err error) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.responded = err == nil
+	if err != nil {
+		return
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		o.retryable = true
+	default:
+		o.retryable = false
+	}
+}
+
+// recordGRPC records the result of a request of a gRPC exporter. The
+// retryable status codes are those the OTLP specification lists.
+func (o *exportOutcome) recordGRPC(err error) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	s, ok := status.FromError(err)
+	o.responded = ok
+	if !ok {
+		return
+	}
+	switch s.Code() {
+	case grpccodes.Canceled, grpccodes.DeadlineExceeded, grpccodes.ResourceExhausted, grpccodes.Aborted,
+		grpccodes.OutOfRange, grpccodes.Unavailable, grpccodes.DataLoss:
+		o.retryable = true
+	default:
+		o.retryable = false
+	}
+}
+
+// rejected returns true if the export failed with an error because the
+// endpoint rejected the data. That is the case when the last response was not
+// retryable, including a successful response which rejected part of the data.
+func (o *exportOutcome) rejected(err error) bool {
+	if err == nil {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.responded && !o.retryable
+}
+
+// persistentSpanExporter is a span exporter which queues spans that fail to
+// export on disk, and exports them when the endpoint recovers.
+type persistentSpanExporter struct {
+	sdktrace.SpanExporter
+	spiller *spiller[sdktrace.ReadOnlySpan]
+}
+
+func newPersistentSpanExporter(
+	exporter sdktrace.SpanExporter,
+	queue *persistentQueue,
+	timeout time.Duration,
+) *persistentSpanExporter {
+	return &persistentSpanExporter{
+		SpanExporter: exporter,
+		spiller:      newSpiller(queue, exporter.ExportSpans, encodeSpans, decodeSpans, timeout, replayInitialInterval),
+	}
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (e *persistentSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	return e.spiller.exportBatch(ctx, spans)
+}
+
+// Shutdown implements sdktrace.SpanExporter.
+func (e *persistentSpanExporter) Shutdown(ctx context.Context) error {
+	e.spiller.shutdown()
+	return e.SpanExporter.Shutdown(ctx)
+}
+
+// persistentLogExporter is a log exporter which queues log records that fail
+// to export on disk, and exports them when the endpoint recovers.
+type persistentLogExporter struct {
+	sdklog.Exporter
+	spiller *spiller[sdklog.Record]
+}
+
+func newPersistentLogExporter(
+	exporter sdklog.Exporter,
+	queue *persistentQueue,
+	timeout time.Duration,
+) *persistentLogExporter {
+	return &persistentLogExporter{
+		Exporter: exporter,
+		spiller:  newSpiller(queue, exporter.Export, encodeLogs, decodeLogs, timeout, replayInitialInterval),
+	}
+}
+
+// Export implements sdklog.Exporter.
+func (e *persistentLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	return e.spiller.exportBatch(ctx, records)
+}
+
+// Shutdown implements sdklog.Exporter.
+func (e *persistentLogExporter) Shutdown(ctx context.Context) error {
+	e.spiller.shutdown()
+	return e.Exporter.Shutdown(ctx)
+}
+
+var _ sdktrace.SpanExporter = &persistentSpanExporter{}
+var _ sdklog.Exporter = &persistentLogExporter{}
@@ -0,0 +1,389 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/log/logtest"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	grpccodes "google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// testOTLPServer is a stand-in for an OTLP endpoint, which can be made to fail
+// requests.
+type testOTLPServer struct {
+	*httptest.Server
+	healthy  atomic.Bool
+	rejected atomic.Bool
+	requests atomic.Int64
+
+	mu    sync.Mutex
+	spans []string
+	logs  []string
+}
+
+func newTestOTLPServer(t *testing.T) *testOTLPServer {
+	s := &testOTLPServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/traces", func(w http.ResponseWriter, r *http.Request) {
+		var req coltracepb.ExportTraceServiceRequest
+		if !s.receive(w, r, &req) {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, rs := range req.GetResourceSpans() {
+			for _, ss := range rs.GetScopeSpans() {
+				for _, span := range ss.GetSpans() {
+					s.spans = append(s.spans, span.GetName())
+				}
+			}
+		}
+	})
+	mux.HandleFunc("/v1/logs", func(w http.ResponseWriter, r *http.Request) {
+		var req collogspb.ExportLogsServiceRequest
+		if !s.receive(w, r, &req) {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, rl := range req.GetResourceLogs() {
+			for _, sl := range rl.GetScopeLogs() {
+				for _, record := range sl.GetLogRecords() {
+					s.logs = append(s.logs, record.GetBody().GetStringValue())
+				}
+			}
+		}
+	})
+	mux.HandleFunc("/v1/metrics", func(w http.ResponseWriter, r *http.Request) {
+		s.receive(w, r, &colmetricspb.ExportMetricsServiceRequest{})
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testOTLPServer) receive(w http.ResponseWriter, r *http.Request, req proto.Message) bool {
+	s.requests.Add(1)
+	if s.rejected.Load() {
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+	if !s.healthy.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return false
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil || proto.Unmarshal(body, req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+	return true
+}
+
+func (s *testOTLPServer) received() ([]string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.spans), slices.Clone(s.logs)
+}
+
+func (s *testOTLPServer) endpoint() string {
+	return s.Listener.Addr().String()
+}
+
+func testPersistentSpanExporter(t *testing.T, server *testOTLPServer, dir string) *persistentSpanExporter {
+	t.Helper()
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpoint(server.endpoint()),
+		otlptracehttp.WithInsecure(),
+		otlptracehttp.WithRetry(otlptracehttp.RetryConfig{Enabled: false}),
+		otlptracehttp.WithHTTPClient(telemetryHTTPClient(&Config{}, &signalTelemetry{})),
+	)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	queue, err := newPersistentQueue(dir, 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	return &persistentSpanExporter{
+		SpanExporter: exporter,
+		spiller:      newSpiller(queue, exporter.ExportSpans, encodeSpans, decodeSpans, time.Second, 10*time.Millisecond),
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPersistentSpanExporter_QueuesAndReplays(t *testing.T) {
+	ctx := context.Background()
+	server := newTestOTLPServer(t)
+	exporter := testPersistentSpanExporter(t, server, t.TempDir())
+	defer func() { _ = exporter.Shutdown(ctx) }()
+
+	// The endpoint is down, so the first batch fails and is queued, and the
+	// second batch is queued without being sent.
+	for _, name := range []string{"first", "second"} {
+		if err := exporter.ExportSpans(ctx, []sdktrace.ReadOnlySpan{testSpanStub(name).Snapshot()}); err != nil {
+			t.Fatalf("expected the batch to be queued, got %v", err)
+		}
+	}
+	if exporter.spiller.queue.len() != 2 {
+		t.Fatalf("expected 2 queued batches, got %d", exporter.spiller.queue.len())
+	}
+
+	server.healthy.Store(true)
+	waitFor(t, func() bool { return exporter.spiller.queue.len() == 0 })
+
+	spans, _ := server.received()
+	if !slices.Equal(spans, []string{"first", "second"}) {
+		t.Errorf("expected the queued spans to be exported in order, got %v", spans)
+	}
+
+	// Once the queue is empty, batches are exported directly.
+	if err := exporter.ExportSpans(ctx, []sdktrace.ReadOnlySpan{testSpanStub("third").Snapshot()}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if spans, _ := server.received(); len(spans) != 3 || spans[2] != "third" {
+		t.Errorf("expected the span to be exported directly, got %v", spans)
+	}
+}
+
+func TestPersistentSpanExporter_ReplaysAfterRestart(t *testing.T) {
+	ctx := context.Background()
+	server := newTestOTLPServer(t)
+	dir := t.TempDir()
+
+	exporter := testPersistentSpanExporter(t, server, dir)
+	if err := exporter.ExportSpans(ctx, []sdktrace.ReadOnlySpan{testSpanStub("queued").Snapshot()}); err != nil {
+		t.Fatalf("expected the batch to be queued, got %v", err)
+	}
+	_ = exporter.Shutdown(ctx)
+
+	server.healthy.Store(true)
+	restarted := testPersistentSpanExporter(t, server, dir)
+	defer func() { _ = restarted.Shutdown(ctx) }()
+
+	waitFor(t, func() bool {
+		spans, _ := server.received()
+		return len(spans) == 1
+	})
+	if spans, _ := server.received(); spans[0] != "queued" {
+		t.Errorf("expected the queued span to be exported after a restart, got %v", spans)
+	}
+}
+
+func TestPersistentSpanExporter_DoesNotQueueRejectedBatches(t *testing.T) {
+	ctx := context.Background()
+	server := newTestOTLPServer(t)
+	server.rejected.Store(true)
+	exporter := testPersistentSpanExporter(t, server, t.TempDir())
+	defer func() { _ = exporter.Shutdown(ctx) }()
+
+	if err := exporter.ExportSpans(ctx, []sdktrace.ReadOnlySpan{testSpanStub("rejected").Snapshot()}); err == nil {
+		t.Error("expected the rejected batch to fail")
+	}
+	if exporter.spiller.queue.len() != 0 {
+		t.Errorf("expected the rejected batch not to be queued, got %d batches", exporter.spiller.queue.len())
+	}
+}
+
+func TestPersistentLogExporter_QueuesAndReplays(t *testing.T) {
+	ctx := context.Background()
+	server := newTestOTLPServer(t)
+	otlpExporter, err := otlploghttp.New(ctx,
+		otlploghttp.WithEndpoint(server.endpoint()),
+		otlploghttp.WithInsecure(),
+		otlploghttp.WithRetry(otlploghttp.RetryConfig{Enabled: false}),
+		otlploghttp.WithHTTPClient(telemetryHTTPClient(&Config{}, &signalTelemetry{})),
+	)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	queue, err := newPersistentQueue(t.TempDir(), 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	exporter := &persistentLogExporter{
+		Exporter: otlpExporter,
+		spiller:  newSpiller(queue, otlpExporter.Export, encodeLogs, decodeLogs, time.Second, 10*time.Millisecond),
+	}
+	defer func() { _ = exporter.Shutdown(ctx) }()
+
+	record := logtest.RecordFactory{Body: log.StringValue("queued"), Resource: testResource()}.NewRecord()
+	if err := exporter.Export(ctx, []sdklog.Record{record}); err != nil {
+		t.Fatalf("expected the batch to be queued, got %v", err)
+	}
+	if server.requests.Load() != 1 || queue.len() != 1 {
+		t.Fatalf("expected the failed batch to be queued")
+	}
+
+	server.healthy.Store(true)
+	waitFor(t, func() bool { return queue.len() == 0 })
+	if _, logs := server.received(); !slices.Equal(logs, []string{"queued"}) {
+		t.Errorf("expected the queued log to be exported, got %v", logs)
+	}
+}
+
+// statusExport returns an export function which responds to each batch with
+// the HTTP status code given for the name of its first span, and records the
+// names of the spans which are accepted.
+func statusExport(status func(name string) int) (func(context.Context, []sdktrace.ReadOnlySpan) error, func() []string) {
+	var mu sync.Mutex
+	var exported []string
+	export := func(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+		mu.Lock()
+		defer mu.Unlock()
+		code := status(spans[0].Name())
+		exportOutcomeFromContext(ctx).recordHTTP(&http.Response{StatusCode: code}, nil)
+		if code != http.StatusOK {
+			return fmt.Errorf("export failed with status %d", code)
+		}
+		exported = append(exported, spans[0].Name())
+		return nil
+	}
+	return export, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(exported)
+	}
+}
+
+func TestSpiller_RetriesBatchAfterTransientFailure(t *testing.T) {
+	queue, err := newPersistentQueue(t.TempDir(), 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	var attempts atomic.Int64
+	export, exported := statusExport(func(name string) int {
+		// The oldest batch fails once more while it is replayed, which must not
+		// cause it to be discarded when the next batch is accepted.
+		if name == "first" && attempts.Add(1) <= 2 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	s := newSpiller(queue, export, encodeSpans, decodeSpans, time.Second, 10*time.Millisecond)
+	defer s.shutdown()
+
+	for _, name := range []string{"first", "second"} {
+		_ = s.exportBatch(context.Background(), []sdktrace.ReadOnlySpan{testSpanStub(name).Snapshot()})
+	}
+
+	waitFor(t, func() bool { return queue.len() == 0 })
+	if got := exported(); !slices.Equal(got, []string{"first", "second"}) {
+		t.Errorf("expected both batches to be exported in order, got %v", got)
+	}
+}
+
+func TestSpiller_DiscardsRejectedBatch(t *testing.T) {
+	queue, err := newPersistentQueue(t.TempDir(), 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	var healthy atomic.Bool
+	export, exported := statusExport(func(name string) int {
+		switch {
+		case !healthy.Load():
+			return http.StatusServiceUnavailable
+		case name == "rejected":
+			return http.StatusBadRequest
+		default:
+			return http.StatusOK
+		}
+	})
+	s := newSpiller(queue, export, encodeSpans, decodeSpans, time.Second, 10*time.Millisecond)
+	defer s.shutdown()
+
+	for _, name := range []string{"rejected", "accepted"} {
+		_ = s.exportBatch(context.Background(), []sdktrace.ReadOnlySpan{testSpanStub(name).Snapshot()})
+	}
+	healthy.Store(true)
+
+	waitFor(t, func() bool { return queue.len() == 0 })
+	if got := exported(); !slices.Equal(got, []string{"accepted"}) {
+		t.Errorf("expected the rejected batch to be discarded, got %v", got)
+	}
+}
+
+func TestSpiller_DiscardsInvalidBatchWithoutCountingItAsExported(t *testing.T) {
+	queue, err := newPersistentQueue(t.TempDir(), 1<<20, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	var healthy atomic.Bool
+	export, exported := statusExport(func(string) int {
+		if !healthy.Load() {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusOK
+	})
+	s := newSpiller(queue, export, encodeSpans, decodeSpans, time.Second, 10*time.Millisecond)
+	defer s.shutdown()
+
+	_ = s.exportBatch(context.Background(), []sdktrace.ReadOnlySpan{testSpanStub("first").Snapshot()})
+	if err := queue.push([]byte("invalid")); err != nil {
+		t.Fatalf("failed to queue the invalid batch: %v", err)
+	}
+	_ = s.exportBatch(context.Background(), []sdktrace.ReadOnlySpan{testSpanStub("third").Snapshot()})
+	// Give the spiller time to retry while the endpoint is failing.
+	time.Sleep(50 * time.Millisecond)
+	if queue.len() != 3 {
+		t.Fatalf("expected the batches to stay queued while the endpoint fails, got %d", queue.len())
+	}
+
+	healthy.Store(true)
+	waitFor(t, func() bool { return queue.len() == 0 })
+	if got := exported(); !slices.Equal(got, []string{"first", "third"}) {
+		t.Errorf("expected the valid batches to be exported, got %v", got)
+	}
+}
+
+func TestExportOutcome_GRPC(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		rejected bool
+	}{
+		{err: grpcstatus.Error(grpccodes.Unavailable, "unavailable"), rejected: false},
+		{err: grpcstatus.Error(grpccodes.ResourceExhausted, "throttled"), rejected: false},
+		{err: grpcstatus.Error(grpccodes.InvalidArgument, "invalid"), rejected: true},
+		// A successful response with an error, such as a partial success.
+		{err: nil, rejected: true},
+		// The connection failed before there was a response.
+		{err: errors.New("connection reset"), rejected: false},
+	} {
+		_, outcome := withExportOutcome(context.Background())
+		outcome.recordGRPC(tc.err)
+		if rejected := outcome.rejected(errors.New("export failed")); rejected != tc.rejected {
+			t.Errorf("expected rejected to be %t for %v, got %t", tc.rejected, tc.err, rejected)
+		}
+	}
+}
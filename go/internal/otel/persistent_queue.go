@@ -0,0 +1,219 @@
+package otel
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logging"
+)
+
+// The extension of the files holding queued batches. Files being written have
+// an additional temporary extension, so that they are never read partially.
+const queuedBatchExtension = ".batch"
+
+// The directories of the open persistent queues. A second queue in the same
+// directory would export and remove the batches of the first, so it is refused.
+//
+//nolint:gochecknoglobals
+var openQueueDirs = struct {
+	sync.Mutex
+	dirs map[string]struct{}
+}{dirs: make(map[string]struct{})}
+
+// queuedBatch is a batch held in a file of the persistent queue.
+type queuedBatch struct {
+	name    string
+	size    int64
+	created time.Time
+}
+
+// persistentQueue holds batches which could not be exported in files in a
+// directory, oldest first, so that they can be exported later, including by a
+// later process using the same directory. The directory must not be used by
+// more than one process at a time, and a queue cannot be opened in a directory
+// which is in use by another queue of the same process until it is closed.
+//
+// The total size of the files is limited to maxBytes, and the oldest batches
+// are discarded to make room for new ones. Batches older than maxAge are
+// discarded.
+type persistentQueue struct {
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	batches []queuedBatch
+	size    int64
+	seq     uint64
+}
+
+// newPersistentQueue opens the queue in the directory, creating the directory
+// if it does not exist. Batches left in the directory by a previous process are
+// loaded. The queue must be closed to use the directory for another queue.
+func newPersistentQueue(dir string, maxBytes int64, maxAge time.Duration) (*persistentQueue, error) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	openQueueDirs.Lock()
+	defer openQueueDirs.Unlock()
+	if _, inUse := openQueueDirs.dirs[dir]; inUse {
+		return nil, fmt.Errorf("the persistent queue directory %s is already in use", dir)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	q := &persistentQueue{dir: dir, maxBytes: maxBytes, maxAge: maxAge, now: time.Now}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		if !strings.HasSuffix(name, queuedBatchExtension) {
+			// Left over from a process which stopped while writing a batch.
+			if strings.HasSuffix(name, ".tmp") {
+				_ = os.Remove(filepath.Join(dir, name))
+			}
+			continue
+		}
+		created, ok := parseQueuedBatchName(name)
+		info, err := entry.Info()
+		if !ok || err != nil {
+			continue
+		}
+		q.batches = append(q.batches, queuedBatch{name: name, size: info.Size(), created: created})
+		q.size += info.Size()
+	}
+	// The names sort in the order the batches were written.
+	slices.SortFunc(q.batches, func(a, b queuedBatch) int { return strings.Compare(a.name, b.name) })
+	q.mu.Lock()
+	q.discardLocked(0)
+	q.mu.Unlock()
+	openQueueDirs.dirs[dir] = struct{}{}
+	return q, nil
+}
+
+// close releases the directory of the queue, so that it can be opened again.
+// The batches remain in the directory.
+func (q *persistentQueue) close() {
+	openQueueDirs.Lock()
+	defer openQueueDirs.Unlock()
+	delete(openQueueDirs.dirs, q.dir)
+}
+
+// queuedBatchName returns a file name which sorts after the names of batches
+// written earlier.
+func queuedBatchName(created time.Time, seq uint64) string {
+	return fmt.Sprintf("%020d-%010d%s", created.UnixNano(), seq, queuedBatchExtension)
+}
+
+func parseQueuedBatchName(name string) (time.Time, bool) {
+	nanos, _, found := strings.Cut(strings.TrimSuffix(name, queuedBatchExtension), "-")
+	if !found {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, n), true
+}
+
+// len returns the number of queued batches.
+func (q *persistentQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.batches)
+}
+
+// push adds a batch to the end of the queue. Older batches are discarded when
+// the queue would otherwise exceed its size.
+func (q *persistentQueue) push(data []byte) error {
+	size := int64(len(data))
+	if size > q.maxBytes {
+		return fmt.Errorf("batch of %d bytes exceeds the persistent queue size of %d bytes", size, q.maxBytes)
+	}
+
+	q.mu.Lock()
+	created := q.now()
+	q.seq++
+	name := queuedBatchName(created, q.seq)
+	q.mu.Unlock()
+
+	// The file is written under a temporary name and renamed, so that a crash
+	// while writing it never leaves a partial batch in the queue.
+	path := filepath.Join(q.dir, name)
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		_ = os.Remove(path + ".tmp")
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.discardLocked(size)
+	q.batches = append(q.batches, queuedBatch{name: name, size: size, created: created})
+	q.size += size
+	return nil
+}
+
+// peek returns up to n of the oldest batches, after discarding expired ones.
+func (q *persistentQueue) peek(n int) []queuedBatch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.discardLocked(0)
+	return slices.Clone(q.batches[:min(n, len(q.batches))])
+}
+
+// read returns the contents of a queued batch.
+func (q *persistentQueue) read(batch queuedBatch) ([]byte, error) {
+	return os.ReadFile(filepath.Join(q.dir, batch.name))
+}
+
+// remove removes a batch from the queue.
+func (q *persistentQueue) remove(batch queuedBatch) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := slices.IndexFunc(q.batches, func(b queuedBatch) bool { return b.name == batch.name })
+	if i < 0 {
+		return
+	}
+	q.removeLocked(i)
+}
+
+// discardLocked discards expired batches, and the oldest batches until there
+// is room for a batch of the given size.
+func (q *persistentQueue) discardLocked(size int64) {
+	cutoff := q.now().Add(-q.maxAge)
+	discarded := 0
+	for len(q.batches) > 0 && (q.batches[0].created.Before(cutoff) || q.size+size > q.maxBytes) {
+		q.removeLocked(0)
+		discarded++
+	}
+	if discarded > 0 {
+		logging.GetLogger().Errorf("discarded %d batches from the persistent queue in %s", discarded, q.dir)
+	}
+}
+
+func (q *persistentQueue) removeLocked(i int) {
+	batch := q.batches[i]
+	if err := os.Remove(filepath.Join(q.dir, batch.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logging.GetLogger().Errorf("failed to remove %s from the persistent queue: %v", batch.name, err)
+	}
+	q.batches = slices.Delete(q.batches, i, i+1)
+	q.size -= batch.size
+}
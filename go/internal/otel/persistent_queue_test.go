@@ -0,0 +1,119 @@
+package otel
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func queuedData(q *persistentQueue, t *testing.T) []string {
+	t.Helper()
+	var data []string
+	for _, batch := range q.peek(100) {
+		content, err := q.read(batch)
+		if err != nil {
+			t.Fatalf("failed to read batch: %v", err)
+		}
+		data = append(data, string(content))
+	}
+	return data
+}
+
+func TestPersistentQueue_PushPeekRemove(t *testing.T) {
+	q, err := newPersistentQueue(t.TempDir(), 1000, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+
+	for _, data := range []string{"first", "second", "third"} {
+		if err := q.push([]byte(data)); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+	if q.len() != 3 {
+		t.Fatalf("expected 3 batches, got %d", q.len())
+	}
+
+	batches := q.peek(2)
+	if len(batches) != 2 {
+		t.Fatalf("expected to peek 2 batches, got %d", len(batches))
+	}
+	q.remove(batches[0])
+	if data := queuedData(q, t); len(data) != 2 || data[0] != "second" || data[1] != "third" {
+		t.Errorf("expected the oldest batch to be removed, got %v", data)
+	}
+}
+
+func TestPersistentQueue_SizeLimit(t *testing.T) {
+	q, err := newPersistentQueue(t.TempDir(), 10, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+
+	for _, data := range []string{"aaaa", "bbbb", "cccc"} {
+		if err := q.push([]byte(data)); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+	if data := queuedData(q, t); len(data) != 2 || data[0] != "bbbb" || data[1] != "cccc" {
+		t.Errorf("expected the oldest batch to be discarded, got %v", data)
+	}
+
+	if err := q.push([]byte("this batch is too large")); err == nil {
+		t.Error("expected a batch larger than the queue to be rejected")
+	}
+	if q.len() != 2 {
+		t.Errorf("expected a rejected batch not to discard batches, got %d batches", q.len())
+	}
+}
+
+func TestPersistentQueue_AgeLimit(t *testing.T) {
+	now := time.Unix(1000, 0)
+	q, err := newPersistentQueue(t.TempDir(), 1000, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	q.now = func() time.Time { return now }
+
+	_ = q.push([]byte("old"))
+	now = now.Add(30 * time.Second)
+	_ = q.push([]byte("new"))
+	now = now.Add(45 * time.Second)
+
+	if data := queuedData(q, t); len(data) != 1 || data[0] != "new" {
+		t.Errorf("expected the expired batch to be discarded, got %v", data)
+	}
+}
+
+func TestPersistentQueue_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	q, err := newPersistentQueue(dir, 1000, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open queue: %v", err)
+	}
+	for _, data := range []string{"first", "second"} {
+		_ = q.push([]byte(data))
+	}
+	// A file left by a process which stopped while writing a batch.
+	partial := filepath.Join(dir, queuedBatchName(time.Now(), 99)+".tmp")
+	if err := os.WriteFile(partial, []byte("partial"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if _, err := newPersistentQueue(dir, 1000, time.Hour); err == nil {
+		t.Fatal("expected a directory in use by an open queue to be refused")
+	}
+	q.close()
+	reopened, err := newPersistentQueue(dir, 1000, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to reopen queue: %v", err)
+	}
+	defer reopened.close()
+	if data := queuedData(reopened, t); len(data) != 2 || data[0] != "first" || data[1] != "second" {
+		t.Errorf("expected the batches to be loaded in order, got %v", data)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Errorf("expected the partially written batch to be removed")
+	}
+}
@@ -0,0 +1,473 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/defaults"
+	"github.com/launchdarkly/observability-sdk/go/internal/metadata"
+)
+
+// The names of the metrics which report the health of the export pipelines.
+const (
+	telemetryExportedMetric       = "launchdarkly.sdk.exported"
+	telemetryDroppedMetric        = "launchdarkly.sdk.dropped"
+	telemetryExportFailuresMetric = "launchdarkly.sdk.export.failures"
+	telemetryExportDurationMetric = "launchdarkly.sdk.export.duration"
+	telemetrySamplingFetchMetric  = "launchdarkly.sdk.sampling_config.fetches"
+	telemetryTraceBufferMetric    = "launchdarkly.sdk.trace_buffer.incomplete_traces"
+)
+
+// The reasons data is dropped, used as the reason attribute of the dropped
+// metric.
+const (
+	dropReasonQueueFull    = "queue_full"
+	dropReasonSampledOut   = "sampled_out"
+	dropReasonPrunedChild  = "pruned_child"
+	dropReasonBufferExport = "buffer_export_failed"
+	transportErrorStatus   = "transport_error"
+	telemetrySignalSpans   = "spans"
+	telemetrySignalLogs    = "logs"
+	telemetrySignalMetrics = "metrics"
+)
+
+// signalTelemetry counts what happens to the data of a signal in the
+// LaunchDarkly export pipeline.
+type signalTelemetry struct {
+	name string
+
+	exported         atomic.Int64
+	droppedQueueFull atomic.Int64
+	sampledOut       atomic.Int64
+	prunedChildren   atomic.Int64
+	bufferDropped    atomic.Int64
+	bufferEvicted    atomic.Int64
+	bufferTimedOut   atomic.Int64
+	exports          atomic.Int64
+	exportNanos      atomic.Int64
+
+	failuresMu sync.Mutex
+	failures   map[string]int64
+}
+
+func (s *signalTelemetry) recordFailure(statusCode string) {
+	s.failuresMu.Lock()
+	defer s.failuresMu.Unlock()
+	if s.failures == nil {
+		s.failures = make(map[string]int64)
+	}
+	s.failures[statusCode]++
+}
+
+// recordExport records an export to the endpoint, which took the given time.
+func (s *signalTelemetry) recordExport(ctx context.Context, items int, elapsed time.Duration, err error) {
+	s.exports.Add(1)
+	s.exportNanos.Add(int64(elapsed))
+	if err == nil {
+		s.exported.Add(int64(items))
+	}
+	if h := exportDuration.Load(); h != nil {
+		(*h).Record(ctx, elapsed.Seconds(), metric.WithAttributes(
+			append(telemetryAttributes(), attribute.String("signal", s.name))...))
+	}
+}
+
+// SignalStats are the counts for one signal since the process started.
+type SignalStats struct {
+	// The number of items accepted by the endpoint.
+	Exported int64
+	// The number of items dropped because the export queue was full.
+	DroppedQueueFull int64
+	// The number of items dropped by the sampling configuration.
+	SampledOut int64
+	// The number of spans dropped because their parent was sampled out.
+	PrunedChildren int64
+	// The number of spans held by the trace buffer which could not be
+	// exported.
+	DroppedFromBuffer int64
+	// The number of traces the trace buffer exported before they were
+	// complete, because the buffer was full or because their root span did not
+	// end within the timeout.
+	BufferEvictedTraces  int64
+	BufferTimedOutTraces int64
+	// The number of export requests, and the total time they took, including
+	// retries.
+	Exports    int64
+	ExportTime time.Duration
+	// The number of failed requests to the endpoint by status code. HTTP
+	// status codes are numbers, gRPC status codes are names, and requests
+	// without a response are counted as transport_error.
+	FailuresByStatus map[string]int64
+}
+
+// Stats are the counts of the telemetry of the export pipelines.
+type Stats struct {
+	Spans                       SignalStats
+	Logs                        SignalStats
+	Metrics                     SignalStats
+	SamplingConfigFetches       int64
+	SamplingConfigFetchFailures int64
+}
+
+func (s *signalTelemetry) stats() SignalStats {
+	s.failuresMu.Lock()
+	failures := maps.Clone(s.failures)
+	s.failuresMu.Unlock()
+	if failures == nil {
+		failures = map[string]int64{}
+	}
+	return SignalStats{
+		Exported:             s.exported.Load(),
+		DroppedQueueFull:     s.droppedQueueFull.Load(),
+		SampledOut:           s.sampledOut.Load(),
+		PrunedChildren:       s.prunedChildren.Load(),
+		DroppedFromBuffer:    s.bufferDropped.Load(),
+		BufferEvictedTraces:  s.bufferEvicted.Load(),
+		BufferTimedOutTraces: s.bufferTimedOut.Load(),
+		Exports:              s.exports.Load(),
+		ExportTime:           time.Duration(s.exportNanos.Load()),
+		FailuresByStatus:     failures,
+	}
+}
+
+// The telemetry is counted for the lifetime of the process, including across
+// restarts.
+//
+//nolint:gochecknoglobals
+var (
+	spanTelemetry   = &signalTelemetry{name: telemetrySignalSpans}
+	logTelemetry    = &signalTelemetry{name: telemetrySignalLogs}
+	metricTelemetry = &signalTelemetry{name: telemetrySignalMetrics}
+
+	samplingConfigFetches       atomic.Int64
+	samplingConfigFetchFailures atomic.Int64
+)
+
+// The histogram the export durations are recorded with, which is set while
+// OTLP is started with telemetry enabled.
+//
+//nolint:gochecknoglobals
+var exportDuration atomic.Pointer[metric.Float64Histogram]
+
+// GetStats returns the telemetry of the export pipelines.
+func GetStats() Stats {
+	return Stats{
+		Spans:                       spanTelemetry.stats(),
+		Logs:                        logTelemetry.stats(),
+		Metrics:                     metricTelemetry.stats(),
+		SamplingConfigFetches:       samplingConfigFetches.Load(),
+		SamplingConfigFetchFailures: samplingConfigFetchFailures.Load(),
+	}
+}
+
+// RecordSamplingConfigFetch records the result of fetching the sampling
+// configuration.
+func RecordSamplingConfigFetch(err error) {
+	samplingConfigFetches.Add(1)
+	if err != nil {
+		samplingConfigFetchFailures.Add(1)
+	}
+}
+
+func telemetryAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.TelemetryDistroNameKey.String(metadata.InstrumentationName),
+		semconv.TelemetryDistroVersionKey.String(metadata.InstrumentationVersion),
+	}
+}
+
+// registerTelemetry reports the telemetry as metrics of the meter. The returned
+// function stops reporting.
+func registerTelemetry(meter metric.Meter) (func(), error) {
+	exported, err := meter.Int64ObservableCounter(telemetryExportedMetric,
+		metric.WithDescription("The number of items accepted by the LaunchDarkly endpoint."))
+	if err != nil {
+		return nil, err
+	}
+	dropped, err := meter.Int64ObservableCounter(telemetryDroppedMetric,
+		metric.WithDescription("The number of items which were not exported to LaunchDarkly."))
+	if err != nil {
+		return nil, err
+	}
+	failures, err := meter.Int64ObservableCounter(telemetryExportFailuresMetric,
+		metric.WithDescription("The number of failed requests to the LaunchDarkly endpoint."))
+	if err != nil {
+		return nil, err
+	}
+	fetches, err := meter.Int64ObservableCounter(telemetrySamplingFetchMetric,
+		metric.WithDescription("The number of times the sampling configuration was fetched."))
+	if err != nil {
+		return nil, err
+	}
+	incompleteTraces, err := meter.Int64ObservableCounter(telemetryTraceBufferMetric,
+		metric.WithDescription("The number of traces the trace buffer exported before they were complete."))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram(telemetryExportDurationMetric,
+		metric.WithUnit("s"),
+		metric.WithDescription("The duration of exports to the LaunchDarkly endpoint, including retries."))
+	if err != nil {
+		return nil, err
+	}
+
+	registration, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		distro := telemetryAttributes()
+		with := func(attrs ...attribute.KeyValue) metric.ObserveOption {
+			return metric.WithAttributes(append(attrs, distro...)...)
+		}
+		for _, s := range []*signalTelemetry{spanTelemetry, logTelemetry, metricTelemetry} {
+			signal := attribute.String("signal", s.name)
+			stats := s.stats()
+			if s != metricTelemetry {
+				o.ObserveInt64(exported, stats.Exported, with(signal))
+				o.ObserveInt64(dropped, stats.DroppedQueueFull,
+					with(signal, attribute.String("reason", dropReasonQueueFull)))
+				o.ObserveInt64(dropped, stats.SampledOut,
+					with(signal, attribute.String("reason", dropReasonSampledOut)))
+			}
+			if s == spanTelemetry {
+				o.ObserveInt64(dropped, stats.PrunedChildren,
+					with(signal, attribute.String("reason", dropReasonPrunedChild)))
+				o.ObserveInt64(dropped, stats.DroppedFromBuffer,
+					with(signal, attribute.String("reason", dropReasonBufferExport)))
+				o.ObserveInt64(incompleteTraces, stats.BufferEvictedTraces, with(attribute.String("reason", "evicted")))
+				o.ObserveInt64(incompleteTraces, stats.BufferTimedOutTraces, with(attribute.String("reason", "timed_out")))
+			}
+			for statusCode, count := range stats.FailuresByStatus {
+				o.ObserveInt64(failures, count, with(signal, attribute.String("status_code", statusCode)))
+			}
+		}
+		total := samplingConfigFetches.Load()
+		failed := samplingConfigFetchFailures.Load()
+		o.ObserveInt64(fetches, total-failed, with(attribute.String("result", "success")))
+		o.ObserveInt64(fetches, failed, with(attribute.String("result", "failure")))
+		return nil
+	}, exported, dropped, failures, fetches, incompleteTraces)
+	if err != nil {
+		return nil, err
+	}
+	exportDuration.Store(&duration)
+	return func() {
+		exportDuration.CompareAndSwap(&duration, nil)
+		_ = registration.Unregister()
+	}, nil
+}
+
+// telemetrySpanExporter counts the spans exported to the endpoint.
+type telemetrySpanExporter struct {
+	sdktrace.SpanExporter
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (e telemetrySpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	started := time.Now()
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	spanTelemetry.recordExport(ctx, len(spans), time.Since(started), err)
+	return err
+}
+
+// telemetryLogExporter counts the log records exported to the endpoint.
+type telemetryLogExporter struct {
+	sdklog.Exporter
+}
+
+// Export implements sdklog.Exporter.
+func (e telemetryLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	started := time.Now()
+	err := e.Exporter.Export(ctx, records)
+	logTelemetry.recordExport(ctx, len(records), time.Since(started), err)
+	return err
+}
+
+// telemetryMetricExporter counts the metric exports to the endpoint. The data
+// points are not counted.
+type telemetryMetricExporter struct {
+	sdkmetric.Exporter
+}
+
+// Export implements sdkmetric.Exporter.
+func (e telemetryMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	started := time.Now()
+	err := e.Exporter.Export(ctx, rm)
+	metricTelemetry.recordExport(ctx, 0, time.Since(started), err)
+	return err
+}
+
+// queueLimit keeps a batch processor from dropping data when its queue is
+// full, so that the data which is dropped can be counted. Data is counted as
+// pending from when it is passed to the processor until the processor passes
+// it to the exporter, and is dropped when maxQueueSize items are pending. As
+// the processor's queue never holds more than the pending items, the processor
+// never drops data itself.
+type queueLimit struct {
+	pending      atomic.Int64
+	maxQueueSize int64
+	telemetry    *signalTelemetry
+}
+
+// admit returns true if the item can be passed to the processor.
+func (q *queueLimit) admit() bool {
+	if q.pending.Add(1) > q.maxQueueSize {
+		q.pending.Add(-1)
+		q.telemetry.droppedQueueFull.Add(1)
+		return false
+	}
+	return true
+}
+
+// release records that the processor passed items to the exporter.
+func (q *queueLimit) release(items int) {
+	q.pending.Add(-int64(items))
+}
+
+// queueLimitSpanProcessor applies a queueLimit to a batch span processor.
+type queueLimitSpanProcessor struct {
+	sdktrace.SpanProcessor
+	limit *queueLimit
+}
+
+// OnEnd implements sdktrace.SpanProcessor.
+func (p queueLimitSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	// The batch processor ignores spans which are not sampled.
+	if !s.SpanContext().IsSampled() || !p.limit.admit() {
+		return
+	}
+	p.SpanProcessor.OnEnd(s)
+}
+
+// queueReleasingSpanExporter releases spans from a queueLimit when the batch
+// processor exports them.
+type queueReleasingSpanExporter struct {
+	sdktrace.SpanExporter
+	limit *queueLimit
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (e queueReleasingSpanExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.limit.release(len(spans))
+	return e.SpanExporter.ExportSpans(ctx, spans)
+}
+
+// queueLimitLogProcessor applies a queueLimit to a batch log processor.
+type queueLimitLogProcessor struct {
+	sdklog.Processor
+	limit *queueLimit
+}
+
+// OnEmit implements sdklog.Processor.
+func (p queueLimitLogProcessor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	if !p.limit.admit() {
+		return nil
+	}
+	return p.Processor.OnEmit(ctx, record)
+}
+
+// queueReleasingLogExporter releases log records from a queueLimit when the
+// batch processor exports them.
+type queueReleasingLogExporter struct {
+	sdklog.Exporter
+	limit *queueLimit
+}
+
+// Export implements sdklog.Exporter.
+func (e queueReleasingLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	e.limit.release(len(records))
+	return e.Exporter.Export(ctx, records)
+}
+
+// telemetryRoundTripper counts the failed requests of the HTTP exporters by
+// status code, and records the response in the outcome of the export.
+type telemetryRoundTripper struct {
+	next      http.RoundTripper
+	telemetry *signalTelemetry
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t telemetryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	exportOutcomeFromContext(req.Context()).recordHTTP(resp, err)
+	switch {
+	case err != nil:
+		t.telemetry.recordFailure(transportErrorStatus)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		t.telemetry.recordFailure(strconv.Itoa(resp.StatusCode))
+	}
+	return resp, err
+}
+
+// telemetryHTTPClient returns a client for an HTTP exporter which counts failed
+// requests. The configured client is copied, and otherwise a client equivalent
+// to the one the exporter would create is used.
+func telemetryHTTPClient(config *Config, telemetry *signalTelemetry) *http.Client {
+	var client http.Client
+	if config.OtlpHTTPClient != nil {
+		client = *config.OtlpHTTPClient
+	} else {
+		transport := &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+			TLSClientConfig:       config.OtlpTLSConfig,
+		}
+		if config.OtlpProxy != nil {
+			transport.Proxy = config.OtlpProxy
+		}
+		client.Transport = transport
+		client.Timeout = defaults.DurationOrDefault(config.OtlpTimeout, defaultOTLPTimeout)
+	}
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = telemetryRoundTripper{next: next, telemetry: telemetry}
+	return &client
+}
+
+// telemetryInterceptor counts the failed requests of a gRPC exporter by status
+// code, and records the result in the outcome of the export.
+func telemetryInterceptor(telemetry *signalTelemetry) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		exportOutcomeFromContext(ctx).recordGRPC(err)
+		if err != nil {
+			if s, ok := status.FromError(err); ok {
+				telemetry.recordFailure(s.Code().String())
+			} else if !errors.Is(err, context.Canceled) {
+				telemetry.recordFailure(transportErrorStatus)
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,170 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+// blockingExporter blocks exports until it is released.
+type blockingExporter struct {
+	syncTestExporter
+	release chan struct{}
+}
+
+func (e *blockingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	<-e.release
+	return e.syncTestExporter.ExportSpans(ctx, spans)
+}
+
+func TestQueueLimitSpanProcessor_CountsDroppedSpans(t *testing.T) {
+	telemetry := &signalTelemetry{name: telemetrySignalSpans}
+	limit := &queueLimit{maxQueueSize: 2, telemetry: telemetry}
+	exporter := &blockingExporter{release: make(chan struct{})}
+	processor := queueLimitSpanProcessor{
+		SpanProcessor: sdktrace.NewBatchSpanProcessor(
+			queueReleasingSpanExporter{SpanExporter: exporter, limit: limit},
+			sdktrace.WithMaxQueueSize(2),
+			sdktrace.WithMaxExportBatchSize(2),
+		),
+		limit: limit,
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+
+	for _, name := range []string{"first", "second", "third", "fourth"} {
+		_, span := provider.Tracer("test").Start(context.Background(), name)
+		span.End()
+	}
+	close(exporter.release)
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	exported := exporter.names()
+	if stats := telemetry.stats(); stats.DroppedQueueFull != int64(4-len(exported)) || len(exported) < 2 {
+		t.Errorf("expected the spans which were not exported to be counted, got %d dropped and %v exported",
+			stats.DroppedQueueFull, exported)
+	}
+}
+
+func TestTraceExporter_CountsSampledOutSpans(t *testing.T) {
+	sampler := NewCustomSampler(neverSampler)
+	sampler.SetConfig(parentSampledOutConfig())
+	telemetry := &signalTelemetry{name: telemetrySignalSpans}
+	exporter := &syncTestExporter{}
+	traceExporter := newTraceExporter(exporter, sampler)
+	traceExporter.telemetry = telemetry
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExporter))
+
+	tracer := provider.Tracer("test")
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	parent.End()
+	_, other := tracer.Start(context.Background(), "other")
+	other.End()
+	_ = provider.Shutdown(context.Background())
+
+	stats := telemetry.stats()
+	if stats.SampledOut != 1 || stats.PrunedChildren != 1 || !slices.Equal(exporter.names(), []string{"other"}) {
+		t.Errorf("expected 1 sampled out and 1 pruned span, got %+v", stats)
+	}
+}
+
+func TestTelemetryHTTPClient_CountsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	telemetry := &signalTelemetry{name: telemetrySignalSpans}
+	client := telemetryHTTPClient(&Config{}, telemetry)
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	server.Close()
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("expected the request to a closed server to fail")
+	}
+
+	failures := telemetry.stats().FailuresByStatus
+	if failures["503"] != 1 || failures[transportErrorStatus] != 1 {
+		t.Errorf("expected the failures to be counted by status code, got %v", failures)
+	}
+}
+
+func TestRegisterTelemetry(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	stop, err := registerTelemetry(provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("registerTelemetry failed: %v", err)
+	}
+	RecordSamplingConfigFetch(nil)
+	spanTelemetry.recordExport(context.Background(), 3, 0, nil)
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("failed to collect metrics: %v", err)
+	}
+	metrics := map[string]metricdata.Aggregation{}
+	for _, m := range data.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m.Data
+	}
+	for _, name := range []string{telemetryExportedMetric, telemetryDroppedMetric, telemetrySamplingFetchMetric,
+		telemetryExportDurationMetric, telemetryTraceBufferMetric} {
+		if metrics[name] == nil {
+			t.Errorf("expected the %s metric to be reported", name)
+		}
+	}
+	exported := metrics[telemetryExportedMetric].(metricdata.Sum[int64])
+	for _, point := range exported.DataPoints {
+		if value, ok := point.Attributes.Value(semconv.TelemetryDistroNameKey); !ok || value.AsString() == "" {
+			t.Errorf("expected the data points to have the distro attributes, got %v", point.Attributes)
+		}
+		if signal, _ := point.Attributes.Value(attribute.Key("signal")); signal.AsString() == telemetrySignalSpans &&
+			point.Value < 3 {
+			t.Errorf("expected the exported spans to be reported, got %d", point.Value)
+		}
+	}
+
+	stop()
+	if exportDuration.Load() != nil {
+		t.Error("expected the export duration not to be recorded after stopping")
+	}
+	_ = provider.Shutdown(context.Background())
+}
+
+func TestCreateSpanProcessor_DefaultQueueSize(t *testing.T) {
+	ctx := context.Background()
+	server := newTestOTLPServer(t)
+	server.healthy.Store(true)
+	processor, err := createSpanProcessor(ctx, &Config{
+		OtlpEndpoint:    "http://" + server.endpoint(),
+		OtlpCompression: CompressionNone,
+	}, NewCustomSampler(nil))
+	if err != nil {
+		t.Fatalf("failed to create the span processor: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+	defer func() { _ = provider.Shutdown(ctx) }()
+
+	_, span := provider.Tracer("test").Start(ctx, "queued")
+	span.End()
+	if err := provider.ForceFlush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spans, _ := server.received(); !slices.Equal(spans, []string{"queued"}) {
+		t.Errorf("expected the span to be queued when the queue size is not configured, got %v", spans)
+	}
+}
@@ -0,0 +1,250 @@
+package otel
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logging"
+)
+
+type bufferedTrace struct {
+	spans     []sdktrace.ReadOnlySpan
+	firstSeen time.Time
+	element   *list.Element
+}
+
+// traceBuffer is a span exporter which holds spans until the trace they belong
+// to is complete, and then exports all of the spans of the trace together.
+// This allows the sampling decisions made by the exporter it wraps to apply to
+// whole traces, even when the spans of a trace end up in different batches.
+//
+// A trace is considered complete when its local root span ends. That is a span
+// with no parent, or with a parent from another process. Traces which are not
+// complete within the timeout, or which are the oldest in the buffer when it
+// is full, are exported as they are.
+//
+// Spans are exported on behalf of the span processor, when the buffer is
+// flushed and when traces expire. These exports are made one at a time, as
+// the wrapped exporter expects, in batches no larger than the batch size.
+type traceBuffer struct {
+	next     sdktrace.SpanExporter
+	timeout  time.Duration
+	maxSpans int
+	// The timeout for exports which are not made on behalf of a span
+	// processor.
+	exportTimeout time.Duration
+	maxBatchSize  int
+	now           func() time.Time
+	// When set, the traces which are exported before they are complete, and
+	// the spans which cannot be exported, are counted.
+	telemetry *signalTelemetry
+
+	mu        sync.Mutex
+	traces    map[trace.TraceID]*bufferedTrace
+	order     *list.List
+	spanCount int
+
+	// Held while exporting, so that exports are not made concurrently.
+	exportMu sync.Mutex
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newTraceBuffer(
+	next sdktrace.SpanExporter,
+	timeout time.Duration,
+	maxSpans int,
+	exportTimeout time.Duration,
+	maxBatchSize int,
+) *traceBuffer {
+	b := &traceBuffer{
+		next:          next,
+		timeout:       timeout,
+		maxSpans:      maxSpans,
+		exportTimeout: exportTimeout,
+		maxBatchSize:  maxBatchSize,
+		now:           time.Now,
+		traces:        make(map[trace.TraceID]*bufferedTrace),
+		order:         list.New(),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go b.expireLoop()
+	return b
+}
+
+func isLocalRoot(s sdktrace.ReadOnlySpan) bool {
+	parent := s.Parent()
+	return !parent.IsValid() || parent.IsRemote()
+}
+
+// ExportSpans implements trace.SpanExporter.
+func (b *traceBuffer) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	b.mu.Lock()
+	var ready []sdktrace.ReadOnlySpan
+	evicted := 0
+	for _, s := range spans {
+		traceID := s.SpanContext().TraceID()
+		t := b.traces[traceID]
+		if t == nil {
+			t = &bufferedTrace{firstSeen: b.now()}
+			t.element = b.order.PushBack(traceID)
+			b.traces[traceID] = t
+		}
+		t.spans = append(t.spans, s)
+		b.spanCount++
+		if isLocalRoot(s) {
+			ready = append(ready, b.remove(traceID)...)
+		}
+	}
+	// Make room by exporting the oldest traces early.
+	for b.spanCount > b.maxSpans && b.order.Len() > 0 {
+		ready = append(ready, b.remove(b.order.Front().Value.(trace.TraceID))...)
+		evicted++
+	}
+	b.mu.Unlock()
+
+	if b.telemetry != nil {
+		b.telemetry.bufferEvicted.Add(int64(evicted))
+	}
+	return b.export(ctx, ready)
+}
+
+// remove removes a trace from the buffer and returns its spans.
+// Must be called with the lock held.
+func (b *traceBuffer) remove(traceID trace.TraceID) []sdktrace.ReadOnlySpan {
+	t := b.traces[traceID]
+	if t == nil {
+		return nil
+	}
+	delete(b.traces, traceID)
+	b.order.Remove(t.element)
+	b.spanCount -= len(t.spans)
+	return t.spans
+}
+
+// expired removes the traces which have been in the buffer longer than the
+// timeout and returns their spans.
+func (b *traceBuffer) expired() ([]sdktrace.ReadOnlySpan, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var ready []sdktrace.ReadOnlySpan
+	count := 0
+	cutoff := b.now().Add(-b.timeout)
+	// Traces are ordered by when they were first seen, so the expired
+	// traces are at the front.
+	for b.order.Len() > 0 {
+		traceID := b.order.Front().Value.(trace.TraceID)
+		if b.traces[traceID].firstSeen.After(cutoff) {
+			break
+		}
+		ready = append(ready, b.remove(traceID)...)
+		count++
+	}
+	return ready, count
+}
+
+// drain removes all traces from the buffer and returns their spans.
+func (b *traceBuffer) drain() []sdktrace.ReadOnlySpan {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ready := make([]sdktrace.ReadOnlySpan, 0, b.spanCount)
+	for b.order.Len() > 0 {
+		ready = append(ready, b.remove(b.order.Front().Value.(trace.TraceID))...)
+	}
+	return ready
+}
+
+func (b *traceBuffer) flushExpired() {
+	ready, count := b.expired()
+	if count == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.exportTimeout)
+	defer cancel()
+	if b.telemetry != nil {
+		b.telemetry.bufferTimedOut.Add(int64(count))
+	}
+	if err := b.export(ctx, ready); err != nil {
+		logging.GetLogger().Errorf("failed to export buffered spans: %v", err)
+	}
+}
+
+func (b *traceBuffer) expireLoop() {
+	defer close(b.done)
+	// Checking at a fraction of the timeout bounds how long past the timeout
+	// a trace can remain in the buffer.
+	ticker := time.NewTicker(max(b.timeout/4, 10*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			b.flushExpired()
+		}
+	}
+}
+
+// export exports the spans in batches. A batch which fails does not prevent
+// the remaining batches from being exported.
+func (b *traceBuffer) export(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	var errs []error
+	for len(spans) > 0 {
+		batch := spans[:min(len(spans), b.maxBatchSize)]
+		spans = spans[len(batch):]
+		b.exportMu.Lock()
+		err := b.next.ExportSpans(ctx, batch)
+		b.exportMu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			if b.telemetry != nil {
+				b.telemetry.bufferDropped.Add(int64(len(batch)))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flush exports all buffered spans, including those of traces which are not
+// complete.
+func (b *traceBuffer) flush(ctx context.Context) error {
+	return b.export(ctx, b.drain())
+}
+
+// Shutdown exports all buffered spans and shuts down the wrapped exporter.
+func (b *traceBuffer) Shutdown(ctx context.Context) error {
+	b.stopOnce.Do(func() {
+		close(b.stop)
+	})
+	<-b.done
+	return errors.Join(b.flush(ctx), b.next.Shutdown(ctx))
+}
+
+// traceBufferSpanProcessor exports the spans held by the trace buffer of its
+// exporter when it is flushed, after the spans the processor holds have been
+// given to the buffer.
+type traceBufferSpanProcessor struct {
+	sdktrace.SpanProcessor
+	buffer *traceBuffer
+}
+
+func (p traceBufferSpanProcessor) ForceFlush(ctx context.Context) error {
+	if err := p.SpanProcessor.ForceFlush(ctx); err != nil {
+		return err
+	}
+	return p.buffer.flush(ctx)
+}
+
+var (
+	_ sdktrace.SpanExporter  = &traceBuffer{}
+	_ sdktrace.SpanProcessor = traceBufferSpanProcessor{}
+)
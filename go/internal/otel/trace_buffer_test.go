@@ -0,0 +1,349 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+)
+
+// syncTestExporter is a testExporter which is safe to use from the goroutine
+// that exports expired traces.
+type syncTestExporter struct {
+	mu       sync.Mutex
+	exported []trace.ReadOnlySpan
+	shutdown bool
+}
+
+func (e *syncTestExporter) ExportSpans(_ context.Context, spans []trace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.exported = append(e.exported, spans...)
+	return nil
+}
+
+func (e *syncTestExporter) Shutdown(_ context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdown = true
+	return nil
+}
+
+func (e *syncTestExporter) names() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	names := make([]string, 0, len(e.exported))
+	for _, s := range e.exported {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+var _ trace.SpanExporter = &syncTestExporter{}
+
+// failingExporter fails every export.
+type failingExporter struct{}
+
+func (failingExporter) ExportSpans(context.Context, []trace.ReadOnlySpan) error {
+	return errors.New("unavailable")
+}
+
+func (failingExporter) Shutdown(context.Context) error { return nil }
+
+func parentSampledOutConfig() *gql.GetSamplingConfigSamplingSamplingConfig {
+	return &gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{
+			{
+				Name: gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfigNameMatchConfig{
+					MatchParts: gql.MatchParts{MatchValue: "parent"},
+				},
+				SamplingRatio: 10,
+			},
+		},
+	}
+}
+
+func TestTraceBuffer_AppliesSamplingAcrossBatches(t *testing.T) {
+	customSampler := NewCustomSampler(neverSampler)
+	customSampler.SetConfig(parentSampledOutConfig())
+	exporter := &syncTestExporter{}
+	buffer := newTraceBuffer(newTraceExporter(exporter, customSampler), time.Hour, 100, time.Second, 512)
+	// The simple span processor exports each span in its own batch.
+	provider := trace.NewTracerProvider(trace.WithSpanProcessor(trace.NewSimpleSpanProcessor(buffer)))
+	tracer := provider.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	if names := exporter.names(); len(names) != 0 {
+		t.Errorf("expected the child to be buffered until the parent ends, got %v", names)
+	}
+	parent.End()
+
+	_, unrelated := tracer.Start(context.Background(), "unrelated")
+	unrelated.End()
+
+	names := exporter.names()
+	if len(names) != 1 || names[0] != "unrelated" {
+		t.Errorf("expected only the unrelated span to be exported, got %v", names)
+	}
+
+	provider.Shutdown(context.Background())
+}
+
+func TestTraceBuffer_WithoutBufferChildLeaks(t *testing.T) {
+	// Demonstrates the behavior the buffer addresses.
+	customSampler := NewCustomSampler(neverSampler)
+	customSampler.SetConfig(parentSampledOutConfig())
+	exporter := &syncTestExporter{}
+	provider := trace.NewTracerProvider(
+		trace.WithSpanProcessor(trace.NewSimpleSpanProcessor(newTraceExporter(exporter, customSampler))),
+	)
+	tracer := provider.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	names := exporter.names()
+	if len(names) != 1 || names[0] != "child" {
+		t.Errorf("expected the child to be exported without the buffer, got %v", names)
+	}
+
+	provider.Shutdown(context.Background())
+}
+
+func TestTraceBuffer_ExportsIncompleteTracesAfterTimeout(t *testing.T) {
+	exporter := &syncTestExporter{}
+	buffer := newTraceBuffer(exporter, 20*time.Millisecond, 100, time.Second, 512)
+	buffer.telemetry = &signalTelemetry{}
+	provider := trace.NewTracerProvider(trace.WithSpanProcessor(trace.NewSimpleSpanProcessor(buffer)))
+	tracer := provider.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(exporter.names()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the child to be exported after the timeout")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if names := exporter.names(); len(names) != 1 || names[0] != "child" {
+		t.Errorf("expected the child to be exported, got %v", names)
+	}
+	if stats := buffer.telemetry.stats(); stats.BufferTimedOutTraces != 1 || stats.BufferEvictedTraces != 0 {
+		t.Errorf("expected the trace to be counted as timed out, got %+v", stats)
+	}
+
+	parent.End()
+	if names := exporter.names(); len(names) != 2 {
+		t.Errorf("expected the parent to be exported when it ends, got %v", names)
+	}
+
+	provider.Shutdown(context.Background())
+}
+
+func TestTraceBuffer_EvictsOldestTraceWhenFull(t *testing.T) {
+	exporter := &syncTestExporter{}
+	buffer := newTraceBuffer(exporter, time.Hour, 2, time.Second, 512)
+	buffer.telemetry = &signalTelemetry{}
+	provider := trace.NewTracerProvider(trace.WithSpanProcessor(trace.NewSimpleSpanProcessor(buffer)))
+	tracer := provider.Tracer("test")
+
+	firstCtx, first := tracer.Start(context.Background(), "first-root")
+	_, firstChild := tracer.Start(firstCtx, "first-child")
+	firstChild.End()
+	secondCtx, second := tracer.Start(context.Background(), "second-root")
+	_, secondChild := tracer.Start(secondCtx, "second-child")
+	secondChild.End()
+
+	if names := exporter.names(); len(names) != 0 {
+		t.Errorf("expected both children to be buffered, got %v", names)
+	}
+
+	_, thirdChild := tracer.Start(secondCtx, "second-child-2")
+	thirdChild.End()
+
+	names := exporter.names()
+	if len(names) != 1 || names[0] != "first-child" {
+		t.Errorf("expected the oldest trace to be evicted, got %v", names)
+	}
+	if stats := buffer.telemetry.stats(); stats.BufferEvictedTraces != 1 || stats.BufferTimedOutTraces != 0 {
+		t.Errorf("expected the trace to be counted as evicted, got %+v", stats)
+	}
+
+	first.End()
+	second.End()
+	provider.Shutdown(context.Background())
+}
+
+func TestTraceBuffer_ShutdownExportsBufferedSpans(t *testing.T) {
+	exporter := &syncTestExporter{}
+	buffer := newTraceBuffer(exporter, time.Hour, 100, time.Second, 512)
+	provider := trace.NewTracerProvider(trace.WithSpanProcessor(trace.NewSimpleSpanProcessor(buffer)))
+	tracer := provider.Tracer("test")
+
+	// The parent is never ended, so the trace is never complete.
+	ctx, _ := tracer.Start(context.Background(), "parent")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := exporter.names(); len(names) != 1 || names[0] != "child" {
+		t.Errorf("expected the buffered child to be exported on shutdown, got %v", names)
+	}
+	if !exporter.shutdown {
+		t.Error("expected the wrapped exporter to be shut down")
+	}
+}
+
+func TestCreateSpanProcessor_FlushExportsBufferedSpans(t *testing.T) {
+	ctx := context.Background()
+	server := newTestOTLPServer(t)
+	server.healthy.Store(true)
+	processor, err := createSpanProcessor(ctx, &Config{
+		OtlpEndpoint:        "http://" + server.endpoint(),
+		OtlpCompression:     CompressionNone,
+		SpanMaxQueueSize:    trace.DefaultMaxQueueSize,
+		TraceBufferTimeout:  time.Hour,
+		TraceBufferMaxSpans: 100,
+	}, NewCustomSampler(nil))
+	if err != nil {
+		t.Fatalf("failed to create the span processor: %v", err)
+	}
+	provider := trace.NewTracerProvider(trace.WithSpanProcessor(processor))
+	defer func() { _ = provider.Shutdown(ctx) }()
+	tracer := provider.Tracer("test")
+
+	// The parent is never ended, so the trace is never complete.
+	spanCtx, _ := tracer.Start(ctx, "parent")
+	_, child := tracer.Start(spanCtx, "child")
+	child.End()
+
+	if err := provider.ForceFlush(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spans, _ := server.received(); len(spans) != 1 || spans[0] != "child" {
+		t.Errorf("expected the buffered child to be exported when flushed, got %v", spans)
+	}
+}
+
+func TestTraceBuffer_CountsSpansWhichCannotBeExported(t *testing.T) {
+	buffer := newTraceBuffer(failingExporter{}, time.Hour, 100, time.Second, 512)
+	buffer.telemetry = &signalTelemetry{}
+	provider := trace.NewTracerProvider(trace.WithSpanProcessor(trace.NewSimpleSpanProcessor(buffer)))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	_, child := provider.Tracer("test").Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	if dropped := buffer.telemetry.stats().DroppedFromBuffer; dropped != 2 {
+		t.Errorf("expected the spans of the trace to be counted as dropped, got %d", dropped)
+	}
+	_ = provider.Shutdown(context.Background())
+}
+
+// concurrencyCheckingExporter records whether it is ever called while another
+// export is in progress.
+type concurrencyCheckingExporter struct {
+	inFlight   atomic.Int32
+	concurrent atomic.Bool
+}
+
+func (e *concurrencyCheckingExporter) ExportSpans(context.Context, []trace.ReadOnlySpan) error {
+	if e.inFlight.Add(1) > 1 {
+		e.concurrent.Store(true)
+	}
+	time.Sleep(time.Millisecond)
+	e.inFlight.Add(-1)
+	return nil
+}
+
+func (e *concurrencyCheckingExporter) Shutdown(context.Context) error { return nil }
+
+func TestTraceBuffer_DoesNotExportConcurrently(t *testing.T) {
+	exporter := &concurrencyCheckingExporter{}
+	buffer := newTraceBuffer(exporter, time.Millisecond, 100, time.Second, 512)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_ = buffer.ExportSpans(ctx, []trace.ReadOnlySpan{testSpanStub("root").Snapshot()})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				_ = buffer.flush(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+	_ = buffer.Shutdown(ctx)
+
+	if exporter.concurrent.Load() {
+		t.Error("expected the wrapped exporter not to be called concurrently")
+	}
+}
+
+// batchRecordingExporter records the size of each batch it exports, and fails
+// the exports of the batches it is told to.
+type batchRecordingExporter struct {
+	sizes []int
+	fail  map[int]bool
+}
+
+func (e *batchRecordingExporter) ExportSpans(_ context.Context, spans []trace.ReadOnlySpan) error {
+	e.sizes = append(e.sizes, len(spans))
+	if e.fail[len(e.sizes)] {
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func (e *batchRecordingExporter) Shutdown(context.Context) error { return nil }
+
+func TestTraceBuffer_ExportsInBatches(t *testing.T) {
+	// The second batch fails.
+	exporter := &batchRecordingExporter{fail: map[int]bool{2: true}}
+	buffer := newTraceBuffer(exporter, time.Hour, 100, time.Second, 2)
+	buffer.telemetry = &signalTelemetry{}
+	provider := trace.NewTracerProvider(trace.WithSpanProcessor(trace.NewSimpleSpanProcessor(buffer)))
+	tracer := provider.Tracer("test")
+
+	// The parents are never ended, so the traces are only exported when the
+	// buffer is flushed.
+	for i := 0; i < 5; i++ {
+		ctx, _ := tracer.Start(context.Background(), "parent")
+		_, child := tracer.Start(ctx, "child")
+		child.End()
+	}
+	if err := buffer.flush(context.Background()); err == nil {
+		t.Error("expected the error of the failed batch to be returned")
+	}
+
+	if len(exporter.sizes) != 3 || exporter.sizes[0] != 2 || exporter.sizes[1] != 2 || exporter.sizes[2] != 1 {
+		t.Errorf("expected batches of 2, 2 and 1 spans, got %v", exporter.sizes)
+	}
+	if dropped := buffer.telemetry.stats().DroppedFromBuffer; dropped != 2 {
+		t.Errorf("expected only the spans of the failed batch to be counted as dropped, got %d", dropped)
+	}
+	_ = provider.Shutdown(context.Background())
+}
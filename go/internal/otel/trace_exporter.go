@@ -4,13 +4,47 @@ import (
 	"context"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/attributes"
+	"github.com/launchdarkly/observability-sdk/go/internal/sampling"
 )
 
 type traceExporter struct {
 	sdktrace.SpanExporter
 	sampler ExportSampler
+	// When set, every span of a trace containing an error is exported,
+	// regardless of the sampling decisions for the individual spans.
+	keepErroredTraces bool
+	// When set, the spans which are not exported are counted.
+	telemetry *signalTelemetry
+}
+
+// isErrorSpan returns true if the span has an error status or recorded an exception.
+func isErrorSpan(s sdktrace.ReadOnlySpan) bool {
+	if s.Status().Code == codes.Error {
+		return true
+	}
+	for _, event := range s.Events() {
+		if event.Name == semconv.ExceptionEventName {
+			return true
+		}
+	}
+	return false
+}
+
+// erroredTraces returns the IDs of the traces which have an error span in the batch.
+func erroredTraces(spans []sdktrace.ReadOnlySpan) map[trace.TraceID]struct{} {
+	errored := make(map[trace.TraceID]struct{})
+	for _, s := range spans {
+		if isErrorSpan(s) {
+			errored[s.SpanContext().TraceID()] = struct{}{}
+		}
+	}
+	return errored
 }
 
 // readOnlySpanWorkaround is a workaround to allow us to add extra attributes to a span.
@@ -21,10 +55,54 @@ type traceExporter struct {
 type readOnlySpanWorkaround struct {
 	sdktrace.ReadOnlySpan
 	extraAttributes []attribute.KeyValue
+	// Set when the extra sampling ratio was applied with the randomness of the
+	// trace ID.
+	byTraceID bool
 }
 
 func (r readOnlySpanWorkaround) Attributes() []attribute.KeyValue {
-	return append(r.ReadOnlySpan.Attributes(), r.extraAttributes...)
+	original := r.ReadOnlySpan.Attributes()
+	ratio, ok := samplingRatio(r.extraAttributes)
+	if !ok {
+		return append(original, r.extraAttributes...)
+	}
+	// A span which was head sampled with a ratio, and sampled again when it
+	// was exported, was kept at the product of the two ratios when the
+	// decisions are independent. When both decisions were made with the
+	// randomness of the trace ID, a span kept by the larger ratio is also kept
+	// by the smaller one, so the larger ratio applies. The ratio is recorded
+	// once.
+	_, consistent := sampling.Threshold(r.SpanContext().TraceState())
+	nested := r.byTraceID && consistent
+	attrs := make([]attribute.KeyValue, 0, len(original)+len(r.extraAttributes))
+	for _, attr := range original {
+		if attr.Key == attributes.AttrSamplingRatio {
+			if nested {
+				ratio = max(ratio, attr.Value.AsInt64())
+			} else {
+				ratio *= attr.Value.AsInt64()
+			}
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	for _, attr := range r.extraAttributes {
+		if attr.Key == attributes.AttrSamplingRatio {
+			attr = attribute.Int64(attributes.AttrSamplingRatio, ratio)
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
+
+// samplingRatio returns the sampling ratio recorded in the attributes.
+func samplingRatio(attrs []attribute.KeyValue) (int64, bool) {
+	for _, attr := range attrs {
+		if attr.Key == attributes.AttrSamplingRatio {
+			return attr.Value.AsInt64(), true
+		}
+	}
+	return 0, false
 }
 
 // ExportSpans implements trace.SpanExporter.
@@ -37,6 +115,11 @@ func (t *traceExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOn
 	spanById := make(map[trace.SpanID]sdktrace.ReadOnlySpan)
 	childrenByParentId := make(map[trace.SpanID][]trace.SpanID)
 
+	var keptTraces map[trace.TraceID]struct{}
+	if t.keepErroredTraces {
+		keptTraces = erroredTraces(spans)
+	}
+
 	// THe first pass we sample items which are directly impacted by a sampling decision.
 	// We also build a map of children spans by parent span id, which allows us to quickly traverse the span tree.
 	for _, s := range spans {
@@ -51,16 +134,26 @@ func (t *traceExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOn
 				rs := readOnlySpanWorkaround{
 					ReadOnlySpan:    s,
 					extraAttributes: res.Attributes,
+					byTraceID:       res.ByTraceID,
 				}
 				spanById[s.SpanContext().SpanID()] = rs
 			} else {
 				spanById[s.SpanContext().SpanID()] = s
 			}
+		} else if _, kept := keptTraces[s.SpanContext().TraceID()]; kept {
+			// The span was not sampled, so the sampling ratio does not apply to it.
+			// It is marked so that it is not counted as a sampled span.
+			spanById[s.SpanContext().SpanID()] = readOnlySpanWorkaround{
+				ReadOnlySpan:    s,
+				extraAttributes: []attribute.KeyValue{attribute.Bool(attributes.AttrSamplingForceKept, true)},
+			}
 		} else {
 			omittedSpanIds = append(omittedSpanIds, s.SpanContext().SpanID())
 		}
 	}
 
+	sampledOut := len(omittedSpanIds)
+
 	// Find all children of spans that have been sampled out and remove them.
 	// Repeat until there are no more children to remove.
 	for len(omittedSpanIds) != 0 {
@@ -73,6 +166,8 @@ func (t *traceExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOn
 		}
 		omittedSpanIds = append(omittedSpanIds, affectedSpans...)
 
+		// A kept trace never has omitted spans, so none of the children
+		// removed here belong to a kept trace.
 		for _, affectedSpanId := range affectedSpans {
 			delete(spanById, affectedSpanId)
 		}
@@ -82,6 +177,10 @@ func (t *traceExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOn
 	for _, s := range spanById {
 		exportedSpans = append(exportedSpans, s)
 	}
+	if t.telemetry != nil {
+		t.telemetry.sampledOut.Add(int64(sampledOut))
+		t.telemetry.prunedChildren.Add(int64(len(spans) - len(exportedSpans) - sampledOut))
+	}
 
 	return t.SpanExporter.ExportSpans(ctx, exportedSpans)
 }
@@ -2,12 +2,18 @@ package otel
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
 
+	"github.com/launchdarkly/observability-sdk/go/attributes"
 	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+	"github.com/launchdarkly/observability-sdk/go/internal/sampling"
 )
 
 type testExporter struct {
@@ -112,6 +118,98 @@ func TestTraceExporter_WithMatchingConfigSampledIn(t *testing.T) {
 	provider.Shutdown(context.Background())
 }
 
+func TestTraceExporter_CombinesHeadAndExportSamplingRatios(t *testing.T) {
+	customSampler := NewCustomSampler(alwaysSampler)
+	exporter := &testExporter{}
+	spanProcessor := trace.NewSimpleSpanProcessor(newTraceExporter(exporter, customSampler))
+	provider := trace.NewTracerProvider(
+		trace.WithSpanProcessor(spanProcessor),
+	)
+	customSampler.SetConfig(&gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{
+			{SamplingRatio: 3},
+		},
+	})
+
+	// The span was head sampled with a ratio of 4.
+	_, span := provider.Tracer("test").Start(context.Background(), "test-span",
+		oteltrace.WithAttributes(attribute.Int64(attributes.AttrSamplingRatio, 4)))
+	span.End()
+
+	if len(exporter.exportedSpans) != 1 {
+		t.Fatalf("expected 1 exported span, got %d", len(exporter.exportedSpans))
+	}
+	var ratios []int64
+	for _, attr := range exporter.exportedSpans[0].Attributes() {
+		if attr.Key == attributes.AttrSamplingRatio {
+			ratios = append(ratios, attr.Value.AsInt64())
+		}
+	}
+	if len(ratios) != 1 || ratios[0] != 12 {
+		t.Errorf("expected a single sampling ratio of 12, got %v", ratios)
+	}
+
+	provider.Shutdown(context.Background())
+}
+
+func TestTraceExporter_TakesLargerRatioWithTraceIDExportSampling(t *testing.T) {
+	// The randomness of the trace is the largest possible value, so the trace
+	// is sampled at every ratio.
+	traceID := oteltrace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	consistent, err := sampling.WithThreshold(oteltrace.TraceState{}, sampling.ThresholdForProbability(0.25))
+	if err != nil {
+		t.Fatalf("failed to record the threshold: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		traceState oteltrace.TraceState
+		expected   int64
+	}{
+		// The head decision used the same randomness, so a span kept with a
+		// ratio of 4 is also kept with a ratio of 3.
+		{name: "consistent head sampling", traceState: consistent, expected: 4},
+		// A rate limited root records no threshold, so the head decision is
+		// independent of the randomness.
+		{name: "rate limited head sampling", traceState: oteltrace.TraceState{}, expected: 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customSampler := NewCustomSampler(neverSampler)
+			customSampler.SetTraceIDSampling(true)
+			customSampler.SetConfig(&gql.GetSamplingConfigSamplingSamplingConfig{
+				Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{
+					{SamplingRatio: 3},
+				},
+			})
+			exporter := &testExporter{}
+			span := tracetest.SpanStub{
+				Name: "root",
+				SpanContext: oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+					TraceID:    traceID,
+					SpanID:     oteltrace.SpanID{1},
+					TraceFlags: oteltrace.FlagsSampled,
+					TraceState: tt.traceState,
+				}),
+				Attributes: []attribute.KeyValue{attribute.Int64(attributes.AttrSamplingRatio, 4)},
+			}.Snapshot()
+
+			err := newTraceExporter(exporter, customSampler).ExportSpans(context.Background(), []trace.ReadOnlySpan{span})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(exporter.exportedSpans) != 1 {
+				t.Fatalf("expected 1 exported span, got %d", len(exporter.exportedSpans))
+			}
+			ratio, _ := samplingRatio(exporter.exportedSpans[0].Attributes())
+			if ratio != tt.expected {
+				t.Errorf("expected a sampling ratio of %d, got %d", tt.expected, ratio)
+			}
+		})
+	}
+}
+
 func TestTraceExporter_WithMatchingConfigSampledOut(t *testing.T) {
 	// Test that spans with matching configuration but sampler returning false are not exported
 	customSampler := NewCustomSampler(neverSampler)
@@ -405,3 +503,99 @@ func TestTraceExporter_RemoveChildrenOfUnsampledSpans(t *testing.T) {
 
 	provider.Shutdown(context.Background())
 }
+
+func TestTraceExporter_KeepErroredTraces(t *testing.T) {
+	customSampler := NewCustomSampler(neverSampler)
+	exporter := &testExporter{}
+	sampledExporter := newTraceExporter(exporter, customSampler)
+	sampledExporter.keepErroredTraces = true
+	spanProcessor := trace.NewBatchSpanProcessor(sampledExporter)
+	provider := trace.NewTracerProvider(
+		trace.WithSpanProcessor(spanProcessor),
+	)
+
+	// Every span is matched and sampled out by the neverSampler.
+	customSampler.SetConfig(&gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{
+			{SamplingRatio: 10},
+		},
+	})
+
+	tracer := provider.Tracer("test")
+
+	// A trace with an exception in a child span.
+	ctx, parent := tracer.Start(context.Background(), "errored-parent")
+	_, child := tracer.Start(ctx, "errored-child")
+	child.RecordError(errors.New("failure"))
+	child.End()
+	parent.End()
+
+	// A trace with an error status on the root span.
+	statusCtx, statusRoot := tracer.Start(context.Background(), "status-root")
+	_, statusChild := tracer.Start(statusCtx, "status-child")
+	statusChild.End()
+	statusRoot.SetStatus(codes.Error, "failed")
+	statusRoot.End()
+
+	// A trace without errors.
+	okCtx, okParent := tracer.Start(context.Background(), "ok-parent")
+	_, okChild := tracer.Start(okCtx, "ok-child")
+	okChild.End()
+	okParent.End()
+
+	spanProcessor.ForceFlush(context.Background())
+
+	exported := make(map[string]trace.ReadOnlySpan)
+	for _, s := range exporter.exportedSpans {
+		exported[s.Name()] = s
+	}
+	for _, name := range []string{"errored-parent", "errored-child", "status-root", "status-child"} {
+		s, ok := exported[name]
+		if !ok {
+			t.Errorf("expected span %s to be exported", name)
+			continue
+		}
+		forceKept := false
+		for _, attr := range s.Attributes() {
+			if attr.Key == attributes.AttrSamplingForceKept && attr.Value.AsBool() {
+				forceKept = true
+			}
+			if attr.Key == attributes.AttrSamplingRatio {
+				t.Errorf("unexpected sampling ratio attribute on force kept span %s", name)
+			}
+		}
+		if !forceKept {
+			t.Errorf("expected span %s to be marked as force kept", name)
+		}
+	}
+	if len(exporter.exportedSpans) != 4 {
+		t.Errorf("expected 4 exported spans, got %d", len(exporter.exportedSpans))
+	}
+
+	provider.Shutdown(context.Background())
+}
+
+func TestTraceExporter_ErroredTracesNotKeptByDefault(t *testing.T) {
+	customSampler := NewCustomSampler(neverSampler)
+	exporter := &testExporter{}
+	spanProcessor := trace.NewBatchSpanProcessor(newTraceExporter(exporter, customSampler))
+	provider := trace.NewTracerProvider(
+		trace.WithSpanProcessor(spanProcessor),
+	)
+	customSampler.SetConfig(&gql.GetSamplingConfigSamplingSamplingConfig{
+		Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{
+			{SamplingRatio: 10},
+		},
+	})
+
+	_, span := provider.Tracer("test").Start(context.Background(), "errored")
+	span.RecordError(errors.New("failure"))
+	span.End()
+	spanProcessor.ForceFlush(context.Background())
+
+	if len(exporter.exportedSpans) != 0 {
+		t.Errorf("expected 0 exported spans, got %d", len(exporter.exportedSpans))
+	}
+
+	provider.Shutdown(context.Background())
+}
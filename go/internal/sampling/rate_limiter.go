@@ -0,0 +1,97 @@
+package sampling
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// The period over which the probability of a rate limiter is estimated. Items
+// seen longer ago than this contribute progressively less to the estimate.
+const rateEstimateWindow = 5 * time.Second
+
+// RateLimiter is a token bucket which allows up to a number of items each
+// second. It also estimates the probability with which it currently allows
+// items, so that counts of the allowed items can be extrapolated.
+type RateLimiter struct {
+	perSecond float64
+	burst     float64
+	now       func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	// Exponentially decayed counts of the items seen and allowed.
+	seen    float64
+	allowed float64
+}
+
+// NewRateLimiter creates a rate limiter which allows up to perSecond items
+// each second. Up to one second of items may be allowed in a burst.
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	burst := math.Max(perSecond, 1)
+	return &RateLimiter{
+		perSecond: perSecond,
+		burst:     burst,
+		now:       time.Now,
+		tokens:    burst,
+	}
+}
+
+// Allow returns true if an item is allowed. It also returns the estimated
+// probability with which items are currently allowed, which includes this item.
+func (l *RateLimiter) Allow() (bool, float64) {
+	return AllowAll(l)
+}
+
+// AllowAll returns true if an item is allowed by every one of the limiters.
+// A token is only spent from each limiter when the item is allowed by all of
+// them, so a limiter which allows an item does not lose a token when a later
+// limiter denies it. It also returns the product of the estimated
+// probabilities of the limiters. Nil limiters are ignored.
+func AllowAll(limiters ...*RateLimiter) (bool, float64) {
+	allowed := true
+	probability := 1.0
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		hasToken, limiterProbability := l.observe()
+		allowed = allowed && hasToken
+		probability *= limiterProbability
+	}
+	if allowed {
+		for _, l := range limiters {
+			if l != nil {
+				l.tokens--
+			}
+		}
+	}
+	return allowed, probability
+}
+
+// observe refills the tokens and counts an item. It returns true if a token is
+// available for the item, which is counted as allowed by this limiter even if
+// it is denied by another, so that the estimated probability of each limiter
+// is independent of the others. Must be called with the lock held.
+func (l *RateLimiter) observe() (bool, float64) {
+	now := l.now()
+	elapsed := 0.0
+	if !l.last.IsZero() {
+		elapsed = math.Max(now.Sub(l.last).Seconds(), 0)
+	}
+	l.last = now
+	l.tokens = math.Min(l.burst, l.tokens+elapsed*l.perSecond)
+
+	decay := math.Exp(-elapsed / rateEstimateWindow.Seconds())
+	l.seen = l.seen*decay + 1
+	l.allowed *= decay
+	hasToken := l.tokens >= 1
+	if hasToken {
+		l.allowed++
+	}
+	return hasToken, l.allowed / l.seen
+}
@@ -0,0 +1,89 @@
+package sampling
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_AllowsBurstThenLimits(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := NewRateLimiter(10)
+	limiter.now = func() time.Time { return now }
+
+	allowed := 0
+	for range 100 {
+		if ok, _ := limiter.Allow(); ok {
+			allowed++
+		}
+	}
+	if allowed != 10 {
+		t.Errorf("expected a burst of 10 items to be allowed, got %d", allowed)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	allowed = 0
+	for range 100 {
+		if ok, _ := limiter.Allow(); ok {
+			allowed++
+		}
+	}
+	if allowed != 5 {
+		t.Errorf("expected 5 items to be allowed after half a second, got %d", allowed)
+	}
+}
+
+func TestRateLimiter_EstimatesProbability(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := NewRateLimiter(10)
+	limiter.now = func() time.Time { return now }
+
+	// 100 items each second, evenly spaced, of which 10 are allowed.
+	var probability float64
+	for range 3000 {
+		now = now.Add(10 * time.Millisecond)
+		_, probability = limiter.Allow()
+	}
+	if math.Abs(probability-0.1) > 0.02 {
+		t.Errorf("expected a probability of about 0.1, got %v", probability)
+	}
+}
+
+func TestRateLimiter_UnderLimit(t *testing.T) {
+	now := time.Unix(1000, 0)
+	limiter := NewRateLimiter(10)
+	limiter.now = func() time.Time { return now }
+
+	for range 100 {
+		now = now.Add(time.Second)
+		ok, probability := limiter.Allow()
+		if !ok || probability != 1 {
+			t.Fatalf("expected items under the limit to be allowed with probability 1, got %t %v", ok, probability)
+		}
+	}
+}
+
+func TestAllowAll_DenialDoesNotSpendOtherTokens(t *testing.T) {
+	now := time.Unix(1000, 0)
+	first := NewRateLimiter(1)
+	second := NewRateLimiter(1)
+	first.now = func() time.Time { return now }
+	second.now = func() time.Time { return now }
+
+	if ok, _ := second.Allow(); !ok {
+		t.Fatal("expected the first item to be allowed")
+	}
+	if ok, _ := AllowAll(first, second); ok {
+		t.Fatal("expected the item to be denied by the second limiter")
+	}
+	if ok, _ := first.Allow(); !ok {
+		t.Error("expected the first limiter to keep its token when the second denied the item")
+	}
+}
+
+func TestAllowAll_IgnoresNilLimiters(t *testing.T) {
+	ok, probability := AllowAll(nil, NewRateLimiter(1), nil)
+	if !ok || probability != 1 {
+		t.Errorf("expected the item to be allowed with probability 1, got %t %v", ok, probability)
+	}
+}
@@ -0,0 +1,149 @@
+// Package sampling implements the consistent probability sampling of the
+// OpenTelemetry specification. A trace is sampled when its randomness value is
+// greater than or equal to a rejection threshold. The threshold is recorded in
+// the "th" field of the "ot" tracestate entry, which allows the services that
+// receive the trace to know the probability it was sampled with.
+//
+// https://opentelemetry.io/docs/specs/otel/trace/tracestate-probability-sampling/
+package sampling
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// The number of bits of randomness and of precision in thresholds.
+	randomnessBits = 56
+	// The number of hexadecimal digits in a threshold or randomness value.
+	hexDigits = randomnessBits / 4
+
+	// MaxThreshold is the threshold which rejects every trace.
+	MaxThreshold   uint64 = 1 << randomnessBits
+	randomnessMask        = MaxThreshold - 1
+
+	otKey = "ot"
+	thKey = "th"
+	rvKey = "rv"
+)
+
+// ThresholdForProbability returns the rejection threshold which samples traces
+// with the given probability.
+func ThresholdForProbability(probability float64) uint64 {
+	if probability >= 1 {
+		return 0
+	}
+	if probability <= 0 {
+		return MaxThreshold
+	}
+	return MaxThreshold - uint64(math.Round(probability*float64(MaxThreshold)))
+}
+
+// Probability returns the probability with which the threshold samples traces.
+func Probability(threshold uint64) float64 {
+	if threshold >= MaxThreshold {
+		return 0
+	}
+	return float64(MaxThreshold-threshold) / float64(MaxThreshold)
+}
+
+// Sampled returns true if a trace with the given randomness is sampled by the threshold.
+func Sampled(randomness uint64, threshold uint64) bool {
+	return randomness >= threshold
+}
+
+// TraceIDRandomness returns the randomness of a trace ID, which is its least
+// significant 56 bits.
+func TraceIDRandomness(traceID trace.TraceID) uint64 {
+	return binary.BigEndian.Uint64(traceID[8:16]) & randomnessMask
+}
+
+// Randomness returns the randomness of a trace. An explicit randomness value in
+// the "rv" field of the tracestate takes precedence over the trace ID.
+func Randomness(traceID trace.TraceID, state trace.TraceState) uint64 {
+	if value, ok := otValue(state, rvKey); ok && len(value) == hexDigits {
+		if rv, err := strconv.ParseUint(value, 16, 64); err == nil {
+			return rv
+		}
+	}
+	return TraceIDRandomness(traceID)
+}
+
+// EncodeThreshold encodes a threshold in the form used in the tracestate.
+// Trailing zeros are removed, and a threshold of zero is encoded as "0".
+func EncodeThreshold(threshold uint64) string {
+	if threshold == 0 {
+		return "0"
+	}
+	encoded := strconv.FormatUint(threshold, 16)
+	encoded = strings.Repeat("0", hexDigits-len(encoded)) + encoded
+	return strings.TrimRight(encoded, "0")
+}
+
+// ParseThreshold parses a threshold encoded in the form used in the tracestate.
+func ParseThreshold(encoded string) (uint64, bool) {
+	if len(encoded) == 0 || len(encoded) > hexDigits {
+		return 0, false
+	}
+	// Omitted trailing digits are zeros.
+	threshold, err := strconv.ParseUint(encoded+strings.Repeat("0", hexDigits-len(encoded)), 16, 64)
+	if err != nil {
+		return 0, false
+	}
+	return threshold, true
+}
+
+// Threshold returns the threshold recorded in the tracestate, if there is a valid one.
+func Threshold(state trace.TraceState) (uint64, bool) {
+	value, ok := otValue(state, thKey)
+	if !ok {
+		return 0, false
+	}
+	return ParseThreshold(value)
+}
+
+// WithThreshold returns the tracestate with the threshold recorded in it.
+// Other fields of the "ot" entry, and other entries, are preserved.
+func WithThreshold(state trace.TraceState, threshold uint64) (trace.TraceState, error) {
+	return withOTValue(state, thKey, EncodeThreshold(threshold))
+}
+
+// WithoutThreshold returns the tracestate without a recorded threshold.
+func WithoutThreshold(state trace.TraceState) (trace.TraceState, error) {
+	return withOTValue(state, thKey, "")
+}
+
+// otValue returns the value of a field of the "ot" tracestate entry.
+// The entry has the form "key:value;key:value".
+func otValue(state trace.TraceState, key string) (string, bool) {
+	for _, field := range strings.Split(state.Get(otKey), ";") {
+		k, v, found := strings.Cut(field, ":")
+		if found && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// withOTValue sets a field of the "ot" tracestate entry. An empty value removes the field.
+func withOTValue(state trace.TraceState, key string, value string) (trace.TraceState, error) {
+	var fields []string
+	if value != "" {
+		fields = append(fields, key+":"+value)
+	}
+	if existing := state.Get(otKey); existing != "" {
+		for _, field := range strings.Split(existing, ";") {
+			if k, _, _ := strings.Cut(field, ":"); k != key {
+				fields = append(fields, field)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return state.Delete(otKey), nil
+	}
+	return state.Insert(otKey, strings.Join(fields, ";"))
+}
@@ -0,0 +1,97 @@
+package sampling
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestThresholdForProbability(t *testing.T) {
+	cases := []struct {
+		probability float64
+		encoded     string
+	}{
+		{1, "0"},
+		{0.5, "8"},
+		{0.25, "c"},
+		{0.1, "e6666666666666"},
+		{0.01, "fd70a3d70a3d71"},
+	}
+	for _, c := range cases {
+		threshold := ThresholdForProbability(c.probability)
+		if encoded := EncodeThreshold(threshold); encoded != c.encoded {
+			t.Errorf("probability %v: expected threshold %q, got %q", c.probability, c.encoded, encoded)
+		}
+		parsed, ok := ParseThreshold(c.encoded)
+		if !ok || parsed != threshold {
+			t.Errorf("probability %v: expected %q to parse to %x, got %x (%t)", c.probability, c.encoded, threshold, parsed, ok)
+		}
+	}
+	if ThresholdForProbability(0) != MaxThreshold {
+		t.Error("expected a probability of 0 to reject every trace")
+	}
+}
+
+func TestParseThreshold_Invalid(t *testing.T) {
+	for _, encoded := range []string{"", "g", "123456789abcdef", "-1"} {
+		if _, ok := ParseThreshold(encoded); ok {
+			t.Errorf("expected %q to be invalid", encoded)
+		}
+	}
+}
+
+func TestProbability(t *testing.T) {
+	if p := Probability(ThresholdForProbability(0.25)); p != 0.25 {
+		t.Errorf("expected probability 0.25, got %v", p)
+	}
+	if p := Probability(MaxThreshold); p != 0 {
+		t.Errorf("expected probability 0, got %v", p)
+	}
+}
+
+func TestRandomness(t *testing.T) {
+	traceID := trace.TraceID{0xff, 0, 0, 0, 0, 0, 0, 0, 0xff, 0, 0, 0, 0, 0, 0, 1}
+	if r := Randomness(traceID, trace.TraceState{}); r != 1 {
+		t.Errorf("expected the lower 56 bits of the trace ID, got %x", r)
+	}
+	state, err := trace.ParseTraceState("ot=rv:abcdef01234567")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := Randomness(traceID, state); r != 0xabcdef01234567 {
+		t.Errorf("expected the explicit randomness value, got %x", r)
+	}
+}
+
+func TestWithThreshold(t *testing.T) {
+	state, err := trace.ParseTraceState("ot=rv:abcdef01234567;th:4,vendor=value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err = WithThreshold(state, ThresholdForProbability(0.25))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ot := state.Get("ot"); ot != "th:c;rv:abcdef01234567" {
+		t.Errorf("expected the threshold to be replaced, got %q", ot)
+	}
+	if v := state.Get("vendor"); v != "value" {
+		t.Errorf("expected other entries to be kept, got %q", v)
+	}
+	threshold, ok := Threshold(state)
+	if !ok || threshold != ThresholdForProbability(0.25) {
+		t.Errorf("expected the threshold to be read back, got %x (%t)", threshold, ok)
+	}
+
+	state, err = WithoutThreshold(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ot := state.Get("ot"); ot != "rv:abcdef01234567" {
+		t.Errorf("expected the threshold to be removed, got %q", ot)
+	}
+	state, err = WithoutThreshold(trace.TraceState{})
+	if err != nil || state.Len() != 0 {
+		t.Errorf("expected an empty tracestate, got %q (%v)", state.String(), err)
+	}
+}
@@ -0,0 +1,205 @@
+// Package ldlogrus provides a logrus hook which records logs with the
+// LaunchDarkly observability plugin.
+package ldlogrus
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logconv"
+	o "github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// Option configures a hook created with NewHook.
+type Option func(h *Hook)
+
+// WithLevels sets the levels of the entries which are recorded.
+//
+// The default is all levels between logrus.PanicLevel and logrus.InfoLevel
+// inclusive.
+func WithLevels(levels ...logrus.Level) Option {
+	return func(h *Hook) {
+		h.levels = levels
+	}
+}
+
+// Hook is a logrus hook which records entries with the observability plugin.
+// Entries are converted to OpenTelemetry log records, which are sampled by
+// the sampling configuration of the plugin like any other logs, and are
+// associated with the span in the context of the entry, set with
+// logrus.WithContext. Pending records are exported after fatal and panic
+// entries, before logrus exits or panics.
+//
+//	logrus.AddHook(ldlogrus.NewHook())
+type Hook struct {
+	levels []logrus.Level
+	// The logger of the plugin is used when it is nil.
+	logger log.Logger
+}
+
+// NewHook creates a hook which records entries with the observability plugin.
+func NewHook(opts ...Option) *Hook {
+	hook := &Hook{
+		levels: []logrus.Level{
+			logrus.PanicLevel,
+			logrus.FatalLevel,
+			logrus.ErrorLevel,
+			logrus.WarnLevel,
+			logrus.InfoLevel,
+		},
+	}
+	for _, opt := range opts {
+		opt(hook)
+	}
+	return hook
+}
+
+// Levels implements logrus.Hook.
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire implements logrus.Hook.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	severity := severities.Convert(entry.Level)
+	logger := logconv.Logger(h.logger)
+	if !logger.Enabled(ctx, log.EnabledParameters{Severity: severity}) {
+		return nil
+	}
+
+	var r log.Record
+	r.SetTimestamp(entry.Time)
+	r.SetSeverity(severity)
+	r.SetSeverityText(entry.Level.String())
+	r.SetBody(log.StringValue(entry.Message))
+
+	// The data is a map, so the keys are sorted to keep the attributes in the
+	// same order for every record.
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	attrs := make([]log.KeyValue, 0, len(keys)+3)
+	for _, key := range keys {
+		value := entry.Data[key]
+		if err, ok := value.(error); ok && key == logrus.ErrorKey {
+			attrs = append(attrs, log.String(string(semconv.ExceptionMessageKey), err.Error()))
+			continue
+		}
+		attrs = append(attrs, log.KeyValue{Key: key, Value: convertValue(value)})
+	}
+	if entry.Caller != nil {
+		attrs = append(attrs,
+			log.String(string(semconv.CodeFilePathKey), entry.Caller.File),
+			log.Int(string(semconv.CodeLineNumberKey), entry.Caller.Line),
+			log.String(string(semconv.CodeFunctionNameKey), entry.Caller.Function),
+		)
+	}
+	r.AddAttributes(attrs...)
+
+	logger.Emit(ctx, r)
+	// logrus exits or panics after firing the hooks for fatal and panic
+	// entries, so pending records are exported first.
+	if entry.Level <= logrus.FatalLevel {
+		_ = o.FlushWithTimeout()
+	}
+	return nil
+}
+
+// severities converts logrus levels to log severities.
+//
+//nolint:gochecknoglobals
+var severities = logconv.Severities[logrus.Level]{
+	logrus.TraceLevel: log.SeverityTrace,
+	logrus.DebugLevel: log.SeverityDebug,
+	logrus.InfoLevel:  log.SeverityInfo,
+	logrus.WarnLevel:  log.SeverityWarn,
+	logrus.ErrorLevel: log.SeverityError,
+	logrus.FatalLevel: log.SeverityFatal1,
+	logrus.PanicLevel: log.SeverityFatal2,
+}
+
+// convertValue converts a value of the data of an entry to a log value, which
+// keeps the type of the value where the log value has one. The keys of maps
+// which are not strings are formatted with fmt.
+func convertValue(value any) log.Value {
+	switch v := value.(type) {
+	case nil:
+		return log.Value{}
+	case string:
+		return log.StringValue(v)
+	case bool:
+		return log.BoolValue(v)
+	case int:
+		return log.IntValue(v)
+	case int64:
+		return log.Int64Value(v)
+	case int32:
+		return log.Int64Value(int64(v))
+	case int16:
+		return log.Int64Value(int64(v))
+	case int8:
+		return log.Int64Value(int64(v))
+	case uint:
+		return logconv.Uint64Value(uint64(v))
+	case uint64:
+		return logconv.Uint64Value(v)
+	case uint32:
+		return log.Int64Value(int64(v))
+	case uint16:
+		return log.Int64Value(int64(v))
+	case uint8:
+		return log.Int64Value(int64(v))
+	case float64:
+		return log.Float64Value(v)
+	case float32:
+		return log.Float64Value(float64(v))
+	case []byte:
+		return log.BytesValue(v)
+	case time.Duration:
+		return log.Int64Value(v.Nanoseconds())
+	case time.Time:
+		return log.StringValue(v.Format(time.RFC3339Nano))
+	case error:
+		return log.StringValue(v.Error())
+	case fmt.Stringer:
+		return log.StringValue(v.String())
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]log.Value, 0, rv.Len())
+		for i := range rv.Len() {
+			values = append(values, convertValue(rv.Index(i).Interface()))
+		}
+		return log.SliceValue(values...)
+	case reflect.Map:
+		kvs := make([]log.KeyValue, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			name := key.String()
+			if key.Kind() != reflect.String {
+				name = fmt.Sprint(key.Interface())
+			}
+			kvs = append(kvs, log.KeyValue{Key: name, Value: convertValue(rv.MapIndex(key).Interface())})
+		}
+		slices.SortFunc(kvs, func(a, b log.KeyValue) int { return cmp.Compare(a.Key, b.Key) })
+		return log.MapValue(kvs...)
+	}
+	return log.StringValue(fmt.Sprintf("%+v", value))
+}
+
+var _ logrus.Hook = &Hook{}
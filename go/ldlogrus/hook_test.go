@@ -0,0 +1,161 @@
+package ldlogrus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logtest"
+)
+
+// newLogger returns a logger which records all levels with the hook.
+func newLogger(hook *Hook) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.TraceLevel)
+	logger.AddHook(hook)
+	return logger
+}
+
+func TestHook_ConvertsEntries(t *testing.T) {
+	recordingLogger, exporter := logtest.NewRecordingLogger()
+	hook := NewHook()
+	hook.logger = recordingLogger
+	logger := newLogger(hook)
+	logger.SetReportCaller(true)
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	ctx, span := tracer.Start(context.Background(), "request")
+	defer span.End()
+
+	logger.WithContext(ctx).WithError(errors.New("timeout")).WithFields(logrus.Fields{
+		"rows":     3,
+		"ratio":    0.5,
+		"cached":   false,
+		"duration": time.Second,
+		"tags":     []string{"a", "b"},
+		"labels":   map[string]int{"x": 1},
+	}).Warn("slow request")
+
+	records := exporter.Exported()
+	logtest.CheckRecords(t, records, logtest.Expected{
+		Body:         "slow request",
+		Severity:     log.SeverityWarn,
+		SeverityText: "warning",
+		SpanContext:  span.SpanContext(),
+		Attributes: map[string]log.Value{
+			"rows":              log.Int64Value(3),
+			"ratio":             log.Float64Value(0.5),
+			"cached":            log.BoolValue(false),
+			"duration":          log.Int64Value(int64(time.Second)),
+			"exception.message": log.StringValue("timeout"),
+			"tags":              log.SliceValue(log.StringValue("a"), log.StringValue("b")),
+			"labels":            log.MapValue(log.Int64("x", 1)),
+		},
+		Absent: []string{logrus.ErrorKey},
+	})
+	attrs := logtest.RecordAttributes(records[0])
+	if attrs["code.file.path"].AsString() == "" || attrs["code.line.number"].AsInt64() == 0 ||
+		attrs["code.function.name"].AsString() == "" {
+		t.Errorf("expected the caller to be converted, got %v", attrs)
+	}
+}
+
+type point struct{ x, y int }
+
+func TestHook_ConvertsData(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     logrus.Fields
+		expected logtest.Expected
+	}{
+		{
+			// Only an error under the error key is an exception.
+			name: "error key without an error",
+			data: logrus.Fields{logrus.ErrorKey: "card declined"},
+			expected: logtest.Expected{
+				Attributes: map[string]log.Value{logrus.ErrorKey: log.StringValue("card declined")},
+				Absent:     []string{"exception.message"},
+			},
+		},
+		{
+			name: "error under another key",
+			data: logrus.Fields{"cause": errors.New("timeout")},
+			expected: logtest.Expected{
+				Attributes: map[string]log.Value{"cause": log.StringValue("timeout")},
+				Absent:     []string{"exception.message"},
+			},
+		},
+		{
+			name: "nil error",
+			data: logrus.Fields{logrus.ErrorKey: nil},
+			expected: logtest.Expected{
+				Attributes: map[string]log.Value{logrus.ErrorKey: {}},
+				Absent:     []string{"exception.message"},
+			},
+		},
+		{
+			name: "integer map keys",
+			data: logrus.Fields{"counts": map[int]string{10: "b", 2: "a"}},
+			expected: logtest.Expected{
+				Attributes: map[string]log.Value{
+					"counts": log.MapValue(log.String("10", "b"), log.String("2", "a")),
+				},
+			},
+		},
+		{
+			name: "struct map keys",
+			data: logrus.Fields{"grid": map[point]bool{{x: 1, y: 2}: true}},
+			expected: logtest.Expected{
+				Attributes: map[string]log.Value{"grid": log.MapValue(log.Bool("{1 2}", true))},
+			},
+		},
+		{
+			name: "large unsigned integer",
+			data: logrus.Fields{"id": uint64(1) << 63},
+			expected: logtest.Expected{
+				Attributes: map[string]log.Value{"id": log.StringValue("9223372036854775808")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recordingLogger, exporter := logtest.NewRecordingLogger()
+			hook := NewHook()
+			hook.logger = recordingLogger
+
+			newLogger(hook).WithFields(tt.data).Info("converted")
+			logtest.CheckRecords(t, exporter.Exported(), tt.expected)
+		})
+	}
+}
+
+func TestHook_FlushesFatalAndPanicEntries(t *testing.T) {
+	logger := newLogger(NewHook())
+	logtest.CheckFlushesFatal(t,
+		func() { logger.Error("failed") },
+		func() {
+			defer func() { _ = recover() }()
+			logger.Panic("panicked")
+		},
+	)
+}
+
+func TestHook_Levels(t *testing.T) {
+	recordingLogger, exporter := logtest.NewRecordingLogger()
+	hook := NewHook(WithLevels(logrus.ErrorLevel))
+	hook.logger = recordingLogger
+	logger := newLogger(hook)
+	logger.Info("ignored")
+	logger.Error("recorded")
+
+	records := exporter.Exported()
+	if len(records) != 1 || records[0].Severity() != log.SeverityError {
+		t.Errorf("expected only the error to be recorded, got %d records", len(records))
+	}
+}
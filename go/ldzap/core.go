@@ -0,0 +1,132 @@
+// Package ldzap provides a zap core which records logs with the LaunchDarkly
+// observability plugin.
+package ldzap
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logconv"
+	o "github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// Core is a zapcore.Core which records logs with the observability plugin.
+// Entries are converted to OpenTelemetry log records, which are sampled by
+// the sampling configuration of the plugin like any other logs.
+//
+// To keep writing logs to an existing core, combine the cores with
+// zapcore.NewTee:
+//
+//	logger := zap.New(zapcore.NewTee(existing.Core(), ldzap.NewCore(zapcore.InfoLevel)))
+//
+// Records are associated with the span in a context which is given as a field
+// created with Context.
+type Core struct {
+	zapcore.LevelEnabler
+	enc *objectEncoder
+	ctx context.Context
+	// The logger of the plugin is used when it is nil.
+	logger log.Logger
+}
+
+// NewCore creates a core which records the entries enabled by the level
+// enabler with the observability plugin.
+func NewCore(enabler zapcore.LevelEnabler) *Core {
+	return &Core{
+		LevelEnabler: enabler,
+		enc:          &objectEncoder{},
+		ctx:          context.Background(),
+	}
+}
+
+// Context returns a field which carries a context. Records written with the
+// field are associated with the span in the context. Other cores ignore the
+// field.
+func Context(ctx context.Context) zap.Field {
+	return zap.Field{Key: "context", Type: zapcore.SkipType, Interface: ctx}
+}
+
+// With implements zapcore.Core.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.enc = c.enc.clone()
+	clone.ctx = clone.addFields(clone.enc, fields)
+	return &clone
+}
+
+// addFields encodes the fields, and returns the context of the last field
+// which carries one, or the context of the core.
+func (c *Core) addFields(enc *objectEncoder, fields []zapcore.Field) context.Context {
+	ctx := c.ctx
+	for _, field := range fields {
+		if fieldCtx, ok := field.Interface.(context.Context); ok {
+			ctx = fieldCtx
+			continue
+		}
+		field.AddTo(enc)
+	}
+	return ctx
+}
+
+// Check implements zapcore.Core.
+func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core.
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := c.enc.clone()
+	ctx := c.addFields(enc, fields)
+
+	var r log.Record
+	r.SetTimestamp(entry.Time)
+	r.SetSeverity(severities.Convert(entry.Level))
+	r.SetSeverityText(entry.Level.CapitalString())
+	r.SetBody(log.StringValue(entry.Message))
+	r.AddAttributes(enc.close()...)
+	if entry.Caller.Defined {
+		r.AddAttributes(
+			log.String(string(semconv.CodeFilePathKey), entry.Caller.File),
+			log.Int(string(semconv.CodeLineNumberKey), entry.Caller.Line),
+			log.String(string(semconv.CodeFunctionNameKey), entry.Caller.Function),
+		)
+	}
+	if entry.Stack != "" {
+		r.AddAttributes(log.String(string(semconv.CodeStacktraceKey), entry.Stack))
+	}
+	logconv.Logger(c.logger).Emit(ctx, r)
+	// The process may exit or panic after entries above the error level, so
+	// pending records are exported first, as they are by the cores of zap.
+	if entry.Level > zapcore.ErrorLevel {
+		return c.Sync()
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core. It exports the pending data of the plugin,
+// waiting at most a few seconds.
+func (c *Core) Sync() error {
+	return o.FlushWithTimeout()
+}
+
+// severities converts zap levels to log severities.
+//
+//nolint:gochecknoglobals
+var severities = logconv.Severities[zapcore.Level]{
+	zapcore.DebugLevel:  log.SeverityDebug,
+	zapcore.InfoLevel:   log.SeverityInfo,
+	zapcore.WarnLevel:   log.SeverityWarn,
+	zapcore.ErrorLevel:  log.SeverityError,
+	zapcore.DPanicLevel: log.SeverityFatal1,
+	zapcore.PanicLevel:  log.SeverityFatal2,
+	zapcore.FatalLevel:  log.SeverityFatal3,
+}
+
+var _ zapcore.Core = &Core{}
@@ -0,0 +1,125 @@
+package ldzap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logtest"
+)
+
+func newTestCore(enabler zapcore.LevelEnabler) (*Core, *logtest.RecordingExporter) {
+	recordingLogger, exporter := logtest.NewRecordingLogger()
+	core := NewCore(enabler)
+	core.logger = recordingLogger
+	return core, exporter
+}
+
+type address struct {
+	city string
+}
+
+func (a address) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("city", a.city)
+	return nil
+}
+
+func TestCore_ConvertsEntries(t *testing.T) {
+	core, exporter := newTestCore(zapcore.DebugLevel)
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	ctx, span := tracer.Start(context.Background(), "request")
+	defer span.End()
+
+	logger := zap.New(core).With(zap.String("service", "checkout"), Context(ctx))
+	logger.Warn("slow request",
+		zap.Int("rows", 3),
+		zap.Duration("duration", time.Second),
+		zap.Error(errors.New("timeout")),
+		zap.Object("address", address{city: "Oakland"}),
+		zap.Strings("tags", []string{"a", "b"}),
+		zap.Any("labels", map[string]int{"x": 1}),
+		zap.Namespace("db"),
+		zap.Bool("cached", false),
+	)
+
+	logtest.CheckRecords(t, exporter.Exported(), logtest.Expected{
+		Body:         "slow request",
+		Severity:     log.SeverityWarn,
+		SeverityText: "WARN",
+		SpanContext:  span.SpanContext(),
+		Attributes: map[string]log.Value{
+			"service":  log.StringValue("checkout"),
+			"rows":     log.Int64Value(3),
+			"duration": log.Int64Value(int64(time.Second)),
+			"error":    log.StringValue("timeout"),
+			"labels":   log.StringValue(`{"x":1}`),
+			"address":  log.MapValue(log.String("city", "Oakland")),
+			"tags":     log.SliceValue(log.StringValue("a"), log.StringValue("b")),
+			// The fields after the namespace are in it.
+			"db": log.MapValue(log.Bool("cached", false)),
+		},
+		Absent: []string{"context"},
+	})
+}
+
+func TestCore_Level(t *testing.T) {
+	core, exporter := newTestCore(zapcore.InfoLevel)
+	logger := zap.New(core)
+	logger.Debug("ignored")
+	logger.Error("recorded")
+
+	records := exporter.Exported()
+	if len(records) != 1 || records[0].Severity() != log.SeverityError {
+		t.Errorf("expected only the error to be recorded, got %d records", len(records))
+	}
+}
+
+func TestCore_WithDoesNotShareFields(t *testing.T) {
+	core, exporter := newTestCore(zapcore.InfoLevel)
+	base := zap.New(core).With(zap.String("a", "1"))
+	base.With(zap.String("b", "2")).Info("first")
+	base.With(zap.String("c", "3")).Info("second")
+
+	records := exporter.Exported()
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if attrs := logtest.RecordAttributes(records[1]); len(attrs) != 2 || attrs["c"].AsString() != "3" {
+		t.Errorf("expected the fields of the second logger only, got %v", attrs)
+	}
+}
+
+func TestCore_FlushesAboveErrorLevel(t *testing.T) {
+	core := NewCore(zapcore.DebugLevel)
+	write := func(level zapcore.Level) func() {
+		return func() {
+			if err := core.Write(zapcore.Entry{Level: level, Message: level.String()}, nil); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+		}
+	}
+	logtest.CheckFlushesFatal(t, write(zapcore.ErrorLevel), write(zapcore.PanicLevel))
+}
+
+func TestConvertLevel(t *testing.T) {
+	cases := map[zapcore.Level]log.Severity{
+		zapcore.DebugLevel:  log.SeverityDebug,
+		zapcore.InfoLevel:   log.SeverityInfo,
+		zapcore.WarnLevel:   log.SeverityWarn,
+		zapcore.ErrorLevel:  log.SeverityError,
+		zapcore.DPanicLevel: log.SeverityFatal1,
+		zapcore.PanicLevel:  log.SeverityFatal2,
+		zapcore.FatalLevel:  log.SeverityFatal3,
+	}
+	for level, expected := range cases {
+		if severity := severities.Convert(level); severity != expected {
+			t.Errorf("expected level %v to be severity %v, got %v", level, expected, severity)
+		}
+	}
+}
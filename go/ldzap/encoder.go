@@ -0,0 +1,280 @@
+package ldzap
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logconv"
+)
+
+// namespace is a namespace opened with OpenNamespace, and the fields which
+// were added before it was opened.
+type namespace struct {
+	key    string
+	fields []log.KeyValue
+}
+
+// objectEncoder is a zapcore.ObjectEncoder which converts fields to log
+// attributes.
+type objectEncoder struct {
+	fields []log.KeyValue
+	// The open namespaces, outermost first.
+	namespaces []namespace
+}
+
+func (e *objectEncoder) clone() *objectEncoder {
+	return &objectEncoder{
+		fields:     slices.Clip(e.fields),
+		namespaces: slices.Clip(e.namespaces),
+	}
+}
+
+// close closes the open namespaces, and returns the attributes. Namespaces
+// without fields are omitted.
+func (e *objectEncoder) close() []log.KeyValue {
+	fields := e.fields
+	for i := len(e.namespaces) - 1; i >= 0; i-- {
+		ns := e.namespaces[i]
+		if len(fields) == 0 {
+			fields = ns.fields
+		} else {
+			fields = append(slices.Clip(ns.fields), log.Map(ns.key, fields...))
+		}
+	}
+	return fields
+}
+
+func (e *objectEncoder) add(key string, value log.Value) {
+	e.fields = append(e.fields, log.KeyValue{Key: key, Value: value})
+}
+
+func (e *objectEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	arr := &arrayEncoder{}
+	err := marshaler.MarshalLogArray(arr)
+	e.add(key, log.SliceValue(arr.values...))
+	return err
+}
+
+func (e *objectEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	obj := &objectEncoder{}
+	err := marshaler.MarshalLogObject(obj)
+	e.add(key, log.MapValue(obj.close()...))
+	return err
+}
+
+func (e *objectEncoder) AddBinary(key string, value []byte) {
+	e.add(key, log.BytesValue(value))
+}
+
+func (e *objectEncoder) AddByteString(key string, value []byte) {
+	e.add(key, log.StringValue(string(value)))
+}
+
+func (e *objectEncoder) AddBool(key string, value bool) {
+	e.add(key, log.BoolValue(value))
+}
+
+func (e *objectEncoder) AddComplex128(key string, value complex128) {
+	e.add(key, log.StringValue(fmt.Sprint(value)))
+}
+
+func (e *objectEncoder) AddComplex64(key string, value complex64) {
+	e.add(key, log.StringValue(fmt.Sprint(value)))
+}
+
+func (e *objectEncoder) AddDuration(key string, value time.Duration) {
+	e.add(key, log.Int64Value(value.Nanoseconds()))
+}
+
+func (e *objectEncoder) AddFloat64(key string, value float64) {
+	e.add(key, log.Float64Value(value))
+}
+
+func (e *objectEncoder) AddFloat32(key string, value float32) {
+	e.add(key, log.Float64Value(float64(value)))
+}
+
+func (e *objectEncoder) AddInt(key string, value int) {
+	e.add(key, log.IntValue(value))
+}
+
+func (e *objectEncoder) AddInt64(key string, value int64) {
+	e.add(key, log.Int64Value(value))
+}
+
+func (e *objectEncoder) AddInt32(key string, value int32) {
+	e.add(key, log.Int64Value(int64(value)))
+}
+
+func (e *objectEncoder) AddInt16(key string, value int16) {
+	e.add(key, log.Int64Value(int64(value)))
+}
+
+func (e *objectEncoder) AddInt8(key string, value int8) {
+	e.add(key, log.Int64Value(int64(value)))
+}
+
+func (e *objectEncoder) AddString(key, value string) {
+	e.add(key, log.StringValue(value))
+}
+
+func (e *objectEncoder) AddTime(key string, value time.Time) {
+	e.add(key, log.StringValue(value.Format(time.RFC3339Nano)))
+}
+
+func (e *objectEncoder) AddUint(key string, value uint) {
+	e.add(key, logconv.Uint64Value(uint64(value)))
+}
+
+func (e *objectEncoder) AddUint64(key string, value uint64) {
+	e.add(key, logconv.Uint64Value(value))
+}
+
+func (e *objectEncoder) AddUint32(key string, value uint32) {
+	e.add(key, log.Int64Value(int64(value)))
+}
+
+func (e *objectEncoder) AddUint16(key string, value uint16) {
+	e.add(key, log.Int64Value(int64(value)))
+}
+
+func (e *objectEncoder) AddUint8(key string, value uint8) {
+	e.add(key, log.Int64Value(int64(value)))
+}
+
+func (e *objectEncoder) AddUintptr(key string, value uintptr) {
+	e.add(key, logconv.Uint64Value(uint64(value)))
+}
+
+func (e *objectEncoder) AddReflected(key string, value any) error {
+	e.add(key, reflectedValue(value))
+	return nil
+}
+
+func (e *objectEncoder) OpenNamespace(key string) {
+	e.namespaces = append(e.namespaces, namespace{key: key, fields: e.fields})
+	e.fields = nil
+}
+
+// arrayEncoder is a zapcore.ArrayEncoder which converts elements to log
+// values.
+type arrayEncoder struct {
+	values []log.Value
+}
+
+func (a *arrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	arr := &arrayEncoder{}
+	err := marshaler.MarshalLogArray(arr)
+	a.values = append(a.values, log.SliceValue(arr.values...))
+	return err
+}
+
+func (a *arrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	obj := &objectEncoder{}
+	err := marshaler.MarshalLogObject(obj)
+	a.values = append(a.values, log.MapValue(obj.close()...))
+	return err
+}
+
+func (a *arrayEncoder) AppendReflected(value any) error {
+	a.values = append(a.values, reflectedValue(value))
+	return nil
+}
+
+func (a *arrayEncoder) AppendBool(value bool) {
+	a.values = append(a.values, log.BoolValue(value))
+}
+
+func (a *arrayEncoder) AppendByteString(value []byte) {
+	a.values = append(a.values, log.StringValue(string(value)))
+}
+
+func (a *arrayEncoder) AppendComplex128(value complex128) {
+	a.values = append(a.values, log.StringValue(fmt.Sprint(value)))
+}
+
+func (a *arrayEncoder) AppendComplex64(value complex64) {
+	a.values = append(a.values, log.StringValue(fmt.Sprint(value)))
+}
+
+func (a *arrayEncoder) AppendDuration(value time.Duration) {
+	a.values = append(a.values, log.Int64Value(value.Nanoseconds()))
+}
+
+func (a *arrayEncoder) AppendFloat64(value float64) {
+	a.values = append(a.values, log.Float64Value(value))
+}
+
+func (a *arrayEncoder) AppendFloat32(value float32) {
+	a.values = append(a.values, log.Float64Value(float64(value)))
+}
+
+func (a *arrayEncoder) AppendInt(value int) {
+	a.values = append(a.values, log.IntValue(value))
+}
+
+func (a *arrayEncoder) AppendInt64(value int64) {
+	a.values = append(a.values, log.Int64Value(value))
+}
+
+func (a *arrayEncoder) AppendInt32(value int32) {
+	a.values = append(a.values, log.Int64Value(int64(value)))
+}
+
+func (a *arrayEncoder) AppendInt16(value int16) {
+	a.values = append(a.values, log.Int64Value(int64(value)))
+}
+
+func (a *arrayEncoder) AppendInt8(value int8) {
+	a.values = append(a.values, log.Int64Value(int64(value)))
+}
+
+func (a *arrayEncoder) AppendString(value string) {
+	a.values = append(a.values, log.StringValue(value))
+}
+
+func (a *arrayEncoder) AppendTime(value time.Time) {
+	a.values = append(a.values, log.StringValue(value.Format(time.RFC3339Nano)))
+}
+
+func (a *arrayEncoder) AppendUint(value uint) {
+	a.values = append(a.values, logconv.Uint64Value(uint64(value)))
+}
+
+func (a *arrayEncoder) AppendUint64(value uint64) {
+	a.values = append(a.values, logconv.Uint64Value(value))
+}
+
+func (a *arrayEncoder) AppendUint32(value uint32) {
+	a.values = append(a.values, log.Int64Value(int64(value)))
+}
+
+func (a *arrayEncoder) AppendUint16(value uint16) {
+	a.values = append(a.values, log.Int64Value(int64(value)))
+}
+
+func (a *arrayEncoder) AppendUint8(value uint8) {
+	a.values = append(a.values, log.Int64Value(int64(value)))
+}
+
+func (a *arrayEncoder) AppendUintptr(value uintptr) {
+	a.values = append(a.values, logconv.Uint64Value(uint64(value)))
+}
+
+// reflectedValue converts a value which zap does not have a type for to a
+// string, which is its JSON encoding when it has one, as it is for the JSON
+// encoder of zap.
+func reflectedValue(value any) log.Value {
+	if data, err := json.Marshal(value); err == nil {
+		return log.StringValue(string(data))
+	}
+	return log.StringValue(fmt.Sprintf("%+v", value))
+}
+
+var _ zapcore.ObjectEncoder = &objectEncoder{}
+var _ zapcore.ArrayEncoder = &arrayEncoder{}
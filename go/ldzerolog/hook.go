@@ -0,0 +1,42 @@
+// Package ldzerolog provides a zerolog writer which records logs with the
+// LaunchDarkly observability plugin.
+//
+// The writer parses the JSON events written by zerolog, so that zerolog does
+// not allocate more than it does when it writes to any other writer. To
+// associate the records with the span in the context of an event, also add
+// Hook to the logger:
+//
+//	logger := zerolog.New(ldzerolog.NewWriter(os.Stdout)).Hook(ldzerolog.Hook{})
+//	logger.Info().Ctx(ctx).Msg("request handled")
+package ldzerolog
+
+import (
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The fields Hook adds to events, which Writer reads the span context of a
+// record from.
+const (
+	TraceIDFieldName    = "trace_id"
+	SpanIDFieldName     = "span_id"
+	TraceFlagsFieldName = "trace_flags"
+)
+
+// Hook is a zerolog.Hook which adds the IDs of the span in the context of an
+// event, set with Event.Ctx, as fields. Writer uses the fields to associate
+// the record with the span, and other writers also receive the fields.
+type Hook struct{}
+
+// Run implements zerolog.Hook.
+func (Hook) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	spanContext := trace.SpanContextFromContext(e.GetCtx())
+	if !spanContext.IsValid() {
+		return
+	}
+	e.Str(TraceIDFieldName, spanContext.TraceID().String()).
+		Str(SpanIDFieldName, spanContext.SpanID().String()).
+		Str(TraceFlagsFieldName, spanContext.TraceFlags().String())
+}
+
+var _ zerolog.Hook = Hook{}
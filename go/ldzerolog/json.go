@@ -0,0 +1,251 @@
+package ldzerolog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+var errInvalidJSON = errors.New("invalid JSON event")
+
+// scanner reads the fields of a JSON object one at a time. Values are not
+// decoded while scanning, so that the fields which are not recorded do not
+// allocate.
+type scanner struct {
+	data   []byte
+	pos    int
+	fields int
+}
+
+func (s *scanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\r', '\n':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume reads the given character, after any whitespace.
+func (s *scanner) consume(c byte) bool {
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// begin reads the start of the object.
+func (s *scanner) begin() error {
+	if !s.consume('{') {
+		return errInvalidJSON
+	}
+	return nil
+}
+
+// next reads the next field of the object, and returns its key and its
+// encoded value. It returns false at the end of the object.
+func (s *scanner) next() (key []byte, value []byte, ok bool, err error) {
+	if s.consume('}') {
+		return nil, nil, false, nil
+	}
+	if s.fields > 0 && !s.consume(',') {
+		return nil, nil, false, errInvalidJSON
+	}
+	s.fields++
+	s.skipSpace()
+	key, err = s.value()
+	if err != nil || len(key) == 0 || key[0] != '"' || !s.consume(':') {
+		return nil, nil, false, errInvalidJSON
+	}
+	s.skipSpace()
+	value, err = s.value()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return key, value, true, nil
+}
+
+// value reads an encoded value.
+func (s *scanner) value() ([]byte, error) {
+	start := s.pos
+	if start >= len(s.data) {
+		return nil, errInvalidJSON
+	}
+	switch s.data[start] {
+	case '"':
+		if err := s.skipString(); err != nil {
+			return nil, err
+		}
+	case '{', '[':
+		depth := 0
+		for s.pos < len(s.data) {
+			switch s.data[s.pos] {
+			case '"':
+				if err := s.skipString(); err != nil {
+					return nil, err
+				}
+				continue
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+			s.pos++
+			if depth == 0 {
+				return s.data[start:s.pos], nil
+			}
+		}
+		return nil, errInvalidJSON
+	default:
+		for s.pos < len(s.data) {
+			switch s.data[s.pos] {
+			case ',', '}', ']', ' ', '\t', '\r', '\n':
+				return s.data[start:s.pos], nil
+			}
+			s.pos++
+		}
+	}
+	return s.data[start:s.pos], nil
+}
+
+// skipString reads a string, including its quotes.
+func (s *scanner) skipString() error {
+	s.pos++
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos += 2
+			continue
+		case '"':
+			s.pos++
+			return nil
+		}
+		s.pos++
+	}
+	return errInvalidJSON
+}
+
+// decodeString decodes an encoded string. Strings without escapes, which are
+// most strings, are not decoded by the JSON package.
+func decodeString(value []byte) (string, bool) {
+	if len(value) < 2 || value[0] != '"' {
+		return "", false
+	}
+	if bytes.IndexByte(value, '\\') < 0 {
+		return string(value[1 : len(value)-1]), true
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// decodeInt decodes an encoded integer.
+func decodeInt(value []byte) (int64, bool) {
+	// Integers which cannot overflow are decoded without converting the value
+	// to a string.
+	if len(value) > 0 && len(value) < 19 {
+		negative := value[0] == '-'
+		digits := value
+		if negative {
+			digits = value[1:]
+		}
+		var n int64
+		for _, c := range digits {
+			if c < '0' || c > '9' {
+				return 0, false
+			}
+			n = n*10 + int64(c-'0')
+		}
+		if len(digits) == 0 {
+			return 0, false
+		}
+		if negative {
+			n = -n
+		}
+		return n, true
+	}
+	n, err := strconv.ParseInt(string(value), 10, 64)
+	return n, err == nil
+}
+
+// decodeValue converts an encoded value to a log value.
+func decodeValue(value []byte) log.Value {
+	if len(value) == 0 {
+		return log.Value{}
+	}
+	switch value[0] {
+	case '"':
+		if s, ok := decodeString(value); ok {
+			return log.StringValue(s)
+		}
+	case 't', 'f':
+		if string(value) == "true" || string(value) == "false" {
+			return log.BoolValue(value[0] == 't')
+		}
+	case 'n':
+		return log.Value{}
+	case '{', '[':
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+		var v any
+		if err := decoder.Decode(&v); err == nil {
+			return convertJSON(v)
+		}
+	default:
+		if n, ok := decodeInt(value); ok {
+			return log.Int64Value(n)
+		}
+		if f, err := strconv.ParseFloat(string(value), 64); err == nil {
+			return log.Float64Value(f)
+		}
+	}
+	return log.StringValue(string(value))
+}
+
+// convertJSON converts a value decoded by the JSON package to a log value.
+func convertJSON(v any) log.Value {
+	switch v := v.(type) {
+	case string:
+		return log.StringValue(v)
+	case bool:
+		return log.BoolValue(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return log.Int64Value(n)
+		}
+		if f, err := v.Float64(); err == nil {
+			return log.Float64Value(f)
+		}
+		return log.StringValue(v.String())
+	case []any:
+		values := make([]log.Value, 0, len(v))
+		for _, item := range v {
+			values = append(values, convertJSON(item))
+		}
+		return log.SliceValue(values...)
+	case map[string]any:
+		// Maps are not ordered, so the keys are sorted to keep the attributes
+		// in the same order for every record.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		kvs := make([]log.KeyValue, 0, len(v))
+		for _, key := range keys {
+			kvs = append(kvs, log.KeyValue{Key: key, Value: convertJSON(v[key])})
+		}
+		return log.MapValue(kvs...)
+	}
+	return log.Value{}
+}
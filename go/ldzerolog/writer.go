@@ -0,0 +1,213 @@
+package ldzerolog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logconv"
+	o "github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// Writer is a zerolog.LevelWriter which records the events zerolog writes
+// with the observability plugin. The level, message, time, caller, error and
+// error stack of an event are converted to the fields of the record of the
+// same meaning, and the other fields of the event are converted to
+// attributes. Records are sampled by the sampling configuration of the plugin
+// like any other logs. Pending records are exported after fatal and panic
+// events, before zerolog exits or panics.
+type Writer struct {
+	next io.Writer
+	// The logger of the plugin is used when it is nil.
+	logger log.Logger
+}
+
+// NewWriter creates a writer which records events with the observability
+// plugin, and also writes them to next, when it is not nil, so that existing
+// logging is unchanged.
+func NewWriter(next io.Writer) *Writer {
+	return &Writer{next: next}
+}
+
+// The attributes of a record are built in a buffer, which the record copies.
+var attributesPool = sync.Pool{ //nolint:gochecknoglobals
+	New: func() any {
+		attrs := make([]log.KeyValue, 0, 16)
+		return &attrs
+	},
+}
+
+// Write implements io.Writer. The level of the event is read from its level
+// field.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.record(p, zerolog.NoLevel, false)
+	if w.next == nil {
+		return len(p), nil
+	}
+	return w.next.Write(p)
+}
+
+// WriteLevel implements zerolog.LevelWriter.
+func (w *Writer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	w.record(p, level, true)
+	if w.next == nil {
+		return len(p), nil
+	}
+	if next, ok := w.next.(zerolog.LevelWriter); ok {
+		return next.WriteLevel(level, p)
+	}
+	return w.next.Write(p)
+}
+
+// record converts an event to a record, and emits it. Events which are not
+// valid JSON are recorded with the event as the body.
+func (w *Writer) record(p []byte, level zerolog.Level, hasLevel bool) {
+	logger := logconv.Logger(w.logger)
+	if hasLevel && !logger.Enabled(context.Background(), log.EnabledParameters{Severity: severities.Convert(level)}) {
+		return
+	}
+
+	attrs := attributesPool.Get().(*[]log.KeyValue)
+	defer func() {
+		clear(*attrs)
+		*attrs = (*attrs)[:0]
+		attributesPool.Put(attrs)
+	}()
+
+	var r log.Record
+	var traceConfig trace.SpanContextConfig
+	s := scanner{data: p}
+	err := s.begin()
+	for err == nil {
+		var key, value []byte
+		var ok bool
+		key, value, ok, err = s.next()
+		if !ok {
+			break
+		}
+		name := key[1 : len(key)-1]
+		if bytes.IndexByte(name, '\\') >= 0 {
+			// The escapes of a key are decoded before it is compared with
+			// the names of the fields zerolog adds.
+			decoded, _ := decodeString(key)
+			name = []byte(decoded)
+		}
+		switch string(name) {
+		case zerolog.LevelFieldName:
+			if !hasLevel {
+				text, _ := decodeString(value)
+				level, _ = zerolog.ParseLevel(text)
+			}
+		case zerolog.MessageFieldName:
+			r.SetBody(decodeValue(value))
+		case zerolog.TimestampFieldName:
+			r.SetTimestamp(decodeTime(value))
+		case zerolog.CallerFieldName:
+			caller, _ := decodeString(value)
+			if i := strings.LastIndexByte(caller, ':'); i >= 0 {
+				line, _ := decodeInt([]byte(caller[i+1:]))
+				*attrs = append(*attrs,
+					log.String(string(semconv.CodeFilePathKey), caller[:i]),
+					log.Int64(string(semconv.CodeLineNumberKey), line),
+				)
+			}
+		case zerolog.ErrorFieldName:
+			*attrs = append(*attrs, log.KeyValue{Key: string(semconv.ExceptionMessageKey), Value: decodeValue(value)})
+		case zerolog.ErrorStackFieldName:
+			stack, ok := decodeString(value)
+			if !ok {
+				stack = string(value)
+			}
+			*attrs = append(*attrs, log.String(string(semconv.ExceptionStacktraceKey), stack))
+		case TraceIDFieldName:
+			text, _ := decodeString(value)
+			traceConfig.TraceID, _ = trace.TraceIDFromHex(text)
+		case SpanIDFieldName:
+			text, _ := decodeString(value)
+			traceConfig.SpanID, _ = trace.SpanIDFromHex(text)
+		case TraceFlagsFieldName:
+			if text, _ := decodeString(value); text == "01" {
+				traceConfig.TraceFlags = trace.FlagsSampled
+			}
+		default:
+			decoded, _ := decodeString(key)
+			*attrs = append(*attrs, log.KeyValue{Key: decoded, Value: decodeValue(value)})
+		}
+	}
+	if err != nil {
+		clear(*attrs)
+		*attrs = (*attrs)[:0]
+		r = log.Record{}
+		r.SetBody(log.StringValue(strings.TrimSpace(string(p))))
+	}
+
+	severity := severities.Convert(level)
+	if !hasLevel && !logger.Enabled(context.Background(), log.EnabledParameters{Severity: severity}) {
+		return
+	}
+	r.SetSeverity(severity)
+	if level != zerolog.NoLevel {
+		r.SetSeverityText(level.String())
+	}
+	r.AddAttributes(*attrs...)
+
+	ctx := context.Background()
+	if spanContext := trace.NewSpanContext(traceConfig); spanContext.IsValid() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, spanContext)
+	}
+	logger.Emit(ctx, r)
+	// zerolog exits or panics after writing fatal and panic events, so pending
+	// records are exported first.
+	if level == zerolog.FatalLevel || level == zerolog.PanicLevel {
+		_ = o.FlushWithTimeout()
+	}
+}
+
+// decodeTime decodes the time of an event, which is formatted as configured
+// by zerolog.TimeFieldFormat.
+func decodeTime(value []byte) time.Time {
+	if n, ok := decodeInt(value); ok {
+		switch zerolog.TimeFieldFormat {
+		case zerolog.TimeFormatUnixMs:
+			return time.UnixMilli(n)
+		case zerolog.TimeFormatUnixMicro:
+			return time.UnixMicro(n)
+		case zerolog.TimeFormatUnixNano:
+			return time.Unix(0, n)
+		default:
+			return time.Unix(n, 0)
+		}
+	}
+	text, ok := decodeString(value)
+	if !ok {
+		return time.Time{}
+	}
+	if t, err := time.Parse(zerolog.TimeFieldFormat, text); err == nil {
+		return t
+	}
+	t, _ := time.Parse(time.RFC3339Nano, text)
+	return t
+}
+
+// severities converts zerolog levels to log severities.
+//
+//nolint:gochecknoglobals
+var severities = logconv.Severities[zerolog.Level]{
+	zerolog.TraceLevel: log.SeverityTrace,
+	zerolog.DebugLevel: log.SeverityDebug,
+	zerolog.InfoLevel:  log.SeverityInfo,
+	zerolog.WarnLevel:  log.SeverityWarn,
+	zerolog.ErrorLevel: log.SeverityError,
+	zerolog.FatalLevel: log.SeverityFatal1,
+	zerolog.PanicLevel: log.SeverityFatal2,
+}
+
+var _ zerolog.LevelWriter = &Writer{}
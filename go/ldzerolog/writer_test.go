@@ -0,0 +1,210 @@
+package ldzerolog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/log/noop"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logtest"
+)
+
+func TestWriter_ConvertsEvents(t *testing.T) {
+	recordingLogger, exporter := logtest.NewRecordingLogger()
+	var stdout bytes.Buffer
+	writer := NewWriter(&stdout)
+	writer.logger = recordingLogger
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	ctx, span := tracer.Start(context.Background(), "request")
+	defer span.End()
+
+	logger := zerolog.New(writer).Hook(Hook{}).With().Timestamp().Caller().Str("service", "checkout").Logger()
+	logger.Warn().Ctx(ctx).
+		Err(errors.New("timeout")).
+		Int("rows", 3).
+		Float64("ratio", 0.5).
+		Bool("cached", false).
+		Str("quoted", "a \"quoted\" value").
+		Dict("address", zerolog.Dict().Str("city", "Oakland")).
+		Strs("tags", []string{"a", "b"}).
+		Msg("slow request")
+
+	records := exporter.Exported()
+	logtest.CheckRecords(t, records, logtest.Expected{
+		Body:         "slow request",
+		Severity:     log.SeverityWarn,
+		SeverityText: "warn",
+		SpanContext:  span.SpanContext(),
+		Attributes: map[string]log.Value{
+			"service":           log.StringValue("checkout"),
+			"rows":              log.Int64Value(3),
+			"ratio":             log.Float64Value(0.5),
+			"cached":            log.BoolValue(false),
+			"quoted":            log.StringValue(`a "quoted" value`),
+			"exception.message": log.StringValue("timeout"),
+			"address":           log.MapValue(log.String("city", "Oakland")),
+			"tags":              log.SliceValue(log.StringValue("a"), log.StringValue("b")),
+		},
+		Absent: []string{"level", "message", "time", TraceIDFieldName, SpanIDFieldName},
+	})
+	if time.Since(records[0].Timestamp()) > time.Minute {
+		t.Errorf("expected the time of the event, got %v", records[0].Timestamp())
+	}
+	attrs := logtest.RecordAttributes(records[0])
+	if attrs["code.file.path"].AsString() == "" || attrs["code.line.number"].AsInt64() == 0 {
+		t.Errorf("expected the caller to be converted, got %v", attrs)
+	}
+	if !bytes.Contains(stdout.Bytes(), []byte(`"message":"slow request"`)) {
+		t.Errorf("expected the event to be written to the next writer, got %q", stdout.String())
+	}
+}
+
+func TestWriter_Write(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    string
+		expected logtest.Expected
+	}{
+		{
+			name:  "event",
+			event: `{"level":"error","time":"2025-01-02T03:04:05Z","message":"failed"}`,
+			expected: logtest.Expected{
+				Body:      "failed",
+				Severity:  log.SeverityError,
+				Timestamp: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+				Absent:    []string{"level", "time", "message"},
+			},
+		},
+		{
+			name:     "not JSON",
+			event:    `not json`,
+			expected: logtest.Expected{Body: "not json"},
+		},
+		{
+			name:     "not an object",
+			event:    `["level","error"]`,
+			expected: logtest.Expected{Body: `["level","error"]`},
+		},
+		{
+			// The fields read before the event ends are not recorded.
+			name:  "truncated",
+			event: `{"level":"error","service":"checkout","message":"fail`,
+			expected: logtest.Expected{
+				Body:   `{"level":"error","service":"checkout","message":"fail`,
+				Absent: []string{"service"},
+			},
+		},
+		{
+			name:  "escaped keys",
+			event: `{"\u006cevel":"warn","a\"b":1,"tab\tkey":true,"caf\u00e9":"x","\u006dessage":"escaped"}`,
+			expected: logtest.Expected{
+				Body:     "escaped",
+				Severity: log.SeverityWarn,
+				Attributes: map[string]log.Value{
+					`a"b`:      log.Int64Value(1),
+					"tab\tkey": log.BoolValue(true),
+					"café":     log.StringValue("x"),
+				},
+				Absent: []string{"level", "message"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recordingLogger, exporter := logtest.NewRecordingLogger()
+			writer := NewWriter(nil)
+			writer.logger = recordingLogger
+
+			if n, err := writer.Write([]byte(tt.event + "\n")); err != nil || n != len(tt.event)+1 {
+				t.Fatalf("write failed: %d %v", n, err)
+			}
+			logtest.CheckRecords(t, exporter.Exported(), tt.expected)
+		})
+	}
+}
+
+func TestWriter_TimeFormats(t *testing.T) {
+	eventTime := time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	tests := []struct {
+		format   string
+		expected time.Time
+	}{
+		{format: zerolog.TimeFormatUnix, expected: eventTime.Truncate(time.Second)},
+		{format: zerolog.TimeFormatUnixMs, expected: eventTime.Truncate(time.Millisecond)},
+		{format: zerolog.TimeFormatUnixMicro, expected: eventTime.Truncate(time.Microsecond)},
+		{format: zerolog.TimeFormatUnixNano, expected: eventTime},
+		{format: time.RFC3339Nano, expected: eventTime},
+		{format: "2006-01-02 15:04:05.000", expected: eventTime.Truncate(time.Millisecond)},
+	}
+	defaultFormat, defaultTimestamp := zerolog.TimeFieldFormat, zerolog.TimestampFunc
+	t.Cleanup(func() {
+		zerolog.TimeFieldFormat, zerolog.TimestampFunc = defaultFormat, defaultTimestamp
+	})
+	zerolog.TimestampFunc = func() time.Time { return eventTime }
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			zerolog.TimeFieldFormat = tt.format
+			recordingLogger, exporter := logtest.NewRecordingLogger()
+			writer := NewWriter(nil)
+			writer.logger = recordingLogger
+
+			logger := zerolog.New(writer).With().Timestamp().Logger()
+			logger.Info().Msg("timed")
+			logtest.CheckRecords(t, exporter.Exported(), logtest.Expected{Timestamp: tt.expected})
+		})
+	}
+}
+
+func TestWriter_FlushesFatalAndPanicEvents(t *testing.T) {
+	logger := zerolog.New(NewWriter(nil))
+	logtest.CheckFlushesFatal(t,
+		func() { logger.Error().Msg("failed") },
+		func() { logger.WithLevel(zerolog.PanicLevel).Msg("panicked") },
+	)
+}
+
+func TestWriter_DisabledDoesNotAllocate(t *testing.T) {
+	writer := NewWriter(nil)
+	writer.logger = noop.NewLoggerProvider().Logger("test")
+	event := []byte(`{"level":"info","service":"checkout","message":"request handled"}` + "\n")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = writer.WriteLevel(zerolog.InfoLevel, event)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations when logs are not recorded, got %v", allocs)
+	}
+}
+
+func TestScanner(t *testing.T) {
+	s := scanner{data: []byte(` { "a" : [1, {"b": "]"}] , "c":"\"}" ,"d":-12}`)}
+	if err := s.begin(); err != nil {
+		t.Fatalf("begin failed: %v", err)
+	}
+	var fields []string
+	for {
+		key, value, ok, err := s.next()
+		if err != nil {
+			t.Fatalf("next failed: %v", err)
+		}
+		if !ok {
+			break
+		}
+		fields = append(fields, string(key)+"="+string(value))
+	}
+	expected := []string{`"a"=[1, {"b": "]"}]`, `"c"="\"}"`, `"d"=-12`}
+	if len(fields) != len(expected) {
+		t.Fatalf("expected fields %v, got %v", expected, fields)
+	}
+	for i := range expected {
+		if fields[i] != expected[i] {
+			t.Errorf("expected field %s, got %s", expected[i], fields[i])
+		}
+	}
+}
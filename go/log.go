@@ -0,0 +1,146 @@
+package ldobserve
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+
+	o "github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// exceptionAttributeKey is the key of the attribute created by Err. Records
+// replace it with the exception attributes it holds.
+const exceptionAttributeKey = "exception"
+
+// errMarkerKey is the key of the first attribute of the maps created by Err,
+// which tells them apart from maps of the application with the same key. It is
+// not added to records.
+const errMarkerKey = "ldobserve.err"
+
+// Debug records a log of severity debug, with the message as its body.
+func Debug(ctx context.Context, msg string, attrs ...log.KeyValue) {
+	emitLog(ctx, o.GetLogger(), log.SeverityDebug, msg, attrs)
+}
+
+// Info records a log of severity info, with the message as its body.
+func Info(ctx context.Context, msg string, attrs ...log.KeyValue) {
+	emitLog(ctx, o.GetLogger(), log.SeverityInfo, msg, attrs)
+}
+
+// Warn records a log of severity warn, with the message as its body.
+func Warn(ctx context.Context, msg string, attrs ...log.KeyValue) {
+	emitLog(ctx, o.GetLogger(), log.SeverityWarn, msg, attrs)
+}
+
+// Error records a log of severity error, with the message as its body. Use
+// Err to add the error which is logged as an attribute.
+//
+//	ldobserve.Error(ctx, "failed to charge card", ldobserve.Err(err), log.String("order", id))
+func Error(ctx context.Context, msg string, attrs ...log.KeyValue) {
+	emitLog(ctx, o.GetLogger(), log.SeverityError, msg, attrs)
+}
+
+// Debugf records a log of severity debug, with the formatted message as its
+// body.
+func Debugf(ctx context.Context, format string, args ...any) {
+	emitLog(ctx, o.GetLogger(), log.SeverityDebug, fmt.Sprintf(format, args...), nil)
+}
+
+// Infof records a log of severity info, with the formatted message as its
+// body.
+func Infof(ctx context.Context, format string, args ...any) {
+	emitLog(ctx, o.GetLogger(), log.SeverityInfo, fmt.Sprintf(format, args...), nil)
+}
+
+// Warnf records a log of severity warn, with the formatted message as its
+// body.
+func Warnf(ctx context.Context, format string, args ...any) {
+	emitLog(ctx, o.GetLogger(), log.SeverityWarn, fmt.Sprintf(format, args...), nil)
+}
+
+// Errorf records a log of severity error, with the formatted message as its
+// body.
+func Errorf(ctx context.Context, format string, args ...any) {
+	emitLog(ctx, o.GetLogger(), log.SeverityError, fmt.Sprintf(format, args...), nil)
+}
+
+// Err returns an attribute describing an error, for the logging functions such
+// as Error and RecordLog. Records have the type, message and stack trace of the
+// error as the exception attributes, so the error is shown with its stack
+// trace. The stack trace is the one the error carries, when it was created
+// with a stack, or else the stack of the caller.
+func Err(err error) log.KeyValue {
+	if err == nil {
+		return log.KeyValue{}
+	}
+	attrs := make([]log.KeyValue, 0, 5)
+	attrs = append(attrs,
+		log.Bool(errMarkerKey, true),
+		log.String(string(semconv.ExceptionTypeKey), reflect.TypeOf(err).String()),
+		log.String(string(semconv.ExceptionMessageKey), err.Error()),
+	)
+	var frames []structuredFrame
+	if stack, ok := stackTraceOf(err); ok {
+		attrs = append(attrs, log.String(string(semconv.ExceptionStacktraceKey), fmt.Sprintf("%+v", stack)))
+		frames = structuredFrames(callersFromStackTrace(stack), err.Error())
+	} else {
+		frames = framesAtRecordTime(err.Error())
+	}
+	if structured, ok := structuredStacktraceAttribute(frames); ok {
+		attrs = append(attrs, log.String(string(structured.Key), structured.Value.AsString()))
+	}
+	return log.Map(exceptionAttributeKey, attrs...)
+}
+
+func emitLog(ctx context.Context, logger log.Logger, severity log.Severity, msg string, attrs []log.KeyValue) {
+	if !logger.Enabled(ctx, log.EnabledParameters{Severity: severity}) {
+		return
+	}
+	logger.Emit(ctx, newLogRecord(time.Now(), severity, msg, attrs))
+}
+
+// emitRecord emits a record with the tags added to its attributes.
+func emitRecord(ctx context.Context, logger log.Logger, record log.Record, tags []log.KeyValue) {
+	addLogAttributes(&record, tags)
+	logger.Emit(ctx, record)
+}
+
+// newLogRecord creates a record which is observed when it occurs.
+func newLogRecord(now time.Time, severity log.Severity, msg string, attrs []log.KeyValue) log.Record {
+	var record log.Record
+	record.SetTimestamp(now)
+	record.SetObservedTimestamp(now)
+	record.SetSeverity(severity)
+	record.SetSeverityText(severity.String())
+	record.SetBody(log.StringValue(msg))
+	addLogAttributes(&record, attrs)
+	return record
+}
+
+// addLogAttributes adds attributes to a record. Attributes created by Err are
+// replaced by the exception attributes they hold, and empty attributes are
+// dropped.
+func addLogAttributes(record *log.Record, attrs []log.KeyValue) {
+	for _, attr := range attrs {
+		switch {
+		case attr.Key == "" && attr.Value.Empty():
+		case isErrAttribute(attr):
+			record.AddAttributes(attr.Value.AsMap()[1:]...)
+		default:
+			record.AddAttributes(attr)
+		}
+	}
+}
+
+// isErrAttribute reports whether an attribute was created by Err.
+func isErrAttribute(attr log.KeyValue) bool {
+	if attr.Key != exceptionAttributeKey || attr.Value.Kind() != log.KindMap {
+		return false
+	}
+	attrs := attr.Value.AsMap()
+	return len(attrs) > 0 && attrs[0].Key == errMarkerKey
+}
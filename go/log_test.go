@@ -0,0 +1,163 @@
+package ldobserve
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"go.opentelemetry.io/otel/log"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logtest"
+	o "github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// warnLogger is enabled for records of severity warn and above.
+type warnLogger struct {
+	log.Logger
+}
+
+func (warnLogger) Enabled(_ context.Context, param log.EnabledParameters) bool {
+	return param.Severity >= log.SeverityWarn
+}
+
+func logAttributes(record log.Record) map[string]log.Value {
+	attrs := map[string]log.Value{}
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	return attrs
+}
+
+func TestNewLogRecord(t *testing.T) {
+	now := time.Unix(1000, 0)
+	record := newLogRecord(now, log.SeverityWarn, "slow request", []log.KeyValue{
+		log.String("route", "/checkout"),
+		log.Int("rows", 3),
+	})
+
+	if !record.Timestamp().Equal(now) || !record.ObservedTimestamp().Equal(now) {
+		t.Errorf("expected the record to be observed when it occurs, got %v and %v",
+			record.Timestamp(), record.ObservedTimestamp())
+	}
+	if record.Severity() != log.SeverityWarn || record.SeverityText() != "WARN" {
+		t.Errorf("expected severity WARN, got %v %q", record.Severity(), record.SeverityText())
+	}
+	if record.Body().AsString() != "slow request" {
+		t.Errorf("expected the message as the body, got %v", record.Body())
+	}
+	if attrs := logAttributes(record); attrs["route"].AsString() != "/checkout" || attrs["rows"].AsInt64() != 3 {
+		t.Errorf("expected the attributes to be added, got %v", attrs)
+	}
+}
+
+func TestErr_CarriedStack(t *testing.T) {
+	record := newLogRecord(time.Now(), log.SeverityError, "failed", []log.KeyValue{
+		Err(pkgerrors.New("card declined")),
+		log.String("order", "1"),
+	})
+
+	attrs := logAttributes(record)
+	if attrs["exception.message"].AsString() != "card declined" || attrs["exception.type"].AsString() == "" {
+		t.Errorf("expected the exception attributes, got %v", attrs)
+	}
+	if attrs["exception.stacktrace"].AsString() == "" || attrs[exceptionStructuredStacktraceKey].AsString() == "" {
+		t.Errorf("expected the stack trace the error carries, got %v", attrs)
+	}
+	if _, ok := attrs[exceptionAttributeKey]; ok || attrs["order"].AsString() != "1" {
+		t.Errorf("expected the error attribute to be replaced by the exception attributes, got %v", attrs)
+	}
+}
+
+func TestErr_Nil(t *testing.T) {
+	var record log.Record
+	addLogAttributes(&record, []log.KeyValue{Err(nil)})
+	if record.AttributesLen() != 0 {
+		t.Errorf("expected a nil error not to add attributes, got %d", record.AttributesLen())
+	}
+}
+
+func TestAddLogAttributes_KeepsOtherMaps(t *testing.T) {
+	var record log.Record
+	addLogAttributes(&record, []log.KeyValue{
+		Err(errors.New("timeout")),
+		log.Map(exceptionAttributeKey, log.String("reason", "custom")),
+		log.Map("cause", log.String("exception.message", "custom")),
+	})
+
+	attrs := logAttributes(record)
+	if attrs["exception.message"].AsString() != "timeout" {
+		t.Errorf("expected the exception attributes, got %v", attrs)
+	}
+	if attrs[exceptionAttributeKey].Kind() != log.KindMap || attrs["cause"].Kind() != log.KindMap {
+		t.Errorf("expected the maps which Err did not create to be kept, got %v", attrs)
+	}
+	if _, ok := attrs[errMarkerKey]; ok {
+		t.Errorf("expected the marker of Err not to be added, got %v", attrs)
+	}
+}
+
+func TestEmitLog_ChecksTheLoggerIsEnabled(t *testing.T) {
+	ctx := context.Background()
+	logger, exporter := logtest.NewRecordingLogger()
+	logger = warnLogger{Logger: logger}
+
+	emitLog(ctx, logger, log.SeverityInfo, "skipped", nil)
+	emitLog(ctx, logger, log.SeverityError, "failed", []log.KeyValue{log.String("order", "1")})
+
+	records := exporter.Exported()
+	if len(records) != 1 {
+		t.Fatalf("expected only the enabled severity to be emitted, got %d records", len(records))
+	}
+	if records[0].Body().AsString() != "failed" || records[0].Severity() != log.SeverityError {
+		t.Errorf("expected the error to be emitted, got %v %v", records[0].Severity(), records[0].Body())
+	}
+	if attrs := logtest.RecordAttributes(records[0]); attrs["order"].AsString() != "1" {
+		t.Errorf("expected the attributes to be added, got %v", attrs)
+	}
+}
+
+func TestEmitRecord_AddsTags(t *testing.T) {
+	logger, exporter := logtest.NewRecordingLogger()
+	var record log.Record
+	record.SetBody(log.StringValue("checkout"))
+	record.AddAttributes(log.String("route", "/checkout"))
+
+	emitRecord(context.Background(), logger, record, []log.KeyValue{
+		log.String("order", "1"),
+		Err(errors.New("card declined")),
+	})
+
+	records := exporter.Exported()
+	if len(records) != 1 {
+		t.Fatalf("expected the record to be emitted, got %d records", len(records))
+	}
+	attrs := logtest.RecordAttributes(records[0])
+	if attrs["route"].AsString() != "/checkout" || attrs["order"].AsString() != "1" {
+		t.Errorf("expected the tags to be added to the attributes of the record, got %v", attrs)
+	}
+	if attrs["exception.message"].AsString() != "card declined" {
+		t.Errorf("expected the exception attributes, got %v", attrs)
+	}
+}
+
+func TestLogFunctions_EmitThroughThePlugin(t *testing.T) {
+	ctx := context.Background()
+	exportRequests := logtest.StartPlugin(t)
+
+	Infof(ctx, "charged order %d", 1)
+	Error(ctx, "failed", Err(errors.New("card declined")))
+	var record log.Record
+	record.SetBody(log.StringValue("checkout"))
+	if err := RecordLog(ctx, record, log.String("order", "1")); err != nil {
+		t.Fatalf("RecordLog failed: %v", err)
+	}
+	if err := o.FlushWithTimeout(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if n := exportRequests(); n == 0 {
+		t.Error("expected the records to be exported by the plugin")
+	}
+}
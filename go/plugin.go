@@ -19,10 +19,7 @@ type ObservabilityPlugin struct {
 
 // NewObservabilityPlugin creates a new observability plugin with the given configuration.
 func NewObservabilityPlugin(opts ...Option) *ObservabilityPlugin {
-	config := defaultConfig()
-	for _, opt := range opts {
-		opt(&config)
-	}
+	config := newConfig(opts...)
 
 	return &ObservabilityPlugin{
 		config: &config,
@@ -0,0 +1,145 @@
+package ldobserve
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+	"github.com/launchdarkly/observability-sdk/go/internal/logging"
+)
+
+const (
+	// The shortest refresh interval that will be used. Shorter intervals are
+	// raised to this value to avoid excessive load on the backend.
+	minSamplingConfigRefreshInterval = 10 * time.Second
+
+	// The delay before retrying after the first failed fetch. The delay
+	// doubles with each consecutive failure.
+	initialSamplingConfigBackoff = time.Second
+
+	// The longest delay between retries of a failed fetch.
+	maxSamplingConfigBackoff = 5 * time.Minute
+)
+
+type samplingConfigFetcher func(ctx context.Context) (*gql.GetSamplingConfigResponse, error)
+
+// samplingConfigRefresher fetches the sampling configuration and applies it.
+// When it has an interval, the configuration is fetched again on that interval
+// until the refresher is stopped or its context is cancelled.
+type samplingConfigRefresher struct {
+	fetch      samplingConfigFetcher
+	apply      func(*gql.GetSamplingConfigResponse)
+	interval   time.Duration
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// The refresher started by the most recent initialization. It is stopped when
+// the plugin is shut down or initialized again.
+//
+//nolint:gochecknoglobals
+var activeRefresher atomic.Pointer[samplingConfigRefresher]
+
+func newSamplingConfigRefresher(
+	fetch samplingConfigFetcher,
+	apply func(*gql.GetSamplingConfigResponse),
+	interval time.Duration,
+) *samplingConfigRefresher {
+	return &samplingConfigRefresher{
+		fetch:      fetch,
+		apply:      apply,
+		interval:   interval,
+		minBackoff: initialSamplingConfigBackoff,
+		maxBackoff: maxSamplingConfigBackoff,
+		done:       make(chan struct{}),
+	}
+}
+
+// start begins fetching in a goroutine. Cancelling the context stops the refresher.
+func (r *samplingConfigRefresher) start(ctx context.Context) {
+	ctx, r.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(r.done)
+		r.run(ctx)
+	}()
+}
+
+// stop stops the refresher and waits for any in-progress fetch to complete.
+func (r *samplingConfigRefresher) stop() {
+	r.stopOnce.Do(func() {
+		if r.cancel != nil {
+			r.cancel()
+			<-r.done
+		}
+	})
+}
+
+func (r *samplingConfigRefresher) run(ctx context.Context) {
+	backoff := r.minBackoff
+	for {
+		cfg, err := r.fetch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		var wait time.Duration
+		if err != nil {
+			logging.GetLogger().Errorf("failed to get sampling config: %v", err)
+			// Without an interval the configuration is only fetched once, which
+			// matches the behavior from before refreshing was supported.
+			if r.interval <= 0 {
+				return
+			}
+			wait = jitter(backoff)
+			backoff = min(backoff*2, r.maxBackoff)
+		} else {
+			logging.GetLogger().Infof("got sampling config: %v", cfg)
+			r.apply(cfg)
+			if r.interval <= 0 {
+				return
+			}
+			wait = r.interval
+			backoff = r.minBackoff
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// jitter returns a duration in the range [d/2, d). Spreading retries avoids
+// many processes which lost connectivity together retrying in lockstep.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	//nolint:gosec // This is not used for cryptographic security.
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// startSamplingConfigRefresh replaces any active refresher with a new one.
+func startSamplingConfigRefresh(ctx context.Context, r *samplingConfigRefresher) {
+	r.start(ctx)
+	if previous := activeRefresher.Swap(r); previous != nil {
+		previous.stop()
+	}
+}
+
+// stopSamplingConfigRefresh stops the active refresher, if there is one.
+func stopSamplingConfigRefresh() {
+	if r := activeRefresher.Swap(nil); r != nil {
+		r.stop()
+	}
+}
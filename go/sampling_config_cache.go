@@ -0,0 +1,117 @@
+package ldobserve
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+)
+
+const (
+	// The version of the cache file format. Files written with a different
+	// version are ignored. This must be incremented whenever the format of the
+	// file, or the sampling configuration it contains, changes incompatibly.
+	samplingConfigCacheVersion = 1
+
+	// Cached configurations older than this are ignored. A configuration this
+	// old is more likely to be wrong than having no configuration at all.
+	maxSamplingConfigCacheAge = 7 * 24 * time.Hour
+)
+
+// samplingConfigCacheEntry is the content of the cache file.
+type samplingConfigCacheEntry struct {
+	Version int `json:"version"`
+	// A hash of the key the configuration was fetched for. A cache file shared
+	// between projects, or left over from a different key, is ignored.
+	KeyHash   string                         `json:"keyHash"`
+	FetchedAt time.Time                      `json:"fetchedAt"`
+	Config    *gql.GetSamplingConfigResponse `json:"config"`
+}
+
+// samplingConfigCache persists the last successfully fetched sampling
+// configuration so that it can be used at startup before, or instead of,
+// a successful fetch.
+type samplingConfigCache struct {
+	path    string
+	keyHash string
+	now     func() time.Time
+}
+
+func newSamplingConfigCache(path string, sdkKey string) *samplingConfigCache {
+	// The key itself is not written to disk.
+	sum := sha256.Sum256([]byte(sdkKey))
+	return &samplingConfigCache{
+		path:    path,
+		keyHash: hex.EncodeToString(sum[:]),
+		now:     time.Now,
+	}
+}
+
+// load returns the cached configuration. An error is returned when there is
+// no usable configuration, including when the file is missing, corrupt, was
+// written by an incompatible version, or is for a different key.
+func (c *samplingConfigCache) load() (*gql.GetSamplingConfigResponse, error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+	var entry samplingConfigCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("decoding sampling config cache: %w", err)
+	}
+	if entry.Version != samplingConfigCacheVersion {
+		return nil, fmt.Errorf("unsupported sampling config cache version %d", entry.Version)
+	}
+	if entry.KeyHash != c.keyHash {
+		return nil, fmt.Errorf("sampling config cache is for a different key")
+	}
+	if c.now().Sub(entry.FetchedAt) > maxSamplingConfigCacheAge {
+		return nil, fmt.Errorf("sampling config cache from %v has expired", entry.FetchedAt)
+	}
+	if entry.Config == nil {
+		return nil, fmt.Errorf("sampling config cache has no configuration")
+	}
+	return entry.Config, nil
+}
+
+// store writes the configuration to the cache. The file is replaced atomically
+// so that a process reading it, or a crash while writing it, never observes a
+// partially written file.
+func (c *samplingConfigCache) store(cfg *gql.GetSamplingConfigResponse) error {
+	entry := &samplingConfigCacheEntry{
+		Version:   samplingConfigCacheVersion,
+		KeyHash:   c.keyHash,
+		FetchedAt: c.now().UTC(),
+		Config:    cfg,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding sampling config cache: %w", err)
+	}
+
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Nothing to remove once the rename has succeeded.
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
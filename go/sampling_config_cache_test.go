@@ -0,0 +1,153 @@
+package ldobserve
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+)
+
+func testSamplingConfig() *gql.GetSamplingConfigResponse {
+	spanConfig := gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{SamplingRatio: 10}
+	spanConfig.Name.MatchValue = "test-span"
+	logConfig := gql.GetSamplingConfigSamplingSamplingConfigLogsLogSamplingConfig{SamplingRatio: 5}
+	logConfig.Message.RegexValue = "^health"
+	return &gql.GetSamplingConfigResponse{
+		Sampling: gql.GetSamplingConfigSamplingSamplingConfig{
+			Spans: []gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig{spanConfig},
+			Logs:  []gql.GetSamplingConfigSamplingSamplingConfigLogsLogSamplingConfig{logConfig},
+		},
+	}
+}
+
+func TestSamplingConfigCache_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "sampling.json")
+	cache := newSamplingConfigCache(path, "sdk-key")
+
+	if err := cache.store(testSamplingConfig()); err != nil {
+		t.Fatalf("unexpected error storing cache: %v", err)
+	}
+
+	loaded, err := cache.load()
+	if err != nil {
+		t.Fatalf("unexpected error loading cache: %v", err)
+	}
+	if len(loaded.Sampling.Spans) != 1 || len(loaded.Sampling.Logs) != 1 {
+		t.Fatalf("expected 1 span and 1 log config, got %+v", loaded.Sampling)
+	}
+	if loaded.Sampling.Spans[0].Name.MatchValue != "test-span" {
+		t.Errorf("expected span name match value to round trip, got %v", loaded.Sampling.Spans[0].Name.MatchValue)
+	}
+	if loaded.Sampling.Spans[0].SamplingRatio != 10 {
+		t.Errorf("expected span sampling ratio 10, got %d", loaded.Sampling.Spans[0].SamplingRatio)
+	}
+	if loaded.Sampling.Logs[0].Message.RegexValue != "^health" {
+		t.Errorf("expected log message regex to round trip, got %q", loaded.Sampling.Logs[0].Message.RegexValue)
+	}
+}
+
+func TestSamplingConfigCache_DoesNotStoreKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sampling.json")
+	cache := newSamplingConfigCache(path, "sdk-secret-key")
+	if err := cache.store(testSamplingConfig()); err != nil {
+		t.Fatalf("unexpected error storing cache: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sdk-secret-key") {
+		t.Error("expected the SDK key not to be written to the cache")
+	}
+}
+
+func TestSamplingConfigCache_IgnoresUnusableFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	keyHash := newSamplingConfigCache("", "sdk-key").keyHash
+	fetchedAt := time.Now().UTC().Format(time.RFC3339)
+
+	cases := map[string]string{
+		"missing": filepath.Join(dir, "missing.json"),
+		"corrupt": write("corrupt.json", `{"version":1,"keyHa`),
+		"version": write("version.json",
+			`{"version":99,"keyHash":"`+keyHash+`","fetchedAt":"`+fetchedAt+`","config":{"sampling":{}}}`),
+		"no config": write("no-config.json",
+			`{"version":1,"keyHash":"`+keyHash+`","fetchedAt":"`+fetchedAt+`"}`),
+	}
+	for name, path := range cases {
+		t.Run(name, func(t *testing.T) {
+			cache := newSamplingConfigCache(path, "sdk-key")
+			if cfg, err := cache.load(); err == nil {
+				t.Errorf("expected an error, got config %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestSamplingConfigCache_IgnoresDifferentKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sampling.json")
+	if err := newSamplingConfigCache(path, "key-a").store(testSamplingConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newSamplingConfigCache(path, "key-b").load(); err == nil {
+		t.Error("expected a cache for a different key to be ignored")
+	}
+}
+
+func TestSamplingConfigCache_IgnoresExpired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sampling.json")
+	cache := newSamplingConfigCache(path, "sdk-key")
+	written := time.Now()
+	cache.now = func() time.Time { return written }
+	if err := cache.store(testSamplingConfig()); err != nil {
+		t.Fatal(err)
+	}
+
+	cache.now = func() time.Time { return written.Add(maxSamplingConfigCacheAge - time.Minute) }
+	if _, err := cache.load(); err != nil {
+		t.Errorf("expected cache within the maximum age to load, got %v", err)
+	}
+
+	cache.now = func() time.Time { return written.Add(maxSamplingConfigCacheAge + time.Minute) }
+	if _, err := cache.load(); err == nil {
+		t.Error("expected expired cache to be ignored")
+	}
+}
+
+func TestSamplingConfigCache_ReplacesExisting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sampling.json")
+	cache := newSamplingConfigCache(path, "sdk-key")
+	if err := cache.store(testSamplingConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.store(&gql.GetSamplingConfigResponse{}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := cache.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Sampling.Spans) != 0 {
+		t.Errorf("expected the latest config to replace the previous one, got %+v", loaded.Sampling)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the cache file in the directory, got %d entries", len(entries))
+	}
+}
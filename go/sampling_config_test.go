@@ -0,0 +1,173 @@
+package ldobserve
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+)
+
+type fakeSamplingConfigBackend struct {
+	mu      sync.Mutex
+	fetches int
+	applied []*gql.GetSamplingConfigResponse
+	errs    []error
+}
+
+func (b *fakeSamplingConfigBackend) fetch(_ context.Context) (*gql.GetSamplingConfigResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fetches++
+	if len(b.errs) > 0 {
+		err := b.errs[0]
+		b.errs = b.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &gql.GetSamplingConfigResponse{
+		Sampling: gql.GetSamplingConfigSamplingSamplingConfig{
+			Spans: make([]gql.GetSamplingConfigSamplingSamplingConfigSpansSpanSamplingConfig, b.fetches),
+		},
+	}, nil
+}
+
+func (b *fakeSamplingConfigBackend) apply(cfg *gql.GetSamplingConfigResponse) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.applied = append(b.applied, cfg)
+}
+
+func (b *fakeSamplingConfigBackend) counts() (fetches int, applied int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fetches, len(b.applied)
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSamplingConfigRefresher_FetchesOnceWithoutInterval(t *testing.T) {
+	backend := &fakeSamplingConfigBackend{}
+	r := newSamplingConfigRefresher(backend.fetch, backend.apply, 0)
+	r.start(context.Background())
+	<-r.done
+
+	fetches, applied := backend.counts()
+	if fetches != 1 || applied != 1 {
+		t.Errorf("expected 1 fetch and 1 apply, got %d fetches and %d applies", fetches, applied)
+	}
+}
+
+func TestSamplingConfigRefresher_DoesNotRetryWithoutInterval(t *testing.T) {
+	backend := &fakeSamplingConfigBackend{errs: []error{errors.New("unreachable")}}
+	r := newSamplingConfigRefresher(backend.fetch, backend.apply, 0)
+	r.start(context.Background())
+	<-r.done
+
+	fetches, applied := backend.counts()
+	if fetches != 1 || applied != 0 {
+		t.Errorf("expected 1 fetch and 0 applies, got %d fetches and %d applies", fetches, applied)
+	}
+}
+
+func TestSamplingConfigRefresher_RefreshesOnInterval(t *testing.T) {
+	backend := &fakeSamplingConfigBackend{}
+	r := newSamplingConfigRefresher(backend.fetch, backend.apply, time.Millisecond)
+	r.start(context.Background())
+	defer r.stop()
+
+	waitFor(t, func() bool {
+		_, applied := backend.counts()
+		return applied >= 3
+	})
+
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	for i, cfg := range backend.applied {
+		if len(cfg.Sampling.Spans) != i+1 {
+			t.Errorf("expected configurations to be applied in order, got %d spans at index %d",
+				len(cfg.Sampling.Spans), i)
+		}
+	}
+}
+
+func TestSamplingConfigRefresher_RetriesAfterFailure(t *testing.T) {
+	backend := &fakeSamplingConfigBackend{errs: []error{errors.New("first"), errors.New("second"), nil}}
+	r := newSamplingConfigRefresher(backend.fetch, backend.apply, time.Hour)
+	r.minBackoff = time.Millisecond
+	r.maxBackoff = 2 * time.Millisecond
+	r.start(context.Background())
+	defer r.stop()
+
+	waitFor(t, func() bool {
+		_, applied := backend.counts()
+		return applied == 1
+	})
+
+	fetches, _ := backend.counts()
+	if fetches != 3 {
+		t.Errorf("expected 3 fetches, got %d", fetches)
+	}
+}
+
+func TestSamplingConfigRefresher_StopsOnContextCancel(t *testing.T) {
+	backend := &fakeSamplingConfigBackend{}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newSamplingConfigRefresher(backend.fetch, backend.apply, time.Hour)
+	r.start(ctx)
+	waitFor(t, func() bool {
+		_, applied := backend.counts()
+		return applied == 1
+	})
+
+	cancel()
+	select {
+	case <-r.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("refresher did not stop after context cancellation")
+	}
+}
+
+func TestSamplingConfigRefresher_Stop(t *testing.T) {
+	backend := &fakeSamplingConfigBackend{}
+	r := newSamplingConfigRefresher(backend.fetch, backend.apply, time.Millisecond)
+	startSamplingConfigRefresh(context.Background(), r)
+	waitFor(t, func() bool {
+		_, applied := backend.counts()
+		return applied >= 1
+	})
+
+	stopSamplingConfigRefresh()
+	fetches, _ := backend.counts()
+	time.Sleep(10 * time.Millisecond)
+	after, _ := backend.counts()
+	if fetches != after {
+		t.Errorf("expected no fetches after stopping, got %d more", after-fetches)
+	}
+	if activeRefresher.Load() != nil {
+		t.Error("expected no active refresher after stopping")
+	}
+	// Stopping more than once is harmless.
+	r.stop()
+}
+
+func TestJitter(t *testing.T) {
+	for range 100 {
+		d := jitter(time.Second)
+		if d < 500*time.Millisecond || d >= time.Second {
+			t.Fatalf("expected jitter in [500ms, 1s), got %v", d)
+		}
+	}
+}
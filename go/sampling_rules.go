@@ -0,0 +1,243 @@
+package ldobserve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/gql"
+	"github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// MatchConfig matches a single value, such as a span name or an attribute value.
+// Only one of MatchValue and RegexValue should be set. A MatchConfig with neither
+// set matches any value.
+type MatchConfig struct {
+	// MatchValue matches values which are exactly equal to it. Strings, booleans,
+	// numbers and slices are supported.
+	MatchValue any `json:"matchValue,omitempty" yaml:"matchValue,omitempty"`
+	// RegexValue matches string values using a regular expression.
+	RegexValue string `json:"regexValue,omitempty" yaml:"regexValue,omitempty"`
+}
+
+// MatchValue creates a MatchConfig that matches values equal to the given value.
+func MatchValue(value any) MatchConfig {
+	return MatchConfig{MatchValue: value}
+}
+
+// MatchRegex creates a MatchConfig that matches string values using the given
+// regular expression.
+func MatchRegex(pattern string) MatchConfig {
+	return MatchConfig{RegexValue: pattern}
+}
+
+// AttributeMatchConfig matches an attribute by its key and value.
+type AttributeMatchConfig struct {
+	Key       MatchConfig `json:"key" yaml:"key"`
+	Attribute MatchConfig `json:"attribute" yaml:"attribute"`
+}
+
+// SpanEventMatchConfig matches a span event by its name and attributes.
+type SpanEventMatchConfig struct {
+	Name MatchConfig `json:"name,omitempty" yaml:"name,omitempty"`
+	// Each attribute listed must match.
+	Attributes []AttributeMatchConfig `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+}
+
+// SpanSamplingRule samples the spans which match each of its specified match
+// configurations. A rule without any match configurations matches all spans.
+type SpanSamplingRule struct {
+	Name MatchConfig `json:"name,omitempty" yaml:"name,omitempty"`
+	// Each attribute listed must match.
+	Attributes []AttributeMatchConfig `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	// Each event listed must match at least one event of the span.
+	Events []SpanEventMatchConfig `json:"events,omitempty" yaml:"events,omitempty"`
+	// The ratio of spans to sample. Expressed in the form 1/n. So if the ratio is 10, then 1 out of
+	// every 10 spans will be sampled. Setting the ratio to 0 will disable sampling for the span.
+	SamplingRatio int `json:"samplingRatio" yaml:"samplingRatio"`
+	// The maximum number of matching spans to sample each second, after the sampling ratio is
+	// applied. Zero means there is no limit.
+	MaxPerSecond float64 `json:"maxPerSecond,omitempty" yaml:"maxPerSecond,omitempty"`
+}
+
+// LogSamplingRule samples the logs which match each of its specified match
+// configurations. A rule without any match configurations matches all logs.
+type LogSamplingRule struct {
+	// Matches the log message. Only logs with a string body can match.
+	Message      MatchConfig `json:"message,omitempty" yaml:"message,omitempty"`
+	SeverityText MatchConfig `json:"severityText,omitempty" yaml:"severityText,omitempty"`
+	// Each attribute listed must match.
+	Attributes []AttributeMatchConfig `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	// The ratio of logs to sample. Expressed in the form 1/n. So if the ratio is 10, then 1 out of
+	// every 10 logs will be sampled. Setting the ratio to 0 will disable sampling for the log.
+	SamplingRatio int `json:"samplingRatio" yaml:"samplingRatio"`
+	// The maximum number of matching logs to sample each second, after the sampling ratio is
+	// applied. Zero means there is no limit.
+	MaxPerSecond float64 `json:"maxPerSecond,omitempty" yaml:"maxPerSecond,omitempty"`
+}
+
+// MetricSamplingRule controls the export of the data points of matching metrics.
+// Metrics are aggregated, so they are not sampled by ratio. Instead data points
+// can be dropped, or have attributes removed to reduce their cardinality. A rule
+// without any match configurations matches all metrics.
+// Metric rules cannot yet be managed in LaunchDarkly, as the remote sampling
+// configuration has no metric rules. Metrics are only sampled by the rules
+// provided by the application with WithSamplingRules.
+type MetricSamplingRule struct {
+	// Matches the name of the instrument.
+	Name MatchConfig `json:"name,omitempty" yaml:"name,omitempty"`
+	// Each attribute listed must match an attribute of the data point.
+	Attributes []AttributeMatchConfig `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	// When set, matching data points are not exported.
+	Drop bool `json:"drop,omitempty" yaml:"drop,omitempty"`
+	// Attributes with keys matching any of these are removed from matching data points.
+	// Data points which become identical are combined.
+	DropAttributes []MatchConfig `json:"dropAttributes,omitempty" yaml:"dropAttributes,omitempty"`
+}
+
+// SamplingRules is a sampling configuration provided by the application.
+// The rules have the same form and behavior as the sampling configuration
+// managed in LaunchDarkly. Rules are matched in order, and the first matching
+// rule decides how an item is sampled. Items which match no rule are always
+// sampled. Rules may also limit the number of items they sample each second.
+// The effective ratio of the limited items is recorded in their
+// launchdarkly.sampling.ratio attribute.
+type SamplingRules struct {
+	Spans   []SpanSamplingRule   `json:"spans,omitempty" yaml:"spans,omitempty"`
+	Logs    []LogSamplingRule    `json:"logs,omitempty" yaml:"logs,omitempty"`
+	Metrics []MetricSamplingRule `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+}
+
+// LoadSamplingRules reads sampling rules from a file. Files with a .yaml or .yml
+// extension are read as YAML, and all other files are read as JSON.
+func LoadSamplingRules(path string) (SamplingRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SamplingRules{}, err
+	}
+	var rules SamplingRules
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &rules)
+	default:
+		err = json.Unmarshal(data, &rules)
+	}
+	if err != nil {
+		return SamplingRules{}, fmt.Errorf("decoding sampling rules from %s: %w", path, err)
+	}
+	return rules, rules.Validate()
+}
+
+// Validate reports problems with the rules, such as regular expressions which
+// do not compile. A rule with an invalid match configuration does not match
+// any items.
+func (r SamplingRules) Validate() error {
+	var errs []error
+	check := func(where string, m MatchConfig) {
+		if m.MatchValue != nil && m.RegexValue != "" {
+			errs = append(errs, fmt.Errorf("%s: only one of matchValue and regexValue may be set", where))
+		}
+		if m.RegexValue != "" {
+			if _, err := regexp.Compile(m.RegexValue); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", where, err))
+			}
+		}
+	}
+	checkAttributes := func(where string, attributes []AttributeMatchConfig) {
+		for i, a := range attributes {
+			check(fmt.Sprintf("%s.attributes[%d].key", where, i), a.Key)
+			check(fmt.Sprintf("%s.attributes[%d].attribute", where, i), a.Attribute)
+		}
+	}
+	checkRatio := func(where string, ratio int, maxPerSecond float64) {
+		if ratio < 0 {
+			errs = append(errs, fmt.Errorf("%s: samplingRatio must not be negative", where))
+		}
+		if maxPerSecond < 0 {
+			errs = append(errs, fmt.Errorf("%s: maxPerSecond must not be negative", where))
+		}
+	}
+
+	for i, span := range r.Spans {
+		where := fmt.Sprintf("spans[%d]", i)
+		check(where+".name", span.Name)
+		checkAttributes(where, span.Attributes)
+		for j, event := range span.Events {
+			eventWhere := fmt.Sprintf("%s.events[%d]", where, j)
+			check(eventWhere+".name", event.Name)
+			checkAttributes(eventWhere, event.Attributes)
+		}
+		checkRatio(where, span.SamplingRatio, span.MaxPerSecond)
+	}
+	for i, log := range r.Logs {
+		where := fmt.Sprintf("logs[%d]", i)
+		check(where+".message", log.Message)
+		check(where+".severityText", log.SeverityText)
+		checkAttributes(where, log.Attributes)
+		checkRatio(where, log.SamplingRatio, log.MaxPerSecond)
+	}
+	for i, metric := range r.Metrics {
+		where := fmt.Sprintf("metrics[%d]", i)
+		check(where+".name", metric.Name)
+		checkAttributes(where, metric.Attributes)
+		for j, drop := range metric.DropAttributes {
+			check(fmt.Sprintf("%s.dropAttributes[%d]", where, j), drop)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// rateLimits returns the rate limits of the rules, in the same order as the rules.
+func (r SamplingRules) rateLimits() otel.RateLimits {
+	limits := otel.RateLimits{
+		Spans: make([]float64, len(r.Spans)),
+		Logs:  make([]float64, len(r.Logs)),
+	}
+	for i, span := range r.Spans {
+		limits.Spans[i] = span.MaxPerSecond
+	}
+	for i, log := range r.Logs {
+		limits.Logs[i] = log.MaxPerSecond
+	}
+	return limits
+}
+
+// toSamplingConfig converts the rules to the form used for the configuration
+// from LaunchDarkly. The rules are converted through JSON, which is the form
+// the LaunchDarkly configuration takes when it is received, so that local
+// match values behave exactly like remote ones. For instance, all numbers
+// become float64.
+func (r SamplingRules) toSamplingConfig() (*gql.GetSamplingConfigSamplingSamplingConfig, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("encoding sampling rules: %w", err)
+	}
+	var config gql.GetSamplingConfigSamplingSamplingConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("converting sampling rules: %w", err)
+	}
+	return &config, nil
+}
+
+// toMetricSamplingConfig converts the metric rules to the form used by the
+// sampler, in the same way as toSamplingConfig.
+func (r SamplingRules) toMetricSamplingConfig() ([]otel.MetricSamplingConfig, error) {
+	if len(r.Metrics) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(r.Metrics)
+	if err != nil {
+		return nil, fmt.Errorf("encoding metric sampling rules: %w", err)
+	}
+	var config []otel.MetricSamplingConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("converting metric sampling rules: %w", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,271 @@
+package ldobserve
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+type testSpan struct {
+	name       string
+	attributes []attribute.KeyValue
+}
+
+func (s testSpan) Events() []sdktrace.Event         { return nil }
+func (s testSpan) Name() string                     { return s.name }
+func (s testSpan) Attributes() []attribute.KeyValue { return s.attributes }
+func (s testSpan) SpanContext() trace.SpanContext   { return trace.SpanContext{} }
+
+func neverSampler(_ int) bool { return false }
+
+func TestSamplingRules_ToSamplingConfig(t *testing.T) {
+	rules := SamplingRules{
+		Spans: []SpanSamplingRule{
+			{
+				Name: MatchRegex("^GET /health"),
+				Attributes: []AttributeMatchConfig{
+					{Key: MatchValue("http.status_code"), Attribute: MatchValue(200)},
+				},
+				SamplingRatio: 100,
+			},
+		},
+		Logs: []LogSamplingRule{
+			{SeverityText: MatchValue("DEBUG"), SamplingRatio: 0},
+		},
+	}
+
+	config, err := rules.toSamplingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(config.Spans) != 1 || len(config.Logs) != 1 {
+		t.Fatalf("expected 1 span rule and 1 log rule, got %+v", config)
+	}
+	if config.Spans[0].Name.RegexValue != "^GET /health" {
+		t.Errorf("expected span name regex to be converted, got %q", config.Spans[0].Name.RegexValue)
+	}
+	// Integers become float64, as they would in the configuration from LaunchDarkly.
+	if v, ok := config.Spans[0].Attributes[0].Attribute.MatchValue.(float64); !ok || v != 200 {
+		t.Errorf("expected attribute match value to be float64 200, got %#v",
+			config.Spans[0].Attributes[0].Attribute.MatchValue)
+	}
+	if config.Spans[0].SamplingRatio != 100 {
+		t.Errorf("expected span sampling ratio 100, got %d", config.Spans[0].SamplingRatio)
+	}
+	if config.Logs[0].SeverityText.MatchValue != "DEBUG" {
+		t.Errorf("expected log severity match value to be converted, got %v", config.Logs[0].SeverityText.MatchValue)
+	}
+}
+
+func TestSamplingRules_MatchLikeRemoteConfig(t *testing.T) {
+	rules := SamplingRules{
+		Spans: []SpanSamplingRule{
+			{
+				Attributes: []AttributeMatchConfig{
+					{Key: MatchValue("http.status_code"), Attribute: MatchValue(200)},
+				},
+				SamplingRatio: 0,
+			},
+		},
+	}
+	config, err := rules.toSamplingConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sampler := otel.NewCustomSampler(neverSampler)
+	sampler.SetLocalConfig(config)
+
+	matching := testSpan{name: "GET /", attributes: []attribute.KeyValue{attribute.Int("http.status_code", 200)}}
+	if res := sampler.SampleSpan(matching); res.Sample {
+		t.Error("expected span matching the integer attribute to be sampled out")
+	}
+	other := testSpan{name: "GET /", attributes: []attribute.KeyValue{attribute.Int("http.status_code", 500)}}
+	if res := sampler.SampleSpan(other); !res.Sample {
+		t.Error("expected span not matching any rule to be sampled")
+	}
+}
+
+func TestLoadSamplingRules(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "rules.yaml")
+	jsonPath := filepath.Join(dir, "rules.json")
+	yamlContent := `
+spans:
+  - name:
+      regexValue: "^GET /health"
+    samplingRatio: 100
+logs:
+  - severityText:
+      matchValue: DEBUG
+    attributes:
+      - key:
+          matchValue: retry
+        attribute:
+          matchValue: 3
+    samplingRatio: 10
+`
+	jsonContent := `{
+	"spans": [{"name": {"regexValue": "^GET /health"}, "samplingRatio": 100}],
+	"logs": [{
+		"severityText": {"matchValue": "DEBUG"},
+		"attributes": [{"key": {"matchValue": "retry"}, "attribute": {"matchValue": 3}}],
+		"samplingRatio": 10
+	}]
+}`
+	if err := os.WriteFile(yamlPath, []byte(yamlContent), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonPath, []byte(jsonContent), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{yamlPath, jsonPath} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			rules, err := LoadSamplingRules(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rules.Spans) != 1 || len(rules.Logs) != 1 {
+				t.Fatalf("expected 1 span rule and 1 log rule, got %+v", rules)
+			}
+			if rules.Spans[0].Name.RegexValue != "^GET /health" || rules.Spans[0].SamplingRatio != 100 {
+				t.Errorf("unexpected span rule %+v", rules.Spans[0])
+			}
+			if rules.Logs[0].SeverityText.MatchValue != "DEBUG" || rules.Logs[0].SamplingRatio != 10 {
+				t.Errorf("unexpected log rule %+v", rules.Logs[0])
+			}
+			config, err := rules.toSamplingConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := config.Logs[0].Attributes[0].Attribute.MatchValue; v != float64(3) {
+				t.Errorf("expected attribute match value to be float64 3, got %#v", v)
+			}
+		})
+	}
+}
+
+func TestLoadSamplingRules_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadSamplingRules(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte(`{"spans": [`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSamplingRules(corrupt); err == nil {
+		t.Error("expected an error for a corrupt file")
+	}
+
+	invalid := filepath.Join(dir, "invalid.yml")
+	if err := os.WriteFile(invalid, []byte("spans:\n  - name:\n      regexValue: \"(\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSamplingRules(invalid); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+}
+
+func TestSamplingRules_Validate(t *testing.T) {
+	valid := SamplingRules{
+		Spans: []SpanSamplingRule{{Name: MatchValue("span"), SamplingRatio: 2}},
+		Logs:  []LogSamplingRule{{Message: MatchRegex("^msg"), SamplingRatio: 2}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid rules, got %v", err)
+	}
+
+	invalid := SamplingRules{
+		Spans: []SpanSamplingRule{
+			{Name: MatchConfig{MatchValue: "a", RegexValue: "b"}},
+			{Events: []SpanEventMatchConfig{{Name: MatchRegex("[")}}},
+		},
+		Logs: []LogSamplingRule{{SamplingRatio: -1}, {MaxPerSecond: -1}},
+	}
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected invalid rules to report an error")
+	}
+}
+
+func TestSamplingRules_RateLimits(t *testing.T) {
+	rules := SamplingRules{
+		Spans: []SpanSamplingRule{
+			{Name: MatchValue("GET /health"), SamplingRatio: 1, MaxPerSecond: 1},
+			{Name: MatchValue("GET /items"), SamplingRatio: 1},
+		},
+		Logs: []LogSamplingRule{{SamplingRatio: 1, MaxPerSecond: 5}},
+	}
+	limits := rules.rateLimits()
+	if len(limits.Spans) != 2 || limits.Spans[0] != 1 || limits.Spans[1] != 0 {
+		t.Errorf("unexpected span rate limits %v", limits.Spans)
+	}
+	if len(limits.Logs) != 1 || limits.Logs[0] != 5 {
+		t.Errorf("unexpected log rate limits %v", limits.Logs)
+	}
+
+	config, err := rules.toSamplingConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sampler := otel.NewCustomSampler(nil)
+	sampler.SetLocalConfig(config)
+	sampler.SetLocalRateLimits(limits)
+
+	health := testSpan{name: "GET /health"}
+	if !sampler.SampleSpan(health).Sample {
+		t.Error("expected the first matching span to be sampled")
+	}
+	if sampler.SampleSpan(health).Sample {
+		t.Error("expected matching spans over the limit not to be sampled")
+	}
+	for range 5 {
+		if !sampler.SampleSpan(testSpan{name: "GET /items"}).Sample {
+			t.Error("expected spans matching an unlimited rule to be sampled")
+		}
+	}
+}
+
+func TestSamplingRules_ToMetricSamplingConfig(t *testing.T) {
+	rules := SamplingRules{
+		Metrics: []MetricSamplingRule{
+			{Name: MatchRegex("^debug\\."), Drop: true},
+			{
+				Attributes:     []AttributeMatchConfig{{Key: MatchValue("status"), Attribute: MatchValue(500)}},
+				DropAttributes: []MatchConfig{MatchValue("user.id"), MatchRegex("^session\\.")},
+			},
+		},
+	}
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config, err := rules.toMetricSamplingConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(config) != 2 {
+		t.Fatalf("expected 2 metric rules, got %d", len(config))
+	}
+	if config[0].Name.RegexValue != "^debug\\." || !config[0].Drop {
+		t.Errorf("unexpected first metric rule %+v", config[0])
+	}
+	if v := config[1].Attributes[0].Attribute.MatchValue; v != float64(500) {
+		t.Errorf("expected attribute match value to be float64 500, got %#v", v)
+	}
+	if len(config[1].DropAttributes) != 2 || config[1].DropAttributes[1].RegexValue != "^session\\." {
+		t.Errorf("unexpected drop attributes %+v", config[1].DropAttributes)
+	}
+
+	invalid := SamplingRules{Metrics: []MetricSamplingRule{{DropAttributes: []MatchConfig{MatchRegex("(")}}}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected an invalid drop attribute regex to report an error")
+	}
+}
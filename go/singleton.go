@@ -2,6 +2,7 @@ package ldobserve
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
 
@@ -93,8 +94,10 @@ func recordSpanError(span trace.Span, err error, tags ...attribute.KeyValue) {
 }
 
 // RecordLog is used to record arbitrary logs in your golang backend.
+// The tags are added to the attributes of the record. Functions such as Info
+// and Error build the record from a message and attributes.
 func RecordLog(ctx context.Context, record log.Record, tags ...log.KeyValue) error {
-	o.GetLogger().Emit(ctx, record)
+	emitRecord(ctx, o.GetLogger(), record, tags)
 	return nil
 }
 
@@ -114,12 +117,84 @@ func RecordCount(ctx context.Context, name string, value int64, tags ...attribut
 }
 
 // Start starts the observability plugin, when the plugin is configured with WithManualStart.
+// Start can also be called after Shutdown, to start the plugin again with the
+// same configuration. Exporters and readers added with WithAdditionalSpanExporter,
+// WithAdditionalLogExporter and WithAdditionalMetricReader are shut down with the
+// plugin, and are not used when it is started again.
 func Start() error {
+	if setup := lastSetup.Load(); setup != nil && !setup.config.disableRemoteSamplingConfig &&
+		activeRefresher.Load() == nil {
+		// The refresh was stopped by Shutdown.
+		startRemoteSamplingConfig(setup.sdkKey, setup.config)
+	}
 	return o.StartOTLP()
 }
 
+// Reconfigure changes the configuration of the observability plugin while the
+// application is running, for instance to change the OTLP endpoint or the
+// service attributes. The options are applied on top of the options the plugin
+// was initialized with, so only the settings which change need to be given. If
+// the plugin is started, then pending data is exported, and the plugin is
+// started again with the new configuration. Otherwise the new configuration is
+// used when Start is called.
+//
+// Exporters and readers added with WithAdditionalSpanExporter,
+// WithAdditionalLogExporter and WithAdditionalMetricReader are replaced by
+// those in the options, as they are shut down when the plugin is restarted.
+//
+// Pending data is exported until the context is done, and data which is not
+// exported by then is dropped. The context given with WithContext when the
+// plugin was initialized still shuts down the plugin, and WithContext has no
+// effect when reconfiguring.
+//
+// Tracers, loggers and meters obtained from the global OpenTelemetry providers
+// before the plugin is reconfigured continue to use the previous providers, and
+// no longer export data. Functions such as StartSpan and RecordMetric use the
+// new providers.
+func Reconfigure(ctx context.Context, opts ...Option) error {
+	previous := lastSetup.Load()
+	if previous == nil {
+		return errors.New("the observability plugin must be initialized before it is reconfigured")
+	}
+	config := previous.config
+	config.additionalSpanExporters = nil
+	config.additionalLogExporters = nil
+	config.additionalMetricReaders = nil
+	// The invalid environment variables were reported when the plugin was
+	// initialized.
+	config.environmentErrors = nil
+	for _, opt := range opts {
+		opt(&config)
+	}
+	config.context = previous.config.context
+	// The plugin is restarted below if it is started, rather than started by
+	// the setup.
+	config.manualStart = true
+	configureOtel(previous.sdkKey, config)
+	return o.Restart(ctx)
+}
+
 // Shutdown stops the observability plugin.
 // It is recommended to call this function when the application is shutting down.
 func Shutdown() {
+	stopSamplingConfigRefresh()
 	o.Shutdown()
 }
+
+// ShutdownWithContext stops the observability plugin, exporting pending data
+// until the context is done. Traces, logs and metrics are exported in parallel,
+// and the returned error joins the errors of each. Pending data which is not
+// exported before the deadline is dropped, so termination is not delayed by an
+// endpoint which cannot be reached.
+func ShutdownWithContext(ctx context.Context) error {
+	stopSamplingConfigRefresh()
+	return o.ShutdownWithContext(ctx)
+}
+
+// Flush exports pending data until the context is done, without stopping the
+// observability plugin. This is intended for environments which suspend the
+// process, such as AWS Lambda after each invocation. Traces, logs and metrics
+// are exported in parallel, and the returned error joins the errors of each.
+func Flush(ctx context.Context) error {
+	return o.Flush(ctx)
+}
@@ -0,0 +1,46 @@
+package ldobserve
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestReconfigure(t *testing.T) {
+	lastSetup.Store(nil)
+	if err := Reconfigure(context.Background(), WithServiceName("service")); err == nil {
+		t.Error("Expected reconfiguring before initialization to fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	PreInitialize("sdk-key",
+		WithContext(ctx),
+		WithManualStart(),
+		WithoutRemoteSamplingConfig(),
+		WithServiceName("service"),
+		WithAdditionalSpanExporter(tracetest.NewInMemoryExporter(), false),
+	)
+	if err := Reconfigure(context.Background(), WithServiceVersion("2.0.0"), WithContext(context.TODO())); err != nil {
+		t.Fatalf("Reconfigure failed: %v", err)
+	}
+
+	setup := lastSetup.Load()
+	if setup.sdkKey != "sdk-key" {
+		t.Errorf("Expected the SDK key to be kept, got %s", setup.sdkKey)
+	}
+	if setup.config.serviceName != "service" || setup.config.serviceVersion != "2.0.0" {
+		t.Errorf("Expected the options to be applied on top of the previous options, got %q %q",
+			setup.config.serviceName, setup.config.serviceVersion)
+	}
+	if !setup.config.disableRemoteSamplingConfig {
+		t.Error("Expected the previous options to be kept")
+	}
+	if setup.config.context != ctx {
+		t.Error("Expected the context the plugin was initialized with to be kept")
+	}
+	if len(setup.config.additionalSpanExporters) != 0 {
+		t.Errorf("Expected the additional exporters to be replaced, got %d", len(setup.config.additionalSpanExporters))
+	}
+}
@@ -0,0 +1,233 @@
+package ldobserve
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logconv"
+)
+
+// SlogHandlerOptions configures a handler created with NewSlogHandler.
+type SlogHandlerOptions struct {
+	// Level is the minimum level of the records which are recorded with the
+	// observability plugin. When it is nil, records of level slog.LevelInfo
+	// and above are recorded. It does not apply to the records handled by
+	// Next.
+	Level slog.Leveler
+	// Next is a handler which also handles every record, such as the handler
+	// which writes logs to stdout, so that existing logging is unchanged.
+	Next slog.Handler
+	// AddSource adds the file, line and function which emitted the record as
+	// attributes.
+	AddSource bool
+}
+
+// SlogHandler is a slog.Handler which records logs with the observability
+// plugin. Records are converted to OpenTelemetry log records, and are
+// associated with the span in the context given to the logger, such as by
+// slog.InfoContext.
+type SlogHandler struct {
+	level     slog.Leveler
+	next      slog.Handler
+	addSource bool
+	// The attributes added with WithAttrs outside of a group.
+	attrs []log.KeyValue
+	// The groups added with WithGroup, outermost first.
+	groups []slogGroup
+	// The logger of the plugin is used when it is nil.
+	logger log.Logger
+}
+
+// slogGroup is a group added with WithGroup, and the attributes added within
+// it.
+type slogGroup struct {
+	name  string
+	attrs []log.KeyValue
+}
+
+// NewSlogHandler creates a slog.Handler which records logs with the
+// observability plugin. The options may be nil.
+//
+//	logger := slog.New(ldobserve.NewSlogHandler(&ldobserve.SlogHandlerOptions{
+//		Next: slog.NewTextHandler(os.Stdout, nil),
+//	}))
+func NewSlogHandler(opts *SlogHandlerOptions) *SlogHandler {
+	h := &SlogHandler{level: slog.LevelInfo}
+	if opts != nil {
+		if opts.Level != nil {
+			h.level = opts.Level
+		}
+		h.next = opts.Next
+		h.addSource = opts.AddSource
+	}
+	return h
+}
+
+func (h *SlogHandler) recordEnabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() &&
+		logconv.Logger(h.logger).Enabled(ctx, log.EnabledParameters{Severity: slogSeverity(level)})
+}
+
+// Enabled implements slog.Handler.
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.recordEnabled(ctx, level) || (h.next != nil && h.next.Enabled(ctx, level))
+}
+
+// Handle implements slog.Handler.
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if h.recordEnabled(ctx, record.Level) {
+		logconv.Logger(h.logger).Emit(ctx, h.convertRecord(record))
+	}
+	if h.next != nil && h.next.Enabled(ctx, record.Level) {
+		return h.next.Handle(ctx, record)
+	}
+	return nil
+}
+
+func (h *SlogHandler) convertRecord(record slog.Record) log.Record {
+	var r log.Record
+	r.SetTimestamp(record.Time)
+	r.SetSeverity(slogSeverity(record.Level))
+	r.SetSeverityText(record.Level.String())
+	r.SetBody(log.StringValue(record.Message))
+
+	attrs := make([]log.KeyValue, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = appendSlogAttr(attrs, attr)
+		return true
+	})
+	// Groups without attributes are omitted, as they are by the handlers of
+	// the slog package.
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		group := h.groups[i]
+		groupAttrs := append(slices.Clip(group.attrs), attrs...)
+		attrs = nil
+		if len(groupAttrs) > 0 {
+			attrs = []log.KeyValue{log.Map(group.name, groupAttrs...)}
+		}
+	}
+	r.AddAttributes(h.attrs...)
+	r.AddAttributes(attrs...)
+
+	if h.addSource && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		r.AddAttributes(
+			log.String(string(semconv.CodeFilePathKey), frame.File),
+			log.Int(string(semconv.CodeLineNumberKey), frame.Line),
+			log.String(string(semconv.CodeFunctionNameKey), frame.Function),
+		)
+	}
+	return r
+}
+
+// WithAttrs implements slog.Handler.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	if len(h.groups) == 0 {
+		clone.attrs = slices.Clip(h.attrs)
+		for _, attr := range attrs {
+			clone.attrs = appendSlogAttr(clone.attrs, attr)
+		}
+	} else {
+		clone.groups = slices.Clone(h.groups)
+		group := &clone.groups[len(clone.groups)-1]
+		group.attrs = slices.Clip(group.attrs)
+		for _, attr := range attrs {
+			group.attrs = appendSlogAttr(group.attrs, attr)
+		}
+	}
+	if h.next != nil {
+		clone.next = h.next.WithAttrs(attrs)
+	}
+	return &clone
+}
+
+// WithGroup implements slog.Handler.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.groups = append(slices.Clip(h.groups), slogGroup{name: name})
+	if h.next != nil {
+		clone.next = h.next.WithGroup(name)
+	}
+	return &clone
+}
+
+// slogSeverity converts a slog level to a log severity. The levels defined by
+// the slog package are converted to the severities of the same name, and the
+// levels between them to the severities between those.
+func slogSeverity(level slog.Level) log.Severity {
+	return log.Severity(min(max(int(level)+int(log.SeverityInfo), int(log.SeverityTrace1)), int(log.SeverityFatal4)))
+}
+
+// appendSlogAttr converts an attribute, and appends it unless it is empty. The
+// attributes of a group with an empty key are appended in place of the group,
+// as they are by the handlers of the slog package.
+func appendSlogAttr(attrs []log.KeyValue, attr slog.Attr) []log.KeyValue {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		group := attr.Value.Group()
+		if len(group) == 0 {
+			return attrs
+		}
+		if attr.Key == "" {
+			for _, a := range group {
+				attrs = appendSlogAttr(attrs, a)
+			}
+			return attrs
+		}
+	}
+	return append(attrs, log.KeyValue{Key: attr.Key, Value: slogValue(attr.Value)})
+}
+
+// slogValue converts a resolved slog value to a log value.
+func slogValue(v slog.Value) log.Value {
+	switch v.Kind() {
+	case slog.KindString:
+		return log.StringValue(v.String())
+	case slog.KindInt64:
+		return log.Int64Value(v.Int64())
+	case slog.KindUint64:
+		return logconv.Uint64Value(v.Uint64())
+	case slog.KindFloat64:
+		return log.Float64Value(v.Float64())
+	case slog.KindBool:
+		return log.BoolValue(v.Bool())
+	case slog.KindDuration:
+		return log.Int64Value(v.Duration().Nanoseconds())
+	case slog.KindTime:
+		return log.StringValue(v.Time().Format(time.RFC3339Nano))
+	case slog.KindGroup:
+		var attrs []log.KeyValue
+		for _, attr := range v.Group() {
+			attrs = appendSlogAttr(attrs, attr)
+		}
+		return log.MapValue(attrs...)
+	}
+	switch value := v.Any().(type) {
+	case error:
+		return log.StringValue(value.Error())
+	case []byte:
+		return log.BytesValue(value)
+	case fmt.Stringer:
+		return log.StringValue(value.String())
+	}
+	return log.StringValue(fmt.Sprintf("%+v", v.Any()))
+}
+
+var _ slog.Handler = &SlogHandler{}
@@ -0,0 +1,125 @@
+package ldobserve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/logtest"
+)
+
+func mapValue(v log.Value) map[string]log.Value {
+	values := map[string]log.Value{}
+	for _, kv := range v.AsMap() {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+type userID string
+
+func (u userID) LogValue() slog.Value {
+	return slog.StringValue("user-" + string(u))
+}
+
+func TestSlogHandler_ConvertsRecords(t *testing.T) {
+	logger, exporter := logtest.NewRecordingLogger()
+	var stdout bytes.Buffer
+	handler := NewSlogHandler(&SlogHandlerOptions{
+		Level: slog.LevelDebug,
+		Next:  slog.NewTextHandler(&stdout, nil),
+	})
+	handler.logger = logger
+
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	ctx, span := tracer.Start(context.Background(), "request")
+	defer span.End()
+
+	slogger := slog.New(handler).With("service", "checkout").WithGroup("request").With("method", "GET")
+	slogger.WarnContext(ctx, "slow request",
+		"user", userID("1"),
+		"duration", 2*time.Second,
+		slog.Group("db", "rows", 3),
+		slog.Group("empty"),
+		"err", errors.New("timeout"),
+	)
+	slogger.DebugContext(ctx, "debug details")
+
+	records := exporter.Exported()
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	r := records[0]
+	if r.Body().AsString() != "slow request" || r.Severity() != log.SeverityWarn || r.SeverityText() != "WARN" {
+		t.Errorf("expected a warning with the message as the body, got %v %v %q",
+			r.Body(), r.Severity(), r.SeverityText())
+	}
+	if r.TraceID() != span.SpanContext().TraceID() || r.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("expected the record to be associated with the span in the context")
+	}
+	attrs := logtest.RecordAttributes(r)
+	if attrs["service"].AsString() != "checkout" {
+		t.Errorf("expected the attribute outside of the group, got %v", attrs)
+	}
+	request := mapValue(attrs["request"])
+	if request["method"].AsString() != "GET" || request["user"].AsString() != "user-1" ||
+		request["duration"].AsInt64() != int64(2*time.Second) || request["err"].AsString() != "timeout" {
+		t.Errorf("expected the attributes to be in the group, got %v", request)
+	}
+	if mapValue(request["db"])["rows"].AsInt64() != 3 {
+		t.Errorf("expected the nested group, got %v", request["db"])
+	}
+	if _, ok := request["empty"]; ok {
+		t.Error("expected the empty group to be omitted")
+	}
+	if records[1].Severity() != log.SeverityDebug {
+		t.Errorf("expected a debug record, got %v", records[1].Severity())
+	}
+
+	// The text handler's level is info, so it only handles the warning.
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 1 ||
+		!strings.Contains(lines[0], "request.method=GET") {
+		t.Errorf("expected the next handler to handle the record, got %q", stdout.String())
+	}
+}
+
+func TestSlogHandler_Level(t *testing.T) {
+	logger, exporter := logtest.NewRecordingLogger()
+	handler := NewSlogHandler(nil)
+	handler.logger = logger
+
+	slogger := slog.New(handler)
+	slogger.Debug("ignored")
+	slogger.Info("recorded")
+
+	if records := exporter.Exported(); len(records) != 1 || records[0].Body().AsString() != "recorded" {
+		t.Errorf("expected only the info record to be recorded, got %d records", len(records))
+	}
+	if handler.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("expected debug records not to be enabled without a next handler")
+	}
+}
+
+func TestSlogSeverity(t *testing.T) {
+	cases := map[slog.Level]log.Severity{
+		slog.LevelDebug:     log.SeverityDebug,
+		slog.LevelInfo:      log.SeverityInfo,
+		slog.LevelInfo + 2:  log.SeverityInfo3,
+		slog.LevelWarn:      log.SeverityWarn,
+		slog.LevelError:     log.SeverityError,
+		slog.LevelError + 8: log.SeverityFatal4,
+		slog.LevelDebug - 8: log.SeverityTrace1,
+	}
+	for level, expected := range cases {
+		if severity := slogSeverity(level); severity != expected {
+			t.Errorf("expected level %v to be severity %v, got %v", level, expected, severity)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package ldobserve
+
+import (
+	"time"
+
+	"github.com/launchdarkly/observability-sdk/go/internal/otel"
+)
+
+// SignalStats are the counts of the export of one signal to LaunchDarkly since
+// the process started.
+type SignalStats struct {
+	// Exported is the number of items accepted by the LaunchDarkly endpoint.
+	// Metric data points are not counted.
+	Exported int64
+	// DroppedQueueFull is the number of items dropped because the export queue
+	// was full.
+	DroppedQueueFull int64
+	// SampledOut is the number of items dropped by the sampling configuration.
+	SampledOut int64
+	// PrunedChildren is the number of spans dropped because their parent span
+	// was sampled out.
+	PrunedChildren int64
+	// DroppedFromBuffer is the number of spans held by the trace buffer,
+	// configured with WithTraceBuffer, which could not be exported.
+	DroppedFromBuffer int64
+	// BufferEvictedTraces and BufferTimedOutTraces are the number of traces
+	// the trace buffer exported before they were complete, because the buffer
+	// was full or because their root span did not end within the timeout.
+	BufferEvictedTraces  int64
+	BufferTimedOutTraces int64
+	// Exports is the number of exports to the endpoint, and ExportTime is the
+	// total time they took, including retries.
+	Exports    int64
+	ExportTime time.Duration
+	// FailuresByStatus is the number of failed requests to the endpoint by
+	// status code. HTTP status codes are numbers, such as "503", gRPC status
+	// codes are names, such as "Unavailable", and requests which did not
+	// receive a response are counted as "transport_error".
+	FailuresByStatus map[string]int64
+}
+
+// TelemetryStats are the counts of the telemetry the plugin keeps about its
+// export pipelines.
+type TelemetryStats struct {
+	Spans   SignalStats
+	Logs    SignalStats
+	Metrics SignalStats
+	// SamplingConfigFetches is the number of times the sampling configuration
+	// was fetched from LaunchDarkly, of which SamplingConfigFetchFailures
+	// failed.
+	SamplingConfigFetches       int64
+	SamplingConfigFetchFailures int64
+}
+
+// Stats returns the telemetry the plugin keeps about its export pipelines,
+// which is counted from when the process starts. Unless the plugin is
+// configured with WithoutSelfTelemetry, the same counts are exported as metrics
+// named launchdarkly.sdk.*, which have the telemetry.distro.name and
+// telemetry.distro.version attributes.
+func Stats() TelemetryStats {
+	stats := otel.GetStats()
+	return TelemetryStats{
+		Spans:                       SignalStats(stats.Spans),
+		Logs:                        SignalStats(stats.Logs),
+		Metrics:                     SignalStats(stats.Metrics),
+		SamplingConfigFetches:       stats.SamplingConfigFetches,
+		SamplingConfigFetchFailures: stats.SamplingConfigFetchFailures,
+	}
+}
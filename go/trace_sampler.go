@@ -1,26 +1,69 @@
 package ldobserve
 
 import (
-	"encoding/binary"
 	"fmt"
+	"math"
 
 	"github.com/samber/lo"
+	"go.opentelemetry.io/otel/attribute"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/attributes"
+	"github.com/launchdarkly/observability-sdk/go/internal/logging"
+	"github.com/launchdarkly/observability-sdk/go/internal/otel"
+	"github.com/launchdarkly/observability-sdk/go/internal/sampling"
 )
 
+// traceSampler samples root spans using the consistent probability sampling
+// of OpenTelemetry. The rejection threshold of a sampled trace is recorded in
+// the "ot=th:" tracestate entry, so the services it propagates to, and the
+// backend, know the probability it was sampled with.
 type traceSampler struct {
-	traceIDUpperBounds map[trace.SpanKind]uint64
-	description        string
+	thresholds map[trace.SpanKind]uint64
+	// Matches root spans against the head sampling rules. The thresholds of
+	// the rules are in the same order as the rules.
+	rules          *otel.CustomSampler
+	ruleThresholds []uint64
+	// Rate limiters for the rules, and for all root spans. A nil limiter
+	// means there is no limit.
+	ruleLimiters []*sampling.RateLimiter
+	limiter      *sampling.RateLimiter
+	description  string
+}
+
+// HeadSamplingRule sets the rate at which traces are sampled when their root
+// span matches the rule. Rules are matched against the name of the span and
+// the attributes it is started with, such as http.route or rpc.method.
+// Attributes which are set after the span starts are not available.
+type HeadSamplingRule struct {
+	Name MatchConfig
+	// Each attribute listed must match.
+	Attributes []AttributeMatchConfig
+	// The fraction of matching traces to sample, from 0 to 1.
+	Rate float64
+	// The maximum number of matching traces to sample each second. Zero means
+	// there is no limit.
+	MaxPerSecond float64
+}
+
+// headSpan presents the parameters of a span which is being started in the
+// form used for sampling rules.
+type headSpan struct {
+	params sdktrace.SamplingParameters
+}
+
+func (s headSpan) Events() []sdktrace.Event         { return nil }
+func (s headSpan) Name() string                     { return s.params.Name }
+func (s headSpan) Attributes() []attribute.KeyValue { return s.params.Attributes }
+func (s headSpan) SpanContext() trace.SpanContext {
+	return trace.NewSpanContext(trace.SpanContextConfig{TraceID: s.params.TraceID})
 }
 
 func (ts traceSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
 	psc := trace.SpanContextFromContext(p.ParentContext)
 	if psc.IsSampled() {
-		return sdktrace.SamplingResult{
-			Decision:   sdktrace.RecordAndSample,
-			Tracestate: psc.TraceState(),
-		}
+		return sampledByParent(p.TraceID, psc.TraceState())
 	}
 	// A valid unsampled parent already decided this trace. Do not let the
 	// child's SpanKind re-roll — that would sample-in Producer children of
@@ -33,13 +76,16 @@ func (ts traceSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.Samp
 			Tracestate: psc.TraceState(),
 		}
 	}
-	bound, ok := ts.traceIDUpperBounds[p.Kind]
+	threshold, ruleLimiter, ok := ts.matchRule(p)
+	if !ok {
+		threshold, ok = ts.thresholds[p.Kind]
+	}
 	if !ok {
-		bound, ok = ts.traceIDUpperBounds[trace.SpanKindUnspecified]
+		threshold, ok = ts.thresholds[trace.SpanKindUnspecified]
 		// If there are no bounds specified, then we sample all
 		// Avoiding doing work here versus having default bounds which would
 		// would require additional work per span.
-		if !ok {
+		if !ok && ts.limiter == nil {
 			return sdktrace.SamplingResult{
 				Decision:   sdktrace.RecordAndSample,
 				Tracestate: psc.TraceState(),
@@ -47,29 +93,190 @@ func (ts traceSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.Samp
 		}
 	}
 
-	x := binary.BigEndian.Uint64(p.TraceID[8:16]) >> 1
-	if x < bound {
+	if !sampling.Sampled(sampling.TraceIDRandomness(p.TraceID), threshold) {
 		return sdktrace.SamplingResult{
-			Decision:   sdktrace.RecordAndSample,
+			Decision:   sdktrace.Drop,
 			Tracestate: psc.TraceState(),
 		}
 	}
+	if ruleLimiter != nil || ts.limiter != nil {
+		return ts.sampleLimited(psc.TraceState(), threshold, ruleLimiter)
+	}
+	state, err := sampling.WithThreshold(psc.TraceState(), threshold)
+	if err != nil {
+		state = psc.TraceState()
+	}
 	return sdktrace.SamplingResult{
-		Decision:   sdktrace.Drop,
-		Tracestate: psc.TraceState(),
+		Decision:   sdktrace.RecordAndSample,
+		Attributes: headSamplingAttributes(threshold),
+		Tracestate: state,
 	}
 }
 
+// sampleLimited applies the rate limits to a root span which was sampled with
+// the threshold. When a limit is reached, the probability the trace is sampled
+// with is no longer consistent with its randomness, so no threshold is
+// recorded in the tracestate. The estimated probability is recorded in the
+// attributes of the span instead, so counts can still be extrapolated.
+func (ts traceSampler) sampleLimited(
+	state trace.TraceState,
+	threshold uint64,
+	ruleLimiter *sampling.RateLimiter,
+) sdktrace.SamplingResult {
+	allowed, limiterProbability := sampling.AllowAll(ruleLimiter, ts.limiter)
+	if !allowed {
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.Drop,
+			Tracestate: state,
+		}
+	}
+	probability := sampling.Probability(threshold) * limiterProbability
+	limited := limiterProbability < 1
+
+	var err error
+	if limited {
+		state, err = sampling.WithoutThreshold(state)
+	} else {
+		state, err = sampling.WithThreshold(state, threshold)
+	}
+	if err != nil {
+		return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSample}
+	}
+	return sdktrace.SamplingResult{
+		Decision: sdktrace.RecordAndSample,
+		Attributes: []attribute.KeyValue{
+			samplingRatioAttribute(probability),
+		},
+		Tracestate: state,
+	}
+}
+
+// sampledByParent follows the decision of a sampled parent. A threshold from
+// the parent is kept when the trace is consistent with it, so that the
+// probability the trace was sampled with is known for each of its spans.
+func sampledByParent(traceID trace.TraceID, state trace.TraceState) sdktrace.SamplingResult {
+	threshold, ok := sampling.Threshold(state)
+	if !ok {
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.RecordAndSample,
+			Tracestate: state,
+		}
+	}
+	if !sampling.Sampled(sampling.Randomness(traceID, state), threshold) {
+		// The trace would not have been sampled with this threshold, so it
+		// does not describe how the trace was sampled.
+		if withoutThreshold, err := sampling.WithoutThreshold(state); err == nil {
+			state = withoutThreshold
+		}
+		return sdktrace.SamplingResult{
+			Decision:   sdktrace.RecordAndSample,
+			Tracestate: state,
+		}
+	}
+	return sdktrace.SamplingResult{
+		Decision:   sdktrace.RecordAndSample,
+		Attributes: headSamplingAttributes(threshold),
+		Tracestate: state,
+	}
+}
+
+// headSamplingAttributes returns the attributes which record the ratio a span
+// was head sampled with. Spans sampled with a probability of 1 have no
+// attributes, which avoids the work for the common case.
+func headSamplingAttributes(threshold uint64) []attribute.KeyValue {
+	if threshold == 0 {
+		return nil
+	}
+	return []attribute.KeyValue{samplingRatioAttribute(sampling.Probability(threshold))}
+}
+
+// samplingRatioAttribute records a sampling probability as a ratio in the
+// form 1/n, which is how the ratios of the sampling configuration are
+// recorded.
+func samplingRatioAttribute(probability float64) attribute.KeyValue {
+	return attribute.Int64(attributes.AttrSamplingRatio, int64(math.Round(1/probability)))
+}
+
+// matchRule returns the threshold and rate limiter of the first head sampling
+// rule which matches the span.
+func (ts traceSampler) matchRule(p sdktrace.SamplingParameters) (uint64, *sampling.RateLimiter, bool) {
+	if ts.rules == nil {
+		return 0, nil, false
+	}
+	index := ts.rules.MatchSpan(headSpan{params: p})
+	if index < 0 || index >= len(ts.ruleThresholds) {
+		return 0, nil, false
+	}
+	return ts.ruleThresholds[index], ts.ruleLimiters[index], true
+}
+
 func (ts traceSampler) Description() string {
 	return ts.description
 }
 
 // creates a per-span-kind sampler that samples each kind at a provided fraction.
-func getSampler(rates map[trace.SpanKind]float64) traceSampler {
-	return traceSampler{
+// Root spans matching one of the rules are sampled at the rate of the first
+// matching rule instead of the rate for their kind.
+func getSampler(rates map[trace.SpanKind]float64, rules ...HeadSamplingRule) traceSampler {
+	ts := traceSampler{
 		description: fmt.Sprintf("TraceIDRatioBased{%+v}", rates),
-		traceIDUpperBounds: lo.MapEntries(rates, func(key trace.SpanKind, value float64) (trace.SpanKind, uint64) {
-			return key, uint64(value * (1 << 63))
+		thresholds: lo.MapEntries(rates, func(key trace.SpanKind, value float64) (trace.SpanKind, uint64) {
+			return key, sampling.ThresholdForProbability(value)
 		}),
 	}
+	if len(rules) == 0 {
+		return ts
+	}
+
+	spanRules := make([]SpanSamplingRule, 0, len(rules))
+	for _, rule := range rules {
+		spanRules = append(spanRules, SpanSamplingRule{Name: rule.Name, Attributes: rule.Attributes})
+	}
+	matchRules := SamplingRules{Spans: spanRules}
+	if err := matchRules.Validate(); err != nil {
+		logging.GetLogger().Errorf("invalid head sampling rules: %v", err)
+	}
+	config, err := matchRules.toSamplingConfig()
+	if err != nil {
+		logging.GetLogger().Errorf("failed to use head sampling rules: %v", err)
+		return ts
+	}
+	ts.rules = otel.NewCustomSampler(nil)
+	ts.rules.SetConfig(config)
+	ts.ruleThresholds = lo.Map(rules, func(rule HeadSamplingRule, _ int) uint64 {
+		return sampling.ThresholdForProbability(rule.Rate)
+	})
+	ts.ruleLimiters = lo.Map(rules, func(rule HeadSamplingRule, _ int) *sampling.RateLimiter {
+		return newRateLimiter(rule.MaxPerSecond)
+	})
+	ts.description = fmt.Sprintf("TraceIDRatioBased{%+v, rules:%d}", rates, len(rules))
+	return ts
+}
+
+// withRateLimit returns the sampler with a limit on the number of root spans
+// sampled each second. A limit of zero or less means there is no limit.
+func (ts traceSampler) withRateLimit(perSecond float64) traceSampler {
+	ts.limiter = newRateLimiter(perSecond)
+	if ts.limiter != nil {
+		ts.description = fmt.Sprintf("RateLimited{%s, maxPerSecond:%v}", ts.description, perSecond)
+	}
+	return ts
+}
+
+func newRateLimiter(perSecond float64) *sampling.RateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return sampling.NewRateLimiter(perSecond)
+}
+
+// EffectiveSamplingRatio returns the ratio, in the form 1/n, that the trace of
+// the span context was head sampled with, according to its tracestate. It
+// returns false if the tracestate does not record how the trace was sampled.
+func EffectiveSamplingRatio(sc trace.SpanContext) (float64, bool) {
+	threshold, ok := sampling.Threshold(sc.TraceState())
+	if !ok || threshold >= sampling.MaxThreshold {
+		return 0, false
+	}
+	return 1 / sampling.Probability(threshold), true
 }
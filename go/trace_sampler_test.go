@@ -7,11 +7,25 @@ import (
 	"math"
 	"math/rand"
 	"testing"
+	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/launchdarkly/observability-sdk/go/attributes"
 )
 
+// highRandomnessTraceID is sampled at any rate above zero.
+var highRandomnessTraceID = trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 0xf1, 0xf2, 0xf3, 0xf4, 0xf5, 0xf6, 0xf7}
+
+// traceIDWithRandomness creates a trace ID whose lower 56 bits are the given randomness.
+func traceIDWithRandomness(randomness uint64) trace.TraceID {
+	traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8}
+	binary.BigEndian.PutUint64(traceID[8:16], randomness)
+	return traceID
+}
+
 func TestTraceSampler_ShouldSample_WithSampledParent(t *testing.T) {
 	// Create a sampler with some rates
 	rates := map[trace.SpanKind]float64{
@@ -88,25 +102,18 @@ func TestTraceSampler_ShouldSample_WithUnsampledParent(t *testing.T) {
 	}
 }
 
-func TestTraceSampler_ShouldSample_NoParent_AboveThreshold(t *testing.T) {
+func TestTraceSampler_ShouldSample_NoParent_BelowThreshold(t *testing.T) {
 	// Create a sampler with specific rates
 	rates := map[trace.SpanKind]float64{
 		trace.SpanKindServer: 0.5,
 	}
 	sampler := getSampler(rates)
 
-	// Test with trace ID that should NOT be sampled (above threshold)
-	// For 0.5 rate, threshold is 0.5 * (1 << 63) = 0x4000000000000000
-	// The condition is: if x < bound then sample, so we need x >= bound to NOT sample
-	// We need (x >> 1) >= threshold, so x >= threshold * 2
-	threshold := uint64(0.5 * (1 << 63))
-	x := threshold*2 + 1 // This ensures (x >> 1) = threshold + 0.5 >= threshold
-
-	// Create trace ID with the calculated value in the upper 8 bytes
-	traceIDBytes := make([]byte, 16)
-	binary.BigEndian.PutUint64(traceIDBytes[8:16], x)
-	traceID := trace.TraceID{}
-	copy(traceID[:], traceIDBytes)
+	// Test with trace ID that should NOT be sampled (below threshold)
+	// For 0.5 rate, the rejection threshold is (1 - 0.5) * (1 << 56) = 0x80000000000000
+	// The condition is: if randomness >= threshold then sample
+	threshold := uint64(1 << 55)
+	traceID := traceIDWithRandomness(threshold / 2)
 
 	params := sdktrace.SamplingParameters{
 		ParentContext: context.Background(),
@@ -133,7 +140,7 @@ func TestTraceSampler_ShouldSample_NoParent(t *testing.T) {
 	// Test with no parent context
 	params := sdktrace.SamplingParameters{
 		ParentContext: context.Background(),
-		TraceID:       trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		TraceID:       highRandomnessTraceID,
 		Name:          "test-span",
 		Kind:          trace.SpanKindServer,
 	}
@@ -180,7 +187,7 @@ func TestTraceSampler_ShouldSample_NoParent_UnspecifiedKindWithDefault(t *testin
 	// Test with Unspecified kind
 	params := sdktrace.SamplingParameters{
 		ParentContext: context.Background(),
-		TraceID:       trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		TraceID:       highRandomnessTraceID,
 		Name:          "test-span",
 		Kind:          trace.SpanKindUnspecified,
 	}
@@ -227,7 +234,7 @@ func TestTraceSampler_ShouldSample_NoParent_UnknownKindWithUnspecifiedFallback(t
 	// Test with Client kind (not in rates, but should fall back to Unspecified rate)
 	params := sdktrace.SamplingParameters{
 		ParentContext: context.Background(),
-		TraceID:       trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		TraceID:       highRandomnessTraceID,
 		Name:          "test-span",
 		Kind:          trace.SpanKindClient,
 	}
@@ -240,7 +247,7 @@ func TestTraceSampler_ShouldSample_NoParent_UnknownKindWithUnspecifiedFallback(t
 	}
 }
 
-func TestTraceSampler_ShouldSample_NoParent_UnknownKindWithUnspecifiedFallback_AboveThreshold(t *testing.T) {
+func TestTraceSampler_ShouldSample_NoParent_UnknownKindWithUnspecifiedFallback_BelowThreshold(t *testing.T) {
 	// Create a sampler with specific rates including Unspecified kind as fallback
 	rates := map[trace.SpanKind]float64{
 		trace.SpanKindServer:      0.5,
@@ -249,16 +256,10 @@ func TestTraceSampler_ShouldSample_NoParent_UnknownKindWithUnspecifiedFallback_A
 	sampler := getSampler(rates)
 
 	// Test with Client kind (not in rates, but should fall back to Unspecified rate)
-	// For 0.25 rate, threshold is 0.25 * (1 << 63) = 0x2000000000000000
-	// We need (x >> 1) >= threshold, so x >= threshold * 2
-	threshold := uint64(0.25 * (1 << 63))
-	x := threshold*2 + 1 // This ensures (x >> 1) = threshold + 0.5 >= threshold
-
-	// Create trace ID with the calculated value in the upper 8 bytes
-	traceIDBytes := make([]byte, 16)
-	binary.BigEndian.PutUint64(traceIDBytes[8:16], x)
-	traceID := trace.TraceID{}
-	copy(traceID[:], traceIDBytes)
+	// For 0.25 rate, the rejection threshold is (1 - 0.25) * (1 << 56) = 0xc0000000000000
+	// The randomness is above the threshold for the 0.5 server rate, but below this one.
+	threshold := uint64(3 << 54)
+	traceID := traceIDWithRandomness(threshold - 1)
 
 	params := sdktrace.SamplingParameters{
 		ParentContext: context.Background(),
@@ -269,7 +270,7 @@ func TestTraceSampler_ShouldSample_NoParent_UnknownKindWithUnspecifiedFallback_A
 
 	result := sampler.ShouldSample(params)
 
-	// Should NOT sample based on Unspecified kind rate (0.25) since trace ID is above threshold
+	// Should NOT sample based on Unspecified kind rate (0.25) since trace ID is below threshold
 	if result.Decision != sdktrace.Drop {
 		t.Errorf("Expected decision %v, got %v", sdktrace.Drop, result.Decision)
 	}
@@ -314,9 +315,9 @@ func TestGetSampler_EmptyRates(t *testing.T) {
 	rates := map[trace.SpanKind]float64{}
 	sampler := getSampler(rates)
 
-	// Should create sampler with empty bounds
-	if len(sampler.traceIDUpperBounds) != 0 {
-		t.Errorf("Expected empty bounds, got %d", len(sampler.traceIDUpperBounds))
+	// Should create sampler with empty thresholds
+	if len(sampler.thresholds) != 0 {
+		t.Errorf("Expected empty thresholds, got %d", len(sampler.thresholds))
 	}
 	if sampler.description != "TraceIDRatioBased{map[]}" {
 		t.Errorf("Expected description %s, got %s", "TraceIDRatioBased{map[]}", sampler.description)
@@ -330,17 +331,17 @@ func TestGetSampler_WithRates(t *testing.T) {
 	}
 	sampler := getSampler(rates)
 
-	// Check that bounds are calculated correctly
-	// 0.5 * (1 << 63) = 0x4000000000000000
-	// 0.25 * (1 << 63) = 0x2000000000000000
-	expectedServerBound := uint64(0.5 * (1 << 63))
-	expectedClientBound := uint64(0.25 * (1 << 63))
+	// Check that rejection thresholds are calculated correctly
+	// (1 - 0.5) * (1 << 56) = 0x80000000000000
+	// (1 - 0.25) * (1 << 56) = 0xc0000000000000
+	expectedServerThreshold := uint64(0x80000000000000)
+	expectedClientThreshold := uint64(0xc0000000000000)
 
-	if sampler.traceIDUpperBounds[trace.SpanKindServer] != expectedServerBound {
-		t.Errorf("Expected server bound %d, got %d", expectedServerBound, sampler.traceIDUpperBounds[trace.SpanKindServer])
+	if sampler.thresholds[trace.SpanKindServer] != expectedServerThreshold {
+		t.Errorf("Expected server threshold %x, got %x", expectedServerThreshold, sampler.thresholds[trace.SpanKindServer])
 	}
-	if sampler.traceIDUpperBounds[trace.SpanKindClient] != expectedClientBound {
-		t.Errorf("Expected client bound %d, got %d", expectedClientBound, sampler.traceIDUpperBounds[trace.SpanKindClient])
+	if sampler.thresholds[trace.SpanKindClient] != expectedClientThreshold {
+		t.Errorf("Expected client threshold %x, got %x", expectedClientThreshold, sampler.thresholds[trace.SpanKindClient])
 	}
 }
 
@@ -360,7 +361,7 @@ func TestTraceSampler_ShouldSample_EdgeCase_ZeroRate(t *testing.T) {
 
 	result := sampler.ShouldSample(params)
 
-	// With 0.0 rate, threshold is 0, so no trace ID should be sampled
+	// With 0.0 rate, threshold is 1 << 56, so no trace ID should be sampled
 	if result.Decision != sdktrace.Drop {
 		t.Errorf("Expected decision %v, got %v", sdktrace.Drop, result.Decision)
 	}
@@ -382,7 +383,7 @@ func TestTraceSampler_ShouldSample_EdgeCase_OneRate(t *testing.T) {
 
 	result := sampler.ShouldSample(params)
 
-	// With 1.0 rate, threshold is max uint64, so all trace IDs should be sampled
+	// With 1.0 rate, threshold is 0, so all trace IDs should be sampled
 	if result.Decision != sdktrace.RecordAndSample {
 		t.Errorf("Expected decision %v, got %v", sdktrace.RecordAndSample, result.Decision)
 	}
@@ -396,20 +397,13 @@ func TestTraceSampler_ShouldSample_TraceIDCalculation(t *testing.T) {
 	sampler := getSampler(rates)
 
 	// Test the exact calculation from the code
-	// The code does: binary.BigEndian.Uint64(p.TraceID[8:16]) >> 1
-	// For 0.5 rate, threshold is 0.5 * (1 << 63) = 0x4000000000000000
-	// The condition is: if x < bound then sample
-
-	// Create a trace ID where the upper 8 bytes when shifted right by 1 equals exactly the threshold
-	threshold := uint64(0.5 * (1 << 63))
-	// We need (x >> 1) = threshold, so x = threshold * 2
-	x := threshold * 2
+	// The randomness is the lower 56 bits of the trace ID
+	// For 0.5 rate, the rejection threshold is (1 - 0.5) * (1 << 56) = 0x80000000000000
+	// The condition is: if randomness >= threshold then sample
 
-	// Create trace ID with the calculated value in the upper 8 bytes
-	traceIDBytes := make([]byte, 16)
-	binary.BigEndian.PutUint64(traceIDBytes[8:16], x)
-	traceID := trace.TraceID{}
-	copy(traceID[:], traceIDBytes)
+	// Create a trace ID where the randomness equals exactly the threshold
+	threshold := uint64(1 << 55)
+	traceID := traceIDWithRandomness(threshold)
 
 	params := sdktrace.SamplingParameters{
 		ParentContext: context.Background(),
@@ -420,10 +414,10 @@ func TestTraceSampler_ShouldSample_TraceIDCalculation(t *testing.T) {
 
 	result := sampler.ShouldSample(params)
 
-	// This should be exactly at the threshold, so (x >> 1) = threshold
-	// Since the condition is x < bound, and x = threshold, this should NOT sample
-	if result.Decision != sdktrace.Drop {
-		t.Errorf("Expected decision %v, got %v", sdktrace.Drop, result.Decision)
+	// This should be exactly at the threshold
+	// Since the condition is randomness >= threshold, this should sample
+	if result.Decision != sdktrace.RecordAndSample {
+		t.Errorf("Expected decision %v, got %v", sdktrace.RecordAndSample, result.Decision)
 	}
 }
 
@@ -435,15 +429,8 @@ func TestTraceSampler_ShouldSample_TraceIDCalculation_JustBelowThreshold(t *test
 	sampler := getSampler(rates)
 
 	// Test with a value just below the threshold
-	threshold := uint64(0.5 * (1 << 63))
-	// We need (x >> 1) < threshold, so x < threshold * 2
-	x := threshold*2 - 2 // This ensures (x >> 1) = threshold - 1 < threshold
-
-	// Create trace ID with the calculated value in the upper 8 bytes
-	traceIDBytes := make([]byte, 16)
-	binary.BigEndian.PutUint64(traceIDBytes[8:16], x)
-	traceID := trace.TraceID{}
-	copy(traceID[:], traceIDBytes)
+	threshold := uint64(1 << 55)
+	traceID := traceIDWithRandomness(threshold - 1)
 
 	params := sdktrace.SamplingParameters{
 		ParentContext: context.Background(),
@@ -454,9 +441,9 @@ func TestTraceSampler_ShouldSample_TraceIDCalculation_JustBelowThreshold(t *test
 
 	result := sampler.ShouldSample(params)
 
-	// This should be just below the threshold, so it should sample
-	if result.Decision != sdktrace.RecordAndSample {
-		t.Errorf("Expected decision %v, got %v", sdktrace.RecordAndSample, result.Decision)
+	// This should be just below the threshold, so it should NOT sample
+	if result.Decision != sdktrace.Drop {
+		t.Errorf("Expected decision %v, got %v", sdktrace.Drop, result.Decision)
 	}
 }
 
@@ -468,15 +455,8 @@ func TestTraceSampler_ShouldSample_TraceIDCalculation_JustAboveThreshold(t *test
 	sampler := getSampler(rates)
 
 	// Test with a value just above the threshold
-	threshold := uint64(0.5 * (1 << 63))
-	// We need (x >> 1) >= threshold, so x >= threshold * 2
-	x := threshold*2 + 2 // This ensures (x >> 1) = threshold + 1 >= threshold
-
-	// Create trace ID with the calculated value in the upper 8 bytes
-	traceIDBytes := make([]byte, 16)
-	binary.BigEndian.PutUint64(traceIDBytes[8:16], x)
-	traceID := trace.TraceID{}
-	copy(traceID[:], traceIDBytes)
+	threshold := uint64(1 << 55)
+	traceID := traceIDWithRandomness(threshold + 1)
 
 	params := sdktrace.SamplingParameters{
 		ParentContext: context.Background(),
@@ -487,9 +467,9 @@ func TestTraceSampler_ShouldSample_TraceIDCalculation_JustAboveThreshold(t *test
 
 	result := sampler.ShouldSample(params)
 
-	// This should be just above the threshold, so it should NOT sample
-	if result.Decision != sdktrace.Drop {
-		t.Errorf("Expected decision %v, got %v", sdktrace.Drop, result.Decision)
+	// This should be just above the threshold, so it should sample
+	if result.Decision != sdktrace.RecordAndSample {
+		t.Errorf("Expected decision %v, got %v", sdktrace.RecordAndSample, result.Decision)
 	}
 }
 
@@ -556,3 +536,321 @@ func generateRandomTraceID() trace.TraceID {
 	}
 	return traceID
 }
+
+func TestTraceSampler_RecordsThresholdInTracestate(t *testing.T) {
+	sampler := getSampler(map[trace.SpanKind]float64{trace.SpanKindServer: 0.25})
+
+	result := sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       highRandomnessTraceID,
+		Name:          "test-span",
+		Kind:          trace.SpanKindServer,
+	})
+
+	if result.Decision != sdktrace.RecordAndSample {
+		t.Fatalf("Expected decision %v, got %v", sdktrace.RecordAndSample, result.Decision)
+	}
+	if ot := result.Tracestate.Get("ot"); ot != "th:c" {
+		t.Errorf("Expected tracestate ot=th:c, got %q", ot)
+	}
+	if len(result.Attributes) != 1 || result.Attributes[0].Key != attributes.AttrSamplingRatio ||
+		result.Attributes[0].Value.AsInt64() != 4 {
+		t.Errorf("Expected sampling ratio attribute of 4, got %v", result.Attributes)
+	}
+	ratio, ok := EffectiveSamplingRatio(trace.NewSpanContext(trace.SpanContextConfig{TraceState: result.Tracestate}))
+	if !ok || ratio != 4 {
+		t.Errorf("Expected effective sampling ratio 4, got %v (%t)", ratio, ok)
+	}
+}
+
+func TestTraceSampler_FullRateRecordsZeroThreshold(t *testing.T) {
+	sampler := getSampler(map[trace.SpanKind]float64{trace.SpanKindServer: 1.0})
+
+	result := sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       traceIDWithRandomness(0),
+		Name:          "test-span",
+		Kind:          trace.SpanKindServer,
+	})
+
+	if ot := result.Tracestate.Get("ot"); ot != "th:0" {
+		t.Errorf("Expected tracestate ot=th:0, got %q", ot)
+	}
+	if len(result.Attributes) != 0 {
+		t.Errorf("Expected no attributes when sampling everything, got %v", result.Attributes)
+	}
+}
+
+func TestTraceSampler_HonorsParentThreshold(t *testing.T) {
+	// The local rates would sample the trace at a different probability,
+	// but the decision and threshold of the parent apply.
+	sampler := getSampler(map[trace.SpanKind]float64{trace.SpanKindServer: 1.0})
+	state, err := trace.ParseTraceState("ot=th:8;rv:ffffffffffffff,vendor=value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceIDWithRandomness(0),
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+		TraceState: state,
+		Remote:     true,
+	})
+
+	result := sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), parentContext),
+		TraceID:       parentContext.TraceID(),
+		Name:          "test-span",
+		Kind:          trace.SpanKindServer,
+	})
+
+	if result.Decision != sdktrace.RecordAndSample {
+		t.Fatalf("Expected decision %v, got %v", sdktrace.RecordAndSample, result.Decision)
+	}
+	if result.Tracestate.String() != state.String() {
+		t.Errorf("Expected tracestate %q to be kept, got %q", state.String(), result.Tracestate.String())
+	}
+	if len(result.Attributes) != 1 || result.Attributes[0].Value.AsInt64() != 2 {
+		t.Errorf("Expected sampling ratio attribute of 2, got %v", result.Attributes)
+	}
+}
+
+func TestTraceSampler_ErasesInconsistentParentThreshold(t *testing.T) {
+	sampler := getSampler(map[trace.SpanKind]float64{trace.SpanKindServer: 1.0})
+	// The randomness of the trace is below the threshold, so the threshold
+	// does not describe how the trace was sampled.
+	state, err := trace.ParseTraceState("ot=th:8;p:1,vendor=value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parentContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceIDWithRandomness(1),
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+		TraceState: state,
+	})
+
+	result := sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), parentContext),
+		TraceID:       parentContext.TraceID(),
+		Name:          "test-span",
+		Kind:          trace.SpanKindServer,
+	})
+
+	if result.Decision != sdktrace.RecordAndSample {
+		t.Fatalf("Expected decision %v, got %v", sdktrace.RecordAndSample, result.Decision)
+	}
+	if ot := result.Tracestate.Get("ot"); ot != "p:1" {
+		t.Errorf("Expected the threshold to be removed from the ot entry, got %q", ot)
+	}
+	if v := result.Tracestate.Get("vendor"); v != "value" {
+		t.Errorf("Expected other tracestate entries to be kept, got %q", v)
+	}
+	if len(result.Attributes) != 0 {
+		t.Errorf("Expected no attributes, got %v", result.Attributes)
+	}
+}
+
+func TestEffectiveSamplingRatio_WithoutThreshold(t *testing.T) {
+	if _, ok := EffectiveSamplingRatio(trace.SpanContext{}); ok {
+		t.Error("Expected no effective sampling ratio without a threshold")
+	}
+}
+
+func TestTraceSampler_HeadSamplingRules(t *testing.T) {
+	rates := map[trace.SpanKind]float64{
+		trace.SpanKindServer: 0.5,
+	}
+	sampler := getSampler(rates,
+		HeadSamplingRule{
+			Attributes: []AttributeMatchConfig{
+				{Key: MatchValue("http.route"), Attribute: MatchRegex("^/health")},
+			},
+			Rate: 0,
+		},
+		HeadSamplingRule{Name: MatchValue("POST /checkout"), Rate: 1},
+	)
+
+	sample := func(traceID trace.TraceID, name string, attrs ...attribute.KeyValue) sdktrace.SamplingDecision {
+		return sampler.ShouldSample(sdktrace.SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       traceID,
+			Name:          name,
+			Kind:          trace.SpanKindServer,
+			Attributes:    attrs,
+		}).Decision
+	}
+
+	// The trace ID would be sampled by the rate for the kind.
+	if d := sample(highRandomnessTraceID, "GET /health", attribute.String("http.route", "/health/live")); d != sdktrace.Drop {
+		t.Errorf("Expected health check route to be dropped, got %v", d)
+	}
+	// The trace ID would be dropped by the rate for the kind.
+	if d := sample(traceIDWithRandomness(0), "POST /checkout"); d != sdktrace.RecordAndSample {
+		t.Errorf("Expected checkout route to be sampled, got %v", d)
+	}
+	// Spans which match no rule use the rate for their kind.
+	if d := sample(traceIDWithRandomness(0), "GET /items", attribute.String("http.route", "/items")); d != sdktrace.Drop {
+		t.Errorf("Expected unmatched span to use the kind rate, got %v", d)
+	}
+	if d := sample(highRandomnessTraceID, "GET /items"); d != sdktrace.RecordAndSample {
+		t.Errorf("Expected unmatched span to use the kind rate, got %v", d)
+	}
+	if !contains(sampler.Description(), "rules:2") {
+		t.Errorf("Expected description to contain the number of rules, got %s", sampler.Description())
+	}
+}
+
+func TestTraceSampler_HeadSamplingRules_RespectParent(t *testing.T) {
+	sampler := getSampler(nil, HeadSamplingRule{Name: MatchValue("POST /checkout"), Rate: 1})
+	parentContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:  trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		Remote:  true,
+	})
+
+	result := sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), parentContext),
+		TraceID:       parentContext.TraceID(),
+		Name:          "POST /checkout",
+		Kind:          trace.SpanKindServer,
+	})
+
+	if result.Decision != sdktrace.Drop {
+		t.Errorf("Expected rules not to apply when the parent is unsampled, got %v", result.Decision)
+	}
+}
+
+func TestTraceSampler_HeadSamplingRules_OnlyRules(t *testing.T) {
+	sampler := getSampler(nil, HeadSamplingRule{Name: MatchValue("GET /health"), Rate: 0})
+
+	result := sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       traceIDWithRandomness(0),
+		Name:          "GET /items",
+		Kind:          trace.SpanKindServer,
+	})
+
+	if result.Decision != sdktrace.RecordAndSample {
+		t.Errorf("Expected unmatched span to be sampled without kind rates, got %v", result.Decision)
+	}
+}
+
+func TestTraceSampler_RateLimit(t *testing.T) {
+	sampler := getSampler(nil).withRateLimit(2)
+
+	sampled := 0
+	for i := range 10 {
+		result := sampler.ShouldSample(sdktrace.SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       traceIDWithRandomness(uint64(i)),
+			Name:          "test-span",
+			Kind:          trace.SpanKindServer,
+		})
+		if result.Decision != sdktrace.RecordAndSample {
+			continue
+		}
+		sampled++
+		// The first traces are within the limit, so they are sampled with a
+		// probability of 1.
+		if ot := result.Tracestate.Get("ot"); ot != "th:0" {
+			t.Errorf("Expected tracestate ot=th:0 within the limit, got %q", ot)
+		}
+		if len(result.Attributes) != 1 || result.Attributes[0].Key != attributes.AttrSamplingRatio ||
+			result.Attributes[0].Value.AsInt64() != 1 {
+			t.Errorf("Expected sampling ratio attribute of 1, got %v", result.Attributes)
+		}
+	}
+	if sampled != 2 {
+		t.Errorf("Expected 2 traces to be sampled, got %d", sampled)
+	}
+	if !contains(sampler.Description(), "maxPerSecond:2") {
+		t.Errorf("Expected description to contain the rate limit, got %s", sampler.Description())
+	}
+}
+
+func TestTraceSampler_RateLimit_PerRule(t *testing.T) {
+	sampler := getSampler(nil, HeadSamplingRule{Name: MatchValue("GET /health"), Rate: 1, MaxPerSecond: 1})
+
+	sample := func(name string) sdktrace.SamplingDecision {
+		return sampler.ShouldSample(sdktrace.SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       highRandomnessTraceID,
+			Name:          name,
+			Kind:          trace.SpanKindServer,
+		}).Decision
+	}
+
+	if d := sample("GET /health"); d != sdktrace.RecordAndSample {
+		t.Errorf("Expected the first matching trace to be sampled, got %v", d)
+	}
+	if d := sample("GET /health"); d != sdktrace.Drop {
+		t.Errorf("Expected matching traces over the limit to be dropped, got %v", d)
+	}
+	for range 5 {
+		if d := sample("GET /items"); d != sdktrace.RecordAndSample {
+			t.Errorf("Expected traces not matching the rule to be unlimited, got %v", d)
+		}
+	}
+}
+
+func TestTraceSampler_RateLimit_RecordsEstimatedRatio(t *testing.T) {
+	sampler := getSampler(nil).withRateLimit(1)
+	params := sdktrace.SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       highRandomnessTraceID,
+		Name:          "test-span",
+		Kind:          trace.SpanKindServer,
+	}
+
+	// Use up the limit, and drop traces, so the estimated probability is below 1.
+	for range 10 {
+		sampler.ShouldSample(params)
+	}
+	// Once a token is available the next trace is sampled with the estimated ratio.
+	var result sdktrace.SamplingResult
+	for result.Decision != sdktrace.RecordAndSample {
+		time.Sleep(50 * time.Millisecond)
+		result = sampler.ShouldSample(params)
+	}
+
+	if ot := result.Tracestate.Get("ot"); ot != "" {
+		t.Errorf("Expected no threshold in the tracestate of a limited trace, got %q", ot)
+	}
+	if len(result.Attributes) != 1 || result.Attributes[0].Key != attributes.AttrSamplingRatio ||
+		result.Attributes[0].Value.AsInt64() <= 1 {
+		t.Errorf("Expected a sampling ratio above 1 for a limited trace, got %v", result.Attributes)
+	}
+}
+
+func TestTraceSampler_RateLimit_GlobalDenialKeepsRuleToken(t *testing.T) {
+	sampler := getSampler(nil, HeadSamplingRule{Name: MatchValue("GET /health"), Rate: 1, MaxPerSecond: 1}).
+		withRateLimit(1)
+
+	sample := func(name string) sdktrace.SamplingDecision {
+		return sampler.ShouldSample(sdktrace.SamplingParameters{
+			ParentContext: context.Background(),
+			TraceID:       highRandomnessTraceID,
+			Name:          name,
+			Kind:          trace.SpanKindServer,
+		}).Decision
+	}
+
+	// Use up the global limit with a trace which does not match the rule.
+	if d := sample("GET /items"); d != sdktrace.RecordAndSample {
+		t.Fatalf("Expected the first trace to be sampled, got %v", d)
+	}
+	// The global limit denies the matching trace, which must not spend the
+	// token of the rule.
+	if d := sample("GET /health"); d != sdktrace.Drop {
+		t.Fatalf("Expected the matching trace to be dropped by the global limit, got %v", d)
+	}
+	for _, limiter := range sampler.ruleLimiters {
+		if limiter == nil {
+			continue
+		}
+		if ok, _ := limiter.Allow(); !ok {
+			t.Errorf("Expected the rule limiter to keep its token when the global limit denied the trace")
+		}
+	}
+}